@@ -4,31 +4,44 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 
 	"application-updater/internal/models"
+	"application-updater/internal/services/audit"
 	"application-updater/internal/services/backup"
 	"application-updater/internal/services/camera"
 	"application-updater/internal/services/device"
 	"application-updater/internal/services/excel"
+	"application-updater/internal/services/firmware"
+	"application-updater/internal/services/lock"
+	"application-updater/internal/services/rollout"
+	"application-updater/internal/services/schedule"
+	"application-updater/internal/services/signing"
 	"application-updater/internal/services/time"
+	"application-updater/internal/services/workspace"
 	"application-updater/internal/utils"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // CameraAdapter adapts camera.Service to excel.CameraService interface
 type cameraAdapter struct {
 	cameraService *camera.Service
+	devices       func() *device.Service
 }
 
 // ConfigureCamerasFromData implements the excel.CameraService interface
-func (a *cameraAdapter) ConfigureCamerasFromData(rows []models.ExcelRow, username, password, urlTemplate string, algorithmType int, region string) []models.CameraConfigResult {
+func (a *cameraAdapter) ConfigureCamerasFromData(rows []models.ExcelRow, username, password, urlTemplate string, algorithms models.CameraAlgorithms, region string) []models.CameraConfigResult {
 	// Create a token getter function for the camera service
 	getTokenFunc := func(ip, user, pass string) (string, error) {
-		return device.NewAuth(&http.Client{}).LoginToDevice(ip, user, pass)
+		return a.devices().LoginToDevice(ip, user, pass)
 	}
 
 	// Call the camera service's method with the token getter and region
-	return a.cameraService.Config.ConfigureCamerasFromData(rows, getTokenFunc, username, password, urlTemplate, algorithmType, region)
+	return a.cameraService.Config.ConfigureCamerasFromData(rows, getTokenFunc, username, password, urlTemplate, algorithms, region)
 }
 
 // App struct represents the main application
@@ -42,6 +55,14 @@ type App struct {
 	excelService  *excel.Service
 	timeService   *time.Service
 	backupService *backup.Service
+	operations    int // bindings in progress that use the workspace services, see beginOperation
+
+	workspaceService *workspace.Service
+	rolloutService   *rollout.Service
+	firmwareService  *firmware.Service
+	scheduleService  *schedule.Service
+	trustedKeys      *signing.KeyStore
+	auditLog         *audit.Log
 }
 
 // NewApp creates a new App instance
@@ -55,17 +76,12 @@ func NewApp() *App {
 	// Get config directory
 	configDir := utils.GetConfigDir()
 
-	// Initialize device service first since other services depend on it
-	deviceService := device.NewService(configDir)
+	// Device inventories and settings live in the active workspace
+	workspaceService := workspace.NewService(configDir)
 
 	// Initialize other services
 	cameraService := camera.NewService(client)
-
-	// Set device manager in camera service
-	cameraService.SetDeviceService(deviceService)
-
 	timeService := time.NewService()
-	backupService := backup.NewService(deviceService)
 
 	// Create adapter to bridge camera service to excel service
 	cameraAdapterInstance := &cameraAdapter{cameraService: cameraService}
@@ -73,15 +89,120 @@ func NewApp() *App {
 	// Excel service needs camera adapter for ConfigureCamerasFromData
 	excelService := excel.NewService(cameraAdapterInstance)
 
-	return &App{
+	app := &App{
 		client:        client,
 		configDir:     configDir,
-		deviceService: deviceService,
 		cameraService: cameraService,
 		excelService:  excelService,
 		timeService:   timeService,
-		backupService: backupService,
+
+		workspaceService: workspaceService,
+		// Update packages, the keys trusted to sign them and the audit log are shared by all workspaces
+		firmwareService: firmware.NewService(filepath.Join(configDir, "packages")),
+		trustedKeys:     signing.NewKeyStore(configDir),
+		auditLog:        audit.NewLog(configDir),
+	}
+
+	// The camera service follows the device inventory of the active workspace
+	cameraService.SetDeviceService(app.devices)
+	cameraAdapterInstance.devices = app.devices
+
+	// Initialize the services that depend on the device inventory
+	app.openWorkspace(workspaceService.Dir(workspaceService.Current().ID))
+
+	return app
+}
+
+// openWorkspace creates the services that store their data in a workspace directory
+func (a *App) openWorkspace(workspaceDir string) {
+	a.deviceService = device.NewService(workspaceDir)
+	a.workspaceService.SetDatabaseSnapshotter(a.deviceService.Snapshot)
+	a.deviceService.SetUploadProgressHandler(a.emitUploadProgress)
+
+	a.backupService = backup.NewService(a.deviceService, workspaceDir)
+	a.rolloutService = rollout.NewService(filepath.Join(workspaceDir, "rollouts"), a.deviceService, a.firmwareService)
+	a.scheduleService = schedule.NewService(workspaceDir, a.deviceService)
+}
+
+// closeWorkspace releases the services opened by openWorkspace
+func (a *App) closeWorkspace() {
+	// Running rollouts are persisted and resume when the workspace is opened again
+	if a.rolloutService != nil {
+		a.rolloutService.Close()
+	}
+	if a.scheduleService != nil {
+		a.scheduleService.Close()
+	}
+
+	// 关闭设备服务资源
+	if a.deviceService != nil {
+		if err := a.deviceService.Close(); err != nil {
+			fmt.Printf("关闭设备服务时出错: %v\n", err)
+		}
+	}
+}
+
+// devices returns the device service of the active workspace
+func (a *App) devices() *device.Service {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.deviceService
+}
+
+// backups returns the backup service of the active workspace
+func (a *App) backups() *backup.Service {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.backupService
+}
+
+// rollouts returns the rollout service of the active workspace
+func (a *App) rollouts() *rollout.Service {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.rolloutService
+}
+
+// schedules returns the schedule service of the active workspace
+func (a *App) schedules() *schedule.Service {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.scheduleService
+}
+
+// beginOperation marks a binding that keeps using the workspace services while it talks to devices,
+// SwitchWorkspace refuses until the returned function has been called
+func (a *App) beginOperation() func() {
+	a.mutex.Lock()
+	a.operations++
+	a.mutex.Unlock()
+
+	return func() {
+		a.mutex.Lock()
+		a.operations--
+		a.mutex.Unlock()
+	}
+}
+
+// workspaceBusyLocked returns an error while work started in the active workspace is still running,
+// the caller must hold a.mutex
+func (a *App) workspaceBusyLocked() error {
+	if a.operations > 0 {
+		return fmt.Errorf("设备操作仍在进行中")
+	}
+	if locks := lock.Default().List(); len(locks) > 0 {
+		return fmt.Errorf("设备 %s 正在执行 %s 操作(开始于 %s)", locks[0].IP, locks[0].Operation, locks[0].Since)
+	}
+	if a.deviceService != nil && a.deviceService.HasRunningJobs() {
+		return fmt.Errorf("有更新任务正在执行")
+	}
+	if a.rolloutService != nil && a.rolloutService.HasRunning() {
+		return fmt.Errorf("有分批发布正在执行，请先暂停")
+	}
+	if a.scheduleService != nil && a.scheduleService.HasRunning() {
+		return fmt.Errorf("有计划更新正在执行")
 	}
+	return nil
 }
 
 func (a *App) SelectFolder() (string, error) {
@@ -94,8 +215,8 @@ func (a *App) DomReady(ctx context.Context) {
 	fmt.Println("DOM is ready")
 
 	// Load devices from storage
-	if a.deviceService != nil {
-		err := a.deviceService.LoadDevices()
+	if devices := a.devices(); devices != nil {
+		err := devices.LoadDevices()
 		if err != nil {
 			fmt.Printf("Warning: Failed to load devices from storage: %v\n", err)
 		} else {
@@ -104,71 +225,149 @@ func (a *App) DomReady(ctx context.Context) {
 	}
 }
 func (a *App) ClearDevices() error {
-	return a.deviceService.ClearDevices()
+	return a.devices().ClearDevices()
 }
 
 // Shutdown is called when the application is shutting down
 func (a *App) Shutdown(ctx context.Context) {
 	fmt.Println("Application is shutting down")
-
-	// 关闭设备服务资源
-	if a.deviceService != nil {
-		if err := a.deviceService.Close(); err != nil {
-			fmt.Printf("关闭设备服务时出错: %v\n", err)
-		}
-	}
+	a.closeWorkspace()
 }
 
-// BeforeClose is called when the user attempts to close the application
+// BeforeClose is called when the user attempts to close the application.
+// Scheduled updates only run while the app is open, so the user may keep it running minimised instead.
 func (a *App) BeforeClose(ctx context.Context) bool {
 	fmt.Println("User is attempting to close the application")
-	return false // Allow the application to close
+
+	schedules := a.schedules()
+	if schedules == nil || !schedules.HasActive() {
+		return false // Allow the application to close
+	}
+
+	minimise, err := utils.ConfirmDialog(ctx, "计划更新", "有尚未执行的计划更新，关闭程序后将不会执行。是否最小化到后台继续运行？")
+	if err != nil || !minimise {
+		return false
+	}
+	runtime.WindowMinimise(ctx)
+	return true
 }
 
 // GetDevices returns all devices
 func (a *App) GetDevices() []models.Device {
-	return a.deviceService.GetDevices()
+	return a.devices().GetDevices()
 }
 
 // GetAllDevices returns all devices without filtering
 func (a *App) GetAllDevices() []models.Device {
-	return a.deviceService.GetAllDevices()
+	return a.devices().GetAllDevices()
+}
+
+// QueryDevices returns one page of devices matching the filter, sorted as requested
+func (a *App) QueryDevices(query models.DeviceQuery) (models.DevicePage, error) {
+	return a.devices().QueryDevices(query)
+}
+
+// GetDeviceTags returns all tags used by devices
+func (a *App) GetDeviceTags() []string {
+	return a.devices().GetTags()
+}
+
+// UpdateDeviceDetails updates the name, notes and tags of a device
+func (a *App) UpdateDeviceDetails(deviceID, name, notes string, tags []string) (models.Device, error) {
+	return a.devices().UpdateDeviceDetails(deviceID, name, notes, tags)
 }
 
 // SetRegionFilter sets the region filter for devices
 func (a *App) SetRegionFilter(region string) []models.Device {
-	a.deviceService.SetRegionFilter(region)
-	return a.deviceService.GetDevices()
+	devices := a.devices()
+	devices.SetRegionFilter(region)
+	return devices.GetDevices()
 }
 
 // GetCurrentRegion returns the current region filter
 func (a *App) GetCurrentRegion() string {
-	return a.deviceService.GetCurrentRegion()
+	return a.devices().GetCurrentRegion()
 }
 
 // RefreshDevices refreshes device status
 func (a *App) RefreshDevices() []models.Device {
-	return a.deviceService.RefreshDevices()
+	defer a.beginOperation()()
+
+	return a.devices().RefreshDevices()
 }
 
 // AddDevice adds a new device
 func (a *App) AddDevice(ip string, region string) (models.Device, error) {
+	defer a.beginOperation()()
+
 	// 调用设备服务的TestAndAddDevice方法，完成测试和添加
-	device, err := a.deviceService.TestAndAddDevice(ip, region)
+	device, err := a.devices().TestAndAddDevice(ip, region)
 	if err != nil {
 		return models.Device{}, fmt.Errorf("设备测试或添加失败: %w", err)
 	}
 	return device, nil
 }
 
-// RemoveDevice removes a device by ID
+// RemoveDevice moves a device to the trash by ID
 func (a *App) RemoveDevice(deviceID string) error {
-	return a.deviceService.RemoveDevice(deviceID)
+	return a.devices().RemoveDevice(deviceID)
+}
+
+// RemoveDevices moves multiple devices to the trash
+func (a *App) RemoveDevices(deviceIDs []string) error {
+	return a.devices().RemoveDevices(deviceIDs)
+}
+
+// GetDeletedDevices returns the devices in the trash
+func (a *App) GetDeletedDevices() []models.Device {
+	return a.devices().GetDeletedDevices()
+}
+
+// RestoreDevices restores devices from the trash
+func (a *App) RestoreDevices(deviceIDs []string) error {
+	return a.devices().RestoreDevices(deviceIDs)
+}
+
+// PurgeDeletedDevices permanently deletes all devices in the trash
+func (a *App) PurgeDeletedDevices() (int, error) {
+	return a.devices().PurgeDeletedDevices()
+}
+
+// GetDeviceSettings gets the device management settings
+func (a *App) GetDeviceSettings() models.DeviceSettings {
+	return a.devices().GetSettings()
+}
+
+// SaveDeviceSettings saves the device management settings
+func (a *App) SaveDeviceSettings(settings models.DeviceSettings) error {
+	return a.devices().SaveSettings(settings)
+}
+
+// GetTransferSettings gets the bandwidth caps and upload concurrency settings
+func (a *App) GetTransferSettings() models.TransferSettings {
+	return a.devices().GetTransferSettings()
+}
+
+// SaveTransferSettings saves the bandwidth caps and upload concurrency settings, running transfers adopt the new caps
+func (a *App) SaveTransferSettings(settings models.TransferSettings) error {
+	return a.devices().SaveTransferSettings(settings)
+}
+
+// SetDevicesMaintenance puts devices into or out of maintenance mode, excluding them from batch operations
+func (a *App) SetDevicesMaintenance(deviceIDs []string, enabled bool, reason string) error {
+	return a.devices().SetDevicesMaintenance(deviceIDs, enabled, reason)
+}
+
+// GetDeviceLocks returns the devices currently busy with an operation
+func (a *App) GetDeviceLocks() []models.DeviceLock {
+	return lock.Default().List()
 }
 
 // LoginToDevice tests login credentials for a device
 func (a *App) LoginToDevice(ip, username, password string) (bool, string) {
-	token, err := a.deviceService.LoginToDevice(ip, username, password)
+	username, password = a.webCredentials(username, password)
+
+	token, err := a.devices().LoginToDevice(ip, username, password)
 	if err != nil {
 		return false, err.Error()
 	}
@@ -177,38 +376,55 @@ func (a *App) LoginToDevice(ip, username, password string) (bool, string) {
 
 // ScanIPRange scans an IP range for devices
 func (a *App) ScanIPRange(startIP, endIP string) []models.Device {
-	devices := a.deviceService.ScanIPRange(a.ctx, startIP, endIP)
+	defer a.beginOperation()()
+
+	devices := a.devices().ScanIPRange(a.ctx, startIP, endIP)
 	return devices
 }
 
-// ConfigureCamera configures a camera on a device
-func (a *App) ConfigureCamera(ip, username, password, cameraName, cameraURL string, algorithmType int) (bool, string) {
+// ConfigureCamera configures a camera on a device, merging or replacing the algorithms of an existing task by policy
+func (a *App) ConfigureCamera(ip, username, password, cameraName, cameraURL string, algorithms models.CameraAlgorithms) (bool, string) {
+	defer a.beginOperation()()
+
+	username, password = a.webCredentials(username, password)
+
+	release, err := lock.Default().TryAcquire(ip, lock.OperationCameraConfig)
+	if err != nil {
+		return false, err.Error()
+	}
+	defer release()
+
 	// 先登录获取token
-	token, err := a.deviceService.LoginToDevice(ip, username, password)
+	token, err := a.devices().LoginToDevice(ip, username, password)
 	if err != nil {
 		return false, fmt.Sprintf("登录失败: %v", err)
 	}
 
 	// 判断是新增还是修改
 	existingCamera := false
+	var currentTypes []int
 	cameras, err := a.cameraService.GetCameraTasksWithToken(ip, token)
 	if err == nil {
 		for _, camera := range cameras {
 			if camera.DeviceName == cameraName {
 				existingCamera = true
+				currentTypes = camera.Types
 				break
 			}
 		}
 	}
 
 	// 配置摄像头
-	return a.cameraService.ConfigureCamera(ip, token, cameraName, cameraURL, algorithmType, existingCamera)
+	types := camera.TaskTypes(models.ExcelRow{}, algorithms, currentTypes, existingCamera)
+	return a.cameraService.ConfigureCamera(ip, token, cameraName, cameraURL, types, existingCamera)
 }
 
 // GetCameraConfig gets camera configuration from a device
 func (a *App) GetCameraConfig(ip, username, password, taskID string) (models.Camera, error) {
+	username, password = a.webCredentials(username, password)
+
 	// 先登录获取token
-	token, err := a.deviceService.LoginToDevice(ip, username, password)
+	token, err := a.devices().LoginToDevice(ip, username, password)
 	if err != nil {
 		return models.Camera{}, err
 	}
@@ -229,9 +445,11 @@ func (a *App) GetCameraConfig(ip, username, password, taskID string) (models.Cam
 
 // GetCameraTasks gets all camera tasks from a device
 func (a *App) GetCameraTasks(ip, username, password string) ([]models.Camera, error) {
+	username, password = a.webCredentials(username, password)
+
 	// 创建用于获取token的函数
 	getTokenFunc := func(deviceIP, user, pass string) (string, error) {
-		return a.deviceService.LoginToDevice(deviceIP, user, pass)
+		return a.devices().LoginToDevice(deviceIP, user, pass)
 	}
 
 	// 获取摄像头任务列表
@@ -240,8 +458,18 @@ func (a *App) GetCameraTasks(ip, username, password string) ([]models.Camera, er
 
 // SetCameraIndex sets the index of a camera
 func (a *App) SetCameraIndex(ip, username, password, taskID string, index int) (bool, string) {
+	defer a.beginOperation()()
+
+	username, password = a.webCredentials(username, password)
+
+	release, err := lock.Default().TryAcquire(ip, lock.OperationCameraConfig)
+	if err != nil {
+		return false, err.Error()
+	}
+	defer release()
+
 	// 先登录获取token
-	token, err := a.deviceService.LoginToDevice(ip, username, password)
+	token, err := a.devices().LoginToDevice(ip, username, password)
 	if err != nil {
 		return false, fmt.Sprintf("登录失败: %v", err)
 	}
@@ -258,7 +486,23 @@ func (a *App) SetCameraIndex(ip, username, password, taskID string, index int) (
 
 // SyncDeviceTime synchronizes the time of devices with the current machine's time
 func (a *App) SyncDeviceTime(username, password string, deviceIPs []string) []models.TimeSyncResult {
-	return a.timeService.SyncDeviceTime(username, password, deviceIPs)
+	defer a.beginOperation()()
+
+	username, password = a.sshCredentials(username, password)
+
+	// 维护模式中的设备不参与批量时间同步
+	maintenance := a.devices().MaintenanceReasons(deviceIPs)
+	skipped := []models.TimeSyncResult{}
+	ips := make([]string, 0, len(deviceIPs))
+	for _, ip := range deviceIPs {
+		if reason, ok := maintenance[ip]; ok {
+			skipped = append(skipped, models.TimeSyncResult{IP: ip, Message: device.MaintenanceMessage(reason)})
+			continue
+		}
+		ips = append(ips, ip)
+	}
+
+	return append(skipped, a.timeService.SyncDeviceTime(username, password, ips)...)
 }
 
 // ParseExcelSheet parses an Excel sheet from base64 encoded file data
@@ -272,14 +516,49 @@ func (a *App) SaveExcelData(fileData string) (string, error) {
 }
 
 // ProcessExcelData processes Excel data rows for camera configuration
-func (a *App) ProcessExcelData(rows []models.ExcelRow, username, password, urlTemplate string, algorithmType int, region string) []models.CameraConfigResult {
-	return a.excelService.ProcessExcelData(rows, username, password, urlTemplate, algorithmType, region)
+func (a *App) ProcessExcelData(rows []models.ExcelRow, username, password, urlTemplate string, algorithms models.CameraAlgorithms, region string) []models.CameraConfigResult {
+	defer a.beginOperation()()
+
+	username, password = a.webCredentials(username, password)
+
+	return a.excelService.ProcessExcelData(rows, username, password, urlTemplate, algorithms, region)
+}
+
+// PlanCameraConfiguration compares Excel rows with the cameras configured on each device without changing them.
+// In reconcile mode tasks that are not in the sheet are listed for deletion.
+func (a *App) PlanCameraConfiguration(rows []models.ExcelRow, username, password, urlTemplate string, algorithms models.CameraAlgorithms, region string, reconcile bool) models.CameraPlan {
+	defer a.beginOperation()()
+
+	username, password = a.webCredentials(username, password)
+
+	getTokenFunc := func(deviceIP, user, pass string) (string, error) {
+		return a.devices().LoginToDevice(deviceIP, user, pass)
+	}
+	return a.cameraService.PlanCameraConfiguration(rows, getTokenFunc, username, password, urlTemplate, algorithms, region, reconcile)
+}
+
+// ApplyCameraPlan applies the approved entries of a camera configuration plan, deleting tasks requires confirmDelete
+func (a *App) ApplyCameraPlan(planID string, approvedIDs []string, confirmDelete bool, username, password string) ([]models.CameraConfigResult, error) {
+	defer a.beginOperation()()
+
+	username, password = a.webCredentials(username, password)
+
+	getTokenFunc := func(deviceIP, user, pass string) (string, error) {
+		return a.devices().LoginToDevice(deviceIP, user, pass)
+	}
+	return a.cameraService.ApplyCameraPlan(planID, approvedIDs, confirmDelete, getTokenFunc, username, password)
 }
 
 // BackupDevices backs up the configuration and database of all devices
 func (a *App) BackupDevices(username, password string, storageDir, areaDir string, selectIps []string) []models.BackupResult {
+	defer a.beginOperation()()
+
+	username, password = a.sshCredentials(username, password)
+
+	backups := a.backups()
+
 	// 从存储中获取备份设置
-	settings, err := a.backupService.GetBackupSettings()
+	settings, err := backups.GetBackupSettings()
 	if err != nil {
 		fmt.Printf("Warning: Failed to get backup settings: %v, using defaults\n", err)
 		settings = &models.BackupSettings{
@@ -291,10 +570,10 @@ func (a *App) BackupDevices(username, password string, storageDir, areaDir strin
 	}
 	settings.BackupPath = storageDir
 	settings.AreaPath = areaDir
-	a.backupService.SaveBackupSettings(settings)
+	backups.SaveBackupSettings(settings)
 
 	// 执行备份
-	results, err := a.backupService.BackupDevices(settings, username, password, selectIps)
+	results, err := backups.BackupDevices(settings, username, password, selectIps)
 	if err != nil {
 		fmt.Printf("Error performing backup: %v\n", err)
 		return []models.BackupResult{}
@@ -315,7 +594,11 @@ func (a *App) BackupDevices(username, password string, storageDir, areaDir strin
 
 // RestoreDevicesDB restores device databases from backup
 func (a *App) RestoreDevicesDB(username, password, storageDir, areaDir string, selectIps []string) []models.RestoreResult {
-	results, err := a.backupService.RestoreDevicesDB(username, password, storageDir, areaDir, selectIps)
+	defer a.beginOperation()()
+
+	username, password = a.sshCredentials(username, password)
+
+	results, err := a.backups().RestoreDevicesDB(username, password, storageDir, areaDir, selectIps)
 	if err != nil {
 		fmt.Printf("Error performing restore: %v\n", err)
 		return []models.RestoreResult{}
@@ -326,7 +609,7 @@ func (a *App) RestoreDevicesDB(username, password, storageDir, areaDir string, s
 // GetBackupSettings gets the current backup settings
 func (a *App) GetBackupSettings() models.BackupSettings {
 	// 从备份服务获取设置
-	settings, err := a.backupService.GetBackupSettings()
+	settings, err := a.backups().GetBackupSettings()
 	if err != nil {
 		fmt.Printf("Warning: Failed to get backup settings: %v, using defaults\n", err)
 		return models.BackupSettings{
@@ -342,47 +625,572 @@ func (a *App) GetBackupSettings() models.BackupSettings {
 // SaveBackupSettings saves backup settings
 func (a *App) SaveBackupSettings(settings models.BackupSettings) error {
 	// 调用服务保存设置
-	return a.backupService.SaveBackupSettings(&settings)
+	return a.backups().SaveBackupSettings(&settings)
 }
 
 // GetRegions returns all device regions
 func (a *App) GetRegions() []string {
-	a.mutex.RLock()
-	defer a.mutex.RUnlock()
-
-	if a.deviceService == nil {
+	devices := a.devices()
+	if devices == nil {
 		return []string{}
 	}
-	return a.deviceService.GetAllRegions()
-
+	return devices.GetAllRegions()
 }
 
-// UpdateDevicesFile uploads update files to devices with build time less than the selected build time
-func (a *App) UpdateDevicesFile(deviceIds []string, fileName string, fileBinary []byte, md5FileName string, md5FileBinary []byte, username string, password string) ([]models.UpdateResult, error) {
-	results, err := a.deviceService.UpdateDevicesFile(deviceIds, fileName, fileBinary, md5FileName, md5FileBinary, username, password)
+// UpdateDevicesFile streams an update file chosen with SelectUpdateFile to devices and verifies that they come back with the new build.
+// md5FilePath may be empty, a matching MD5 file is then generated. The file is imported into the package
+// repository first, so a bare binary is refused until an override is recorded for it with OverridePackageSignature.
+func (a *App) UpdateDevicesFile(deviceIds []string, filePath string, md5FilePath string, username string, password string, options models.UpgradeOptions) ([]models.UpdateResult, error) {
+	// The file goes through the package repository so the same signature rules apply as for repository packages
+	pkg, err := a.ImportPackage(filePath, md5FilePath, "", "")
 	if err != nil {
 		return nil, err
 	}
+	return a.UpdateDevicesFromPackage(deviceIds, pkg.ID, username, password, options)
+}
 
-	// Convert device.UpdateResult to models.UpdateResult
-	modelResults := make([]models.UpdateResult, len(results))
-	for i, result := range results {
-		modelResults[i] = models.UpdateResult{
-			IP:      result.IP,
-			Success: result.Success,
-			Message: result.Message,
+// PreflightUpdate checks the devices before an update and returns the go/no-go table, nothing is uploaded.
+// A file is imported into the package repository first so its size and manifest are taken into account.
+func (a *App) PreflightUpdate(deviceIds []string, filePath string, md5FilePath string, username string, password string, options models.UpgradeOptions) ([]models.PreflightResult, error) {
+	defer a.beginOperation()()
+
+	username, password = a.webCredentials(username, password)
+
+	if filePath != "" {
+		pkg, err := a.ImportPackage(filePath, md5FilePath, "", "")
+		if err != nil {
+			return nil, err
 		}
+		options = packageOptions(pkg, options)
+		filePath, _ = a.firmwareService.Paths(pkg)
+	}
+	return a.devices().PreflightDevices(deviceIds, filePath, username, password, options)
+}
+
+// emitUploadProgress forwards per-device upload progress to the frontend as "update:progress" events
+func (a *App) emitUploadProgress(progress models.UploadProgress) {
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, "update:progress", progress)
+	}
+}
+
+// UpdateDevicesFromPackage uploads a repository package to devices if its signature is trusted or overridden.
+// The package's build time is the expected build unless options name one.
+func (a *App) UpdateDevicesFromPackage(deviceIds []string, packageID string, username string, password string, options models.UpgradeOptions) ([]models.UpdateResult, error) {
+	defer a.beginOperation()()
+
+	username, password = a.webCredentials(username, password)
+
+	pkg, err := a.deployablePackage(packageID)
+	if err != nil {
+		return nil, err
+	}
+	options = packageOptions(pkg, options)
+
+	a.firmwareService.MarkUsed(pkg.ID)
+	filePath, md5FilePath := a.firmwareService.Paths(pkg)
+	job, err := a.devices().StartUpdateJob(deviceIds, pkg.ID, pkg.FileName, filePath, pkg.MD5FileName, md5FilePath, username, password, options)
+	if err != nil {
+		return nil, err
+	}
+	return a.devices().JobResults(job), nil
+}
+
+// PreviewBuildTarget lists the devices a version-targeted update would change and why others are skipped
+func (a *App) PreviewBuildTarget(target models.BuildTarget) (models.BuildTargetPreview, error) {
+	return a.devices().PreviewBuildTarget(target)
+}
+
+// UpdateDevicesByBuildTarget installs a repository package on the devices selected by a build target.
+// Devices already running the package's build are skipped, also if they reach it while the job runs.
+func (a *App) UpdateDevicesByBuildTarget(target models.BuildTarget, packageID string, username string, password string, options models.UpgradeOptions) ([]models.UpdateResult, error) {
+	defer a.beginOperation()()
+
+	username, password = a.webCredentials(username, password)
+
+	pkg, err := a.firmwareService.Get(packageID)
+	if err != nil {
+		return nil, err
+	}
+	if target.TargetBuild == "" {
+		target.TargetBuild = pkg.BuildTime
+	}
+
+	preview, err := a.devices().PreviewBuildTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	deviceIds := device.SelectedDeviceIDs(preview)
+	if len(deviceIds) == 0 {
+		return nil, fmt.Errorf("没有需要更新的设备")
+	}
+
+	if options.ExpectedBuild == "" {
+		options.ExpectedBuild = target.TargetBuild
 	}
+	options.SkipCurrent = true
+	return a.UpdateDevicesFromPackage(deviceIds, packageID, username, password, options)
+}
+
+// RollbackDevices restores the pre-upgrade snapshot on devices over SSH and verifies that their build reverted
+func (a *App) RollbackDevices(deviceIds []string, sshUsername string, sshPassword string) ([]models.UpdateResult, error) {
+	defer a.beginOperation()()
+
+	sshUsername, sshPassword = a.sshCredentials(sshUsername, sshPassword)
+
+	return a.devices().RollbackDevices(deviceIds, sshUsername, sshPassword)
+}
+
+// RollbackRolloutWave restores the pre-upgrade snapshot on all devices of a paused or aborted rollout's wave
+func (a *App) RollbackRolloutWave(id string, wave int, sshUsername string, sshPassword string) ([]models.UpdateResult, error) {
+	defer a.beginOperation()()
+
+	sshUsername, sshPassword = a.sshCredentials(sshUsername, sshPassword)
+
+	return a.rollouts().RollbackWave(id, wave, sshUsername, sshPassword)
+}
+
+// GetUpdateJobs returns the persisted update jobs of the current workspace, newest first
+func (a *App) GetUpdateJobs() []models.UpdateJob {
+	return a.devices().GetUpdateJobs()
+}
+
+// ResumeUpdateJob continues an interrupted update job on its remaining devices and retryable failures.
+// Finished and interrupted jobs do not keep passwords, so the credentials are entered again
+func (a *App) ResumeUpdateJob(id string, username string, password string, sshUsername string, sshPassword string) (models.UpdateJob, error) {
+	defer a.beginOperation()()
+
+	username, password = a.webCredentials(username, password)
+	sshUsername, sshPassword = a.sshCredentials(sshUsername, sshPassword)
 
-	return modelResults, nil
+	return a.devices().ResumeUpdateJob(id, username, password, sshUsername, sshPassword)
+}
+
+// GetUpdateJobReport lists the completed, failed, skipped and pending devices of an update job
+func (a *App) GetUpdateJobReport(id string) (models.UpdateJobReport, error) {
+	return a.devices().GetUpdateJobReport(id)
+}
+
+// DeleteUpdateJob removes an update job that is not running, devices still waiting to come online are no longer updated
+func (a *App) DeleteUpdateJob(id string) error {
+	return a.devices().DeleteUpdateJob(id)
 }
 
 // SetDevicesRegion sets the region for multiple devices
 func (a *App) SetDevicesRegion(deviceIDs []string, region string) error {
-	return a.deviceService.SetDevicesRegion(deviceIDs, region)
+	return a.devices().SetDevicesRegion(deviceIDs, region)
 }
 
 // SetDeviceRegion 设置单个设备的区域
 func (a *App) SetDeviceRegion(deviceID string, region string) error {
-	return a.deviceService.SetDeviceRegion(deviceID, region)
+	return a.devices().SetDeviceRegion(deviceID, region)
+}
+
+// GetWorkspaces returns all workspaces, including archived ones
+func (a *App) GetWorkspaces() []models.Workspace {
+	return a.workspaceService.List()
+}
+
+// GetCurrentWorkspace returns the active workspace
+func (a *App) GetCurrentWorkspace() models.Workspace {
+	return a.workspaceService.Current()
+}
+
+// CreateWorkspace creates an empty workspace
+func (a *App) CreateWorkspace(name, description string) (models.Workspace, error) {
+	return a.workspaceService.Create(name, description)
+}
+
+// CloneWorkspace creates a new workspace from a copy of an existing one
+func (a *App) CloneWorkspace(sourceID, name string) (models.Workspace, error) {
+	return a.workspaceService.Clone(sourceID, name)
+}
+
+// SwitchWorkspace activates a workspace and reopens the device inventory and settings from it
+func (a *App) SwitchWorkspace(id string) (models.Workspace, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if id == a.workspaceService.Current().ID {
+		return a.workspaceService.Current(), nil
+	}
+
+	// Background work keeps using the services of the active workspace until it has finished
+	if err := a.workspaceBusyLocked(); err != nil {
+		return models.Workspace{}, fmt.Errorf("无法切换工作区: %w", err)
+	}
+
+	ws, err := a.workspaceService.Switch(id)
+	if err != nil {
+		return models.Workspace{}, err
+	}
+
+	a.closeWorkspace()
+	a.openWorkspace(a.workspaceService.Dir(ws.ID))
+
+	fmt.Printf("Switched to workspace %s (%s)\n", ws.Name, ws.ID)
+	return ws, nil
+}
+
+// ArchiveWorkspace archives a workspace that is not active
+func (a *App) ArchiveWorkspace(id string) error {
+	return a.workspaceService.Archive(id)
+}
+
+// UnarchiveWorkspace restores an archived workspace
+func (a *App) UnarchiveWorkspace(id string) error {
+	return a.workspaceService.Unarchive(id)
+}
+
+// webCredentials fills blank device login credentials with the defaults of the active workspace
+func (a *App) webCredentials(username, password string) (string, string) {
+	defaults := a.workspaceService.Current().Credentials
+	return withDefault(username, defaults.Username), withDefault(password, defaults.Password)
+}
+
+// sshCredentials fills blank SSH credentials with the defaults of the active workspace
+func (a *App) sshCredentials(username, password string) (string, string) {
+	defaults := a.workspaceService.Current().Credentials
+	return withDefault(username, defaults.SSHUsername), withDefault(password, defaults.SSHPassword)
+}
+
+func withDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// SaveWorkspaceCredentials saves the default device credentials of a workspace
+func (a *App) SaveWorkspaceCredentials(id string, credentials models.WorkspaceCredentials) error {
+	return a.workspaceService.SaveCredentials(id, credentials)
+}
+
+// workspaceBundleFilters limits the file dialogs to workspace bundles
+var workspaceBundleFilters = []runtime.FileFilter{
+	{DisplayName: "Workspace Bundle (*.zip)", Pattern: "*.zip"},
+}
+
+// ExportWorkspace asks for a destination and exports a workspace bundle to it
+func (a *App) ExportWorkspace(id string) (string, error) {
+	destination, err := utils.SaveFileDialog(a.ctx, "导出工作区", "workspace.zip", workspaceBundleFilters)
+	if err != nil || destination == "" {
+		return "", err
+	}
+	if err := a.workspaceService.Export(id, destination); err != nil {
+		return "", err
+	}
+	return destination, nil
+}
+
+// ImportWorkspace asks for a workspace bundle and imports it as a new workspace
+func (a *App) ImportWorkspace() (models.Workspace, error) {
+	source, err := utils.OpenFileDialog(a.ctx, "导入工作区", workspaceBundleFilters)
+	if err != nil {
+		return models.Workspace{}, err
+	}
+	if source == "" {
+		return models.Workspace{}, fmt.Errorf("未选择文件")
+	}
+	return a.workspaceService.Import(source)
+}
+
+// CreateRollout stages a repository package with a rollout plan without starting it.
+// The plan verifies against the package's build time unless it names one itself.
+func (a *App) CreateRollout(name string, plan models.RolloutPlan, packageID string, username, password string) (models.Rollout, error) {
+	username, password = a.webCredentials(username, password)
+
+	pkg, err := a.deployablePackage(packageID)
+	if err != nil {
+		return models.Rollout{}, err
+	}
+	plan.Verify = packageOptions(pkg, plan.Verify)
+
+	rollout, err := a.rollouts().Create(name, plan, pkg.ID, username, password)
+	if err != nil {
+		return models.Rollout{}, err
+	}
+	a.firmwareService.MarkUsed(pkg.ID)
+	return rollout, nil
+}
+
+// GetRollouts returns all rollouts of the current workspace, newest first
+func (a *App) GetRollouts() []models.Rollout {
+	return a.rollouts().List()
+}
+
+// StartRollout starts a pending rollout with its canary wave
+func (a *App) StartRollout(id string) error {
+	return a.rollouts().Start(id)
+}
+
+// PauseRollout pauses a running rollout
+func (a *App) PauseRollout(id string) error {
+	return a.rollouts().Pause(id)
+}
+
+// ResumeRollout resumes a paused rollout
+func (a *App) ResumeRollout(id string) error {
+	return a.rollouts().Resume(id)
+}
+
+// AbortRollout stops a rollout permanently
+func (a *App) AbortRollout(id string) error {
+	return a.rollouts().Abort(id)
+}
+
+// SelectUpdateFile shows a dialog to pick an update binary or MD5 file, the path is passed to UpdateDevicesFile or ImportPackage
+func (a *App) SelectUpdateFile(title string) (string, error) {
+	return utils.OpenFileDialog(a.ctx, title, nil)
+}
+
+// ImportPackage adds an update binary or a signed package to the repository, an identical binary returns the existing package.
+// Notes and build time are taken from the manifest of a signed package.
+func (a *App) ImportPackage(filePath, md5FilePath, notes, buildTime string) (models.FirmwarePackage, error) {
+	if signing.IsBundle(filePath) {
+		pkg, _, err := a.firmwareService.ImportBundle(filePath)
+		return pkg, err
+	}
+	pkg, _, err := a.firmwareService.Import(filePath, md5FilePath, notes, buildTime)
+	return pkg, err
+}
+
+// deployablePackage returns a repository package if its signature is trusted or an override was recorded.
+// Every deployment that relies on an override is written to the audit log.
+func (a *App) deployablePackage(packageID string) (models.FirmwarePackage, error) {
+	pkg, err := a.firmwareService.Get(packageID)
+	if err != nil {
+		return models.FirmwarePackage{}, err
+	}
+	overridden, err := firmware.CheckDeployable(pkg, a.trustedKeys.List())
+	if err != nil {
+		return models.FirmwarePackage{}, fmt.Errorf("%w，如确需部署请在包仓库中记录放行原因", err)
+	}
+	if overridden {
+		if err := a.auditLog.Record(audit.ActionDeployOverride, packageTarget(pkg), pkg.Override.Reason); err != nil {
+			return models.FirmwarePackage{}, err
+		}
+	}
+	return pkg, nil
+}
+
+// packageOptions fills in the verification options a package implies
+func packageOptions(pkg models.FirmwarePackage, options models.UpgradeOptions) models.UpgradeOptions {
+	if options.ExpectedBuild == "" {
+		options.ExpectedBuild = pkg.BuildTime
+	}
+	if options.MinBuild == "" && pkg.Manifest != nil {
+		options.MinBuild = pkg.Manifest.MinBuild
+	}
+	// Detects a package that was modified or damaged on disk since it was imported
+	options.ExpectedSHA256 = pkg.SHA256
+	return options
+}
+
+// packageTarget describes a package in the audit log
+func packageTarget(pkg models.FirmwarePackage) string {
+	return fmt.Sprintf("%s (%s)", pkg.FileName, pkg.ID)
+}
+
+// ApplyBundle installs a multi-artifact bundle, either a signed archive or an unpacked bundle.json, on devices.
+// A bundle without a trusted signature is refused unless an override reason is given, which is written to the audit log.
+func (a *App) ApplyBundle(deviceIds []string, bundlePath string, username string, password string, options models.UpgradeOptions, overrideReason string) ([]models.BundleResult, error) {
+	defer a.beginOperation()()
+
+	username, password = a.webCredentials(username, password)
+
+	var bundle signing.ArtifactBundle
+	if signing.IsArtifactBundle(bundlePath) {
+		dir, err := os.MkdirTemp("", "bundle-*")
+		if err != nil {
+			return nil, fmt.Errorf("创建临时目录失败: %w", err)
+		}
+		defer os.RemoveAll(dir)
+		if bundle, err = signing.OpenArtifactBundle(bundlePath, dir); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if bundle, err = signing.LoadArtifactBundle(bundlePath); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := signing.VerifyBundleManifest(&bundle.Manifest, bundle.Signature, a.trustedKeys.List()); err != nil {
+		reason := strings.TrimSpace(overrideReason)
+		if reason == "" {
+			refused := &firmware.RefusedError{Target: "更新包 " + bundle.Manifest.Name, Err: err}
+			return nil, fmt.Errorf("%w，如确需部署请填写放行原因", refused)
+		}
+		if err := a.auditLog.Record(audit.ActionDeployOverride, "bundle "+bundle.Manifest.Name, reason); err != nil {
+			return nil, err
+		}
+	}
+	return a.devices().ApplyBundle(deviceIds, bundle.Manifest, bundle.Dir, username, password, options)
+}
+
+// OverridePackageSignature approves an unsigned or unverifiable package for deployment.
+// The override is written to the audit log first and refused if it cannot be recorded.
+func (a *App) OverridePackageSignature(id, reason string) (models.FirmwarePackage, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return models.FirmwarePackage{}, fmt.Errorf("请填写放行原因")
+	}
+	pkg, err := a.firmwareService.Get(id)
+	if err != nil {
+		return models.FirmwarePackage{}, err
+	}
+	if err := a.auditLog.Record(audit.ActionOverridePackage, packageTarget(pkg), reason); err != nil {
+		return models.FirmwarePackage{}, err
+	}
+	return a.firmwareService.SetOverride(id, reason)
+}
+
+// GetTrustedKeys returns the public keys whose package signatures are accepted
+func (a *App) GetTrustedKeys() []models.TrustedKey {
+	return a.trustedKeys.List()
+}
+
+// AddTrustedKey trusts a base64 ed25519 public key, the change is written to the audit log
+func (a *App) AddTrustedKey(name, publicKey string) (models.TrustedKey, error) {
+	key, err := a.trustedKeys.Add(name, publicKey)
+	if err != nil {
+		return models.TrustedKey{}, err
+	}
+	if err := a.auditLog.Record(audit.ActionTrustKey, fmt.Sprintf("%s (%s)", key.Name, key.ID), ""); err != nil {
+		a.trustedKeys.Remove(key.ID)
+		return models.TrustedKey{}, err
+	}
+	return key, nil
+}
+
+// RemoveTrustedKey stops trusting a key, the change is written to the audit log
+func (a *App) RemoveTrustedKey(id string) error {
+	key, err := a.trustedKeys.Remove(id)
+	if err != nil {
+		return err
+	}
+	return a.auditLog.Record(audit.ActionUntrustKey, fmt.Sprintf("%s (%s)", key.Name, key.ID), "")
+}
+
+// GetAuditLog returns the audit log, newest first
+func (a *App) GetAuditLog() ([]models.AuditEntry, error) {
+	return a.auditLog.List()
+}
+
+// GetPackages returns all packages in the repository, newest first
+func (a *App) GetPackages() []models.FirmwarePackage {
+	return a.firmwareService.List()
+}
+
+// SetPackageNotes updates the notes of a package
+func (a *App) SetPackageNotes(id, notes string) (models.FirmwarePackage, error) {
+	return a.firmwareService.SetNotes(id, notes)
+}
+
+// SetPackagePinned protects a package from garbage collection
+func (a *App) SetPackagePinned(id string, pinned bool) (models.FirmwarePackage, error) {
+	return a.firmwareService.SetPinned(id, pinned)
+}
+
+// DeletePackage removes a package from the repository
+func (a *App) DeletePackage(id string) error {
+	return a.firmwareService.Delete(id)
+}
+
+// CollectPackageGarbage removes unpinned packages unused for the given number of days (0 uses the default).
+// Packages still needed by a schedule, update job or rollout in any workspace are kept.
+func (a *App) CollectPackageGarbage(unusedDays int) ([]models.FirmwarePackage, error) {
+	inUse, err := a.packageReferences()
+	if err != nil {
+		return nil, fmt.Errorf("无法确定仍在使用的更新包: %w", err)
+	}
+	return a.firmwareService.CollectGarbage(unusedDays, inUse)
+}
+
+// packageReferences collects the packages needed by unfinished work in all workspaces, the package repository is shared
+func (a *App) packageReferences() ([]models.PackageReference, error) {
+	var inUse []models.PackageReference
+	for _, ws := range a.workspaceService.List() {
+		dir := a.workspaceService.Dir(ws.ID)
+		schedules, err := schedule.PackageReferences(dir)
+		if err != nil {
+			return nil, fmt.Errorf("工作区 %s: %w", ws.Name, err)
+		}
+		jobs, err := device.PackageReferences(dir)
+		if err != nil {
+			return nil, fmt.Errorf("工作区 %s: %w", ws.Name, err)
+		}
+		rollouts, err := rollout.PackageReferences(filepath.Join(dir, "rollouts"))
+		if err != nil {
+			return nil, fmt.Errorf("工作区 %s: %w", ws.Name, err)
+		}
+		inUse = append(inUse, schedules...)
+		inUse = append(inUse, jobs...)
+		inUse = append(inUse, rollouts...)
+	}
+	return inUse, nil
+}
+
+// ScheduleUpdate schedules a repository package for devices at runAt ("2006-01-02 15:04", empty for now),
+// optionally only inside the maintenance windows of each device's region
+func (a *App) ScheduleUpdate(name string, deviceIds []string, packageID string, runAt string, useWindows bool, username, password string, options models.UpgradeOptions) (models.ScheduledUpdate, error) {
+	username, password = a.webCredentials(username, password)
+
+	pkg, err := a.deployablePackage(packageID)
+	if err != nil {
+		return models.ScheduledUpdate{}, err
+	}
+	options = packageOptions(pkg, options)
+
+	filePath, md5FilePath := a.firmwareService.Paths(pkg)
+	scheduled, err := a.schedules().Create(models.ScheduledUpdate{
+		Name:        name,
+		RunAt:       runAt,
+		UseWindows:  useWindows,
+		DeviceIDs:   deviceIds,
+		PackageID:   pkg.ID,
+		FileName:    pkg.FileName,
+		FilePath:    filePath,
+		MD5FileName: pkg.MD5FileName,
+		MD5FilePath: md5FilePath,
+		Username:    username,
+		Password:    password,
+		Options:     options,
+	})
+	if err != nil {
+		return models.ScheduledUpdate{}, err
+	}
+	a.firmwareService.MarkUsed(pkg.ID)
+	return scheduled, nil
+}
+
+// GetScheduledUpdates returns the scheduled updates of the current workspace, newest first
+func (a *App) GetScheduledUpdates() []models.ScheduledUpdate {
+	return a.schedules().List()
+}
+
+// PauseScheduledUpdate stops a scheduled update from starting further batches
+func (a *App) PauseScheduledUpdate(id string) error {
+	return a.schedules().Pause(id)
+}
+
+// ResumeScheduledUpdate lets a paused scheduled update run again
+func (a *App) ResumeScheduledUpdate(id string) error {
+	return a.schedules().Resume(id)
+}
+
+// CancelScheduledUpdate stops a scheduled update permanently
+func (a *App) CancelScheduledUpdate(id string) error {
+	return a.schedules().Cancel(id)
+}
+
+// GetMaintenanceWindows returns the per-region maintenance windows
+func (a *App) GetMaintenanceWindows() []models.MaintenanceWindow {
+	return a.schedules().GetWindows()
+}
+
+// SaveMaintenanceWindows replaces the per-region maintenance windows
+func (a *App) SaveMaintenanceWindows(windows []models.MaintenanceWindow) error {
+	return a.schedules().SaveWindows(windows)
 }
@@ -0,0 +1,184 @@
+// Command package-signer creates ed25519 keys and signed update packages for application-updater.
+//
+//	package-signer keygen -out release
+//	package-signer sign -key release.key -binary application-web -target-build "2024-05-01 10:00:00" -out application-web.aupkg
+//	package-signer verify -pub release.pub application-web.aupkg
+//	package-signer sign-bundle -key release.key -manifest release/bundle.json -out release.aubundle
+//
+// The public key (release.pub) is added to the trusted keys in the updater's settings.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"application-updater/internal/models"
+	"application-updater/internal/services/signing"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "keygen":
+		err = keygen(os.Args[2:])
+	case "sign":
+		err = sign(os.Args[2:])
+	case "verify":
+		err = verify(os.Args[2:])
+	case "sign-bundle":
+		err = signBundle(os.Args[2:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: package-signer keygen|sign|verify|sign-bundle [flags]")
+}
+
+// keygen writes <out>.key (private, keep secret) and <out>.pub
+func keygen(args []string) error {
+	flags := flag.NewFlagSet("keygen", flag.ExitOnError)
+	out := flags.String("out", "signing", "path prefix of the key files")
+	flags.Parse(args)
+
+	publicKey, privateKey, err := signing.GenerateKey()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out+".key", []byte(privateKey+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+	if err := os.WriteFile(*out+".pub", []byte(publicKey+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write public key: %w", err)
+	}
+
+	parsed, _ := signing.ParsePublicKey(publicKey)
+	fmt.Printf("Key %s written to %s.key and %s.pub\n", signing.KeyID(parsed), *out, *out)
+	fmt.Printf("Public key: %s\n", publicKey)
+	return nil
+}
+
+// sign bundles a binary with its manifest and signature
+func sign(args []string) error {
+	flags := flag.NewFlagSet("sign", flag.ExitOnError)
+	keyPath := flags.String("key", "", "private key file written by keygen")
+	binary := flags.String("binary", "", "application binary to sign")
+	targetBuild := flags.String("target-build", "", "buildTime devices report after installing the package")
+	minBuild := flags.String("min-build", "", "oldest buildTime that may be upgraded directly")
+	notes := flags.String("notes", "", "release notes")
+	out := flags.String("out", "", "package file to write (default <binary>"+signing.BundleExtension+")")
+	flags.Parse(args)
+
+	if *keyPath == "" || *binary == "" {
+		return fmt.Errorf("-key and -binary are required")
+	}
+	privateKey, err := readKey(*keyPath)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		*out = *binary + signing.BundleExtension
+	}
+
+	manifest, err := signing.WriteBundle(*out, *binary, models.PackageManifest{
+		TargetBuild:  *targetBuild,
+		MinBuild:     *minBuild,
+		ReleaseNotes: *notes,
+	}, privateKey)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Signed %s (sha256 %s) with key %s: %s\n", manifest.FileName, manifest.SHA256, manifest.KeyID, *out)
+	return nil
+}
+
+// signBundle signs a multi-artifact bundle, the files are resolved relative to the manifest
+func signBundle(args []string) error {
+	flags := flag.NewFlagSet("sign-bundle", flag.ExitOnError)
+	keyPath := flags.String("key", "", "private key file written by keygen")
+	manifestPath := flags.String("manifest", "", "bundle.json describing the artifacts")
+	out := flags.String("out", "", "bundle file to write (default <manifest directory>"+signing.ArtifactBundleExtension+")")
+	flags.Parse(args)
+
+	if *keyPath == "" || *manifestPath == "" {
+		return fmt.Errorf("-key and -manifest are required")
+	}
+	privateKey, err := readKey(*keyPath)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		dir, err := filepath.Abs(filepath.Dir(*manifestPath))
+		if err != nil {
+			return err
+		}
+		*out = dir + signing.ArtifactBundleExtension
+	}
+
+	manifest, err := signing.WriteArtifactBundle(*out, *manifestPath, privateKey)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Signed bundle %s (%d artifacts) with key %s: %s\n", manifest.Name, len(manifest.Artifacts), manifest.KeyID, *out)
+	return nil
+}
+
+// verify checks a package against a single public key
+func verify(args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	pubPath := flags.String("pub", "", "public key file written by keygen")
+	flags.Parse(args)
+
+	if *pubPath == "" || flags.NArg() != 1 {
+		return fmt.Errorf("usage: package-signer verify -pub <key.pub> <package>")
+	}
+	publicKey, err := readKey(*pubPath)
+	if err != nil {
+		return err
+	}
+	parsed, err := signing.ParsePublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "package-verify-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	bundle, err := signing.OpenBundle(flags.Arg(0), dir)
+	if err != nil {
+		return err
+	}
+	key := models.TrustedKey{ID: signing.KeyID(parsed), PublicKey: publicKey, AddedAt: time.Now().Format(time.RFC3339)}
+	if _, err := signing.VerifyManifest(&bundle.Manifest, bundle.Signature, []models.TrustedKey{key}); err != nil {
+		return err
+	}
+	fmt.Printf("OK: %s, target build %q, min build %q, signed %s\n",
+		bundle.Manifest.FileName, bundle.Manifest.TargetBuild, bundle.Manifest.MinBuild, bundle.Manifest.SignedAt)
+	return nil
+}
+
+func readKey(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read key: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
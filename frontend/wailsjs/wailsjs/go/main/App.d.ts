@@ -2,16 +2,44 @@
 // This file is automatically generated. DO NOT EDIT
 import {models} from '../models';
 
+export function AbortRollout(arg1:string):Promise<void>;
+
 export function AddDevice(arg1:string,arg2:string):Promise<models.Device>;
 
+export function AddTrustedKey(arg1:string,arg2:string):Promise<models.TrustedKey>;
+
+export function ApplyBundle(arg1:Array<string>,arg2:string,arg3:string,arg4:string,arg5:models.UpgradeOptions,arg6:string):Promise<Array<models.BundleResult>>;
+
+export function ApplyCameraPlan(arg1:string,arg2:Array<string>,arg3:boolean,arg4:string,arg5:string):Promise<Array<models.CameraConfigResult>>;
+
+export function ArchiveWorkspace(arg1:string):Promise<void>;
+
 export function BackupDevices(arg1:string,arg2:string,arg3:string,arg4:string,arg5:Array<string>):Promise<Array<models.BackupResult>>;
 
+export function CancelScheduledUpdate(arg1:string):Promise<void>;
+
 export function ClearDevices():Promise<void>;
 
-export function ConfigureCamera(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:number):Promise<boolean|string>;
+export function CloneWorkspace(arg1:string,arg2:string):Promise<models.Workspace>;
+
+export function CollectPackageGarbage(arg1:number):Promise<Array<models.FirmwarePackage>>;
+
+export function ConfigureCamera(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:models.CameraAlgorithms):Promise<boolean|string>;
+
+export function CreateRollout(arg1:string,arg2:models.RolloutPlan,arg3:string,arg4:string,arg5:string):Promise<models.Rollout>;
+
+export function CreateWorkspace(arg1:string,arg2:string):Promise<models.Workspace>;
+
+export function DeletePackage(arg1:string):Promise<void>;
+
+export function DeleteUpdateJob(arg1:string):Promise<void>;
+
+export function ExportWorkspace(arg1:string):Promise<string>;
 
 export function GetAllDevices():Promise<Array<models.Device>>;
 
+export function GetAuditLog():Promise<Array<models.AuditEntry>>;
+
 export function GetBackupSettings():Promise<models.BackupSettings>;
 
 export function GetCameraConfig(arg1:string,arg2:string,arg3:string,arg4:string):Promise<models.Camera>;
@@ -20,38 +48,132 @@ export function GetCameraTasks(arg1:string,arg2:string,arg3:string):Promise<Arra
 
 export function GetCurrentRegion():Promise<string>;
 
+export function GetCurrentWorkspace():Promise<models.Workspace>;
+
+export function GetDeletedDevices():Promise<Array<models.Device>>;
+
+export function GetDeviceLocks():Promise<Array<models.DeviceLock>>;
+
+export function GetDeviceSettings():Promise<models.DeviceSettings>;
+
+export function GetDeviceTags():Promise<Array<string>>;
+
 export function GetDevices():Promise<Array<models.Device>>;
 
+export function GetMaintenanceWindows():Promise<Array<models.MaintenanceWindow>>;
+
+export function GetPackages():Promise<Array<models.FirmwarePackage>>;
+
 export function GetRegions():Promise<Array<string>>;
 
+export function GetRollouts():Promise<Array<models.Rollout>>;
+
+export function GetScheduledUpdates():Promise<Array<models.ScheduledUpdate>>;
+
+export function GetTransferSettings():Promise<models.TransferSettings>;
+
+export function GetTrustedKeys():Promise<Array<models.TrustedKey>>;
+
+export function GetUpdateJobReport(arg1:string):Promise<models.UpdateJobReport>;
+
+export function GetUpdateJobs():Promise<Array<models.UpdateJob>>;
+
+export function GetWorkspaces():Promise<Array<models.Workspace>>;
+
+export function ImportPackage(arg1:string,arg2:string,arg3:string,arg4:string):Promise<models.FirmwarePackage>;
+
+export function ImportWorkspace():Promise<models.Workspace>;
+
 export function LoginToDevice(arg1:string,arg2:string,arg3:string):Promise<boolean|string>;
 
+export function OverridePackageSignature(arg1:string,arg2:string):Promise<models.FirmwarePackage>;
+
 export function ParseExcelSheet(arg1:string,arg2:number):Promise<Array<models.ExcelRow>>;
 
-export function ProcessExcelData(arg1:Array<models.ExcelRow>,arg2:string,arg3:string,arg4:string,arg5:number,arg6:string):Promise<Array<models.CameraConfigResult>>;
+export function PauseRollout(arg1:string):Promise<void>;
+
+export function PauseScheduledUpdate(arg1:string):Promise<void>;
+
+export function PlanCameraConfiguration(arg1:Array<models.ExcelRow>,arg2:string,arg3:string,arg4:string,arg5:models.CameraAlgorithms,arg6:string,arg7:boolean):Promise<models.CameraPlan>;
+
+export function PreflightUpdate(arg1:Array<string>,arg2:string,arg3:string,arg4:string,arg5:string,arg6:models.UpgradeOptions):Promise<Array<models.PreflightResult>>;
+
+export function PreviewBuildTarget(arg1:models.BuildTarget):Promise<models.BuildTargetPreview>;
+
+export function ProcessExcelData(arg1:Array<models.ExcelRow>,arg2:string,arg3:string,arg4:string,arg5:models.CameraAlgorithms,arg6:string):Promise<Array<models.CameraConfigResult>>;
+
+export function PurgeDeletedDevices():Promise<number>;
+
+export function QueryDevices(arg1:models.DeviceQuery):Promise<models.DevicePage>;
 
 export function RefreshDevices():Promise<Array<models.Device>>;
 
 export function RemoveDevice(arg1:string):Promise<void>;
 
+export function RemoveDevices(arg1:Array<string>):Promise<void>;
+
+export function RemoveTrustedKey(arg1:string):Promise<void>;
+
+export function RestoreDevices(arg1:Array<string>):Promise<void>;
+
 export function RestoreDevicesDB(arg1:string,arg2:string,arg3:string,arg4:string,arg5:Array<string>):Promise<Array<models.RestoreResult>>;
 
+export function ResumeRollout(arg1:string):Promise<void>;
+
+export function ResumeScheduledUpdate(arg1:string):Promise<void>;
+
+export function ResumeUpdateJob(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<models.UpdateJob>;
+
+export function RollbackDevices(arg1:Array<string>,arg2:string,arg3:string):Promise<Array<models.UpdateResult>>;
+
+export function RollbackRolloutWave(arg1:string,arg2:number,arg3:string,arg4:string):Promise<Array<models.UpdateResult>>;
+
 export function SaveBackupSettings(arg1:models.BackupSettings):Promise<void>;
 
+export function SaveDeviceSettings(arg1:models.DeviceSettings):Promise<void>;
+
 export function SaveExcelData(arg1:string):Promise<string>;
 
+export function SaveMaintenanceWindows(arg1:Array<models.MaintenanceWindow>):Promise<void>;
+
+export function SaveTransferSettings(arg1:models.TransferSettings):Promise<void>;
+
+export function SaveWorkspaceCredentials(arg1:string,arg2:models.WorkspaceCredentials):Promise<void>;
+
 export function ScanIPRange(arg1:string,arg2:string):Promise<Array<models.Device>>;
 
+export function ScheduleUpdate(arg1:string,arg2:Array<string>,arg3:string,arg4:string,arg5:boolean,arg6:string,arg7:string,arg8:models.UpgradeOptions):Promise<models.ScheduledUpdate>;
+
 export function SelectFolder():Promise<string>;
 
+export function SelectUpdateFile(arg1:string):Promise<string>;
+
 export function SetCameraIndex(arg1:string,arg2:string,arg3:string,arg4:string,arg5:number):Promise<boolean|string>;
 
 export function SetDeviceRegion(arg1:string,arg2:string):Promise<void>;
 
+export function SetDevicesMaintenance(arg1:Array<string>,arg2:boolean,arg3:string):Promise<void>;
+
 export function SetDevicesRegion(arg1:Array<string>,arg2:string):Promise<void>;
 
+export function SetPackageNotes(arg1:string,arg2:string):Promise<models.FirmwarePackage>;
+
+export function SetPackagePinned(arg1:string,arg2:boolean):Promise<models.FirmwarePackage>;
+
 export function SetRegionFilter(arg1:string):Promise<Array<models.Device>>;
 
+export function StartRollout(arg1:string):Promise<void>;
+
+export function SwitchWorkspace(arg1:string):Promise<models.Workspace>;
+
 export function SyncDeviceTime(arg1:string,arg2:string,arg3:Array<string>):Promise<Array<models.TimeSyncResult>>;
 
-export function UpdateDevicesFile(arg1:Array<string>,arg2:string,arg3:Array<number>,arg4:string,arg5:Array<number>,arg6:string,arg7:string):Promise<Array<models.UpdateResult>>;
+export function UnarchiveWorkspace(arg1:string):Promise<void>;
+
+export function UpdateDeviceDetails(arg1:string,arg2:string,arg3:string,arg4:Array<string>):Promise<models.Device>;
+
+export function UpdateDevicesByBuildTarget(arg1:models.BuildTarget,arg2:string,arg3:string,arg4:string,arg5:models.UpgradeOptions):Promise<Array<models.UpdateResult>>;
+
+export function UpdateDevicesFile(arg1:Array<string>,arg2:string,arg3:string,arg4:string,arg5:string,arg6:models.UpgradeOptions):Promise<Array<models.UpdateResult>>;
+
+export function UpdateDevicesFromPackage(arg1:Array<string>,arg2:string,arg3:string,arg4:string,arg5:models.UpgradeOptions):Promise<Array<models.UpdateResult>>;
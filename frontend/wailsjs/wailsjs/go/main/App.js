@@ -2,26 +2,82 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AbortRollout(arg1) {
+  return window['go']['main']['App']['AbortRollout'](arg1);
+}
+
 export function AddDevice(arg1, arg2) {
   return window['go']['main']['App']['AddDevice'](arg1, arg2);
 }
 
+export function AddTrustedKey(arg1, arg2) {
+  return window['go']['main']['App']['AddTrustedKey'](arg1, arg2);
+}
+
+export function ApplyBundle(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['ApplyBundle'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
+export function ApplyCameraPlan(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['ApplyCameraPlan'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function ArchiveWorkspace(arg1) {
+  return window['go']['main']['App']['ArchiveWorkspace'](arg1);
+}
+
 export function BackupDevices(arg1, arg2, arg3, arg4, arg5) {
   return window['go']['main']['App']['BackupDevices'](arg1, arg2, arg3, arg4, arg5);
 }
 
+export function CancelScheduledUpdate(arg1) {
+  return window['go']['main']['App']['CancelScheduledUpdate'](arg1);
+}
+
 export function ClearDevices() {
   return window['go']['main']['App']['ClearDevices']();
 }
 
+export function CloneWorkspace(arg1, arg2) {
+  return window['go']['main']['App']['CloneWorkspace'](arg1, arg2);
+}
+
+export function CollectPackageGarbage(arg1) {
+  return window['go']['main']['App']['CollectPackageGarbage'](arg1);
+}
+
 export function ConfigureCamera(arg1, arg2, arg3, arg4, arg5, arg6) {
   return window['go']['main']['App']['ConfigureCamera'](arg1, arg2, arg3, arg4, arg5, arg6);
 }
 
+export function CreateRollout(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['CreateRollout'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function CreateWorkspace(arg1, arg2) {
+  return window['go']['main']['App']['CreateWorkspace'](arg1, arg2);
+}
+
+export function DeletePackage(arg1) {
+  return window['go']['main']['App']['DeletePackage'](arg1);
+}
+
+export function DeleteUpdateJob(arg1) {
+  return window['go']['main']['App']['DeleteUpdateJob'](arg1);
+}
+
+export function ExportWorkspace(arg1) {
+  return window['go']['main']['App']['ExportWorkspace'](arg1);
+}
+
 export function GetAllDevices() {
   return window['go']['main']['App']['GetAllDevices']();
 }
 
+export function GetAuditLog() {
+  return window['go']['main']['App']['GetAuditLog']();
+}
+
 export function GetBackupSettings() {
   return window['go']['main']['App']['GetBackupSettings']();
 }
@@ -38,26 +94,122 @@ export function GetCurrentRegion() {
   return window['go']['main']['App']['GetCurrentRegion']();
 }
 
+export function GetCurrentWorkspace() {
+  return window['go']['main']['App']['GetCurrentWorkspace']();
+}
+
+export function GetDeletedDevices() {
+  return window['go']['main']['App']['GetDeletedDevices']();
+}
+
+export function GetDeviceLocks() {
+  return window['go']['main']['App']['GetDeviceLocks']();
+}
+
+export function GetDeviceSettings() {
+  return window['go']['main']['App']['GetDeviceSettings']();
+}
+
+export function GetDeviceTags() {
+  return window['go']['main']['App']['GetDeviceTags']();
+}
+
 export function GetDevices() {
   return window['go']['main']['App']['GetDevices']();
 }
 
+export function GetMaintenanceWindows() {
+  return window['go']['main']['App']['GetMaintenanceWindows']();
+}
+
+export function GetPackages() {
+  return window['go']['main']['App']['GetPackages']();
+}
+
 export function GetRegions() {
   return window['go']['main']['App']['GetRegions']();
 }
 
+export function GetRollouts() {
+  return window['go']['main']['App']['GetRollouts']();
+}
+
+export function GetScheduledUpdates() {
+  return window['go']['main']['App']['GetScheduledUpdates']();
+}
+
+export function GetTransferSettings() {
+  return window['go']['main']['App']['GetTransferSettings']();
+}
+
+export function GetTrustedKeys() {
+  return window['go']['main']['App']['GetTrustedKeys']();
+}
+
+export function GetUpdateJobReport(arg1) {
+  return window['go']['main']['App']['GetUpdateJobReport'](arg1);
+}
+
+export function GetUpdateJobs() {
+  return window['go']['main']['App']['GetUpdateJobs']();
+}
+
+export function GetWorkspaces() {
+  return window['go']['main']['App']['GetWorkspaces']();
+}
+
+export function ImportPackage(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['ImportPackage'](arg1, arg2, arg3, arg4);
+}
+
+export function ImportWorkspace() {
+  return window['go']['main']['App']['ImportWorkspace']();
+}
+
 export function LoginToDevice(arg1, arg2, arg3) {
   return window['go']['main']['App']['LoginToDevice'](arg1, arg2, arg3);
 }
 
+export function OverridePackageSignature(arg1, arg2) {
+  return window['go']['main']['App']['OverridePackageSignature'](arg1, arg2);
+}
+
 export function ParseExcelSheet(arg1, arg2) {
   return window['go']['main']['App']['ParseExcelSheet'](arg1, arg2);
 }
 
+export function PauseRollout(arg1) {
+  return window['go']['main']['App']['PauseRollout'](arg1);
+}
+
+export function PauseScheduledUpdate(arg1) {
+  return window['go']['main']['App']['PauseScheduledUpdate'](arg1);
+}
+
+export function PlanCameraConfiguration(arg1, arg2, arg3, arg4, arg5, arg6, arg7) {
+  return window['go']['main']['App']['PlanCameraConfiguration'](arg1, arg2, arg3, arg4, arg5, arg6, arg7);
+}
+
+export function PreflightUpdate(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['PreflightUpdate'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
+export function PreviewBuildTarget(arg1) {
+  return window['go']['main']['App']['PreviewBuildTarget'](arg1);
+}
+
 export function ProcessExcelData(arg1, arg2, arg3, arg4, arg5, arg6) {
   return window['go']['main']['App']['ProcessExcelData'](arg1, arg2, arg3, arg4, arg5, arg6);
 }
 
+export function PurgeDeletedDevices() {
+  return window['go']['main']['App']['PurgeDeletedDevices']();
+}
+
+export function QueryDevices(arg1) {
+  return window['go']['main']['App']['QueryDevices'](arg1);
+}
+
 export function RefreshDevices() {
   return window['go']['main']['App']['RefreshDevices']();
 }
@@ -66,26 +218,82 @@ export function RemoveDevice(arg1) {
   return window['go']['main']['App']['RemoveDevice'](arg1);
 }
 
+export function RemoveDevices(arg1) {
+  return window['go']['main']['App']['RemoveDevices'](arg1);
+}
+
+export function RemoveTrustedKey(arg1) {
+  return window['go']['main']['App']['RemoveTrustedKey'](arg1);
+}
+
+export function RestoreDevices(arg1) {
+  return window['go']['main']['App']['RestoreDevices'](arg1);
+}
+
 export function RestoreDevicesDB(arg1, arg2, arg3, arg4, arg5) {
   return window['go']['main']['App']['RestoreDevicesDB'](arg1, arg2, arg3, arg4, arg5);
 }
 
+export function ResumeRollout(arg1) {
+  return window['go']['main']['App']['ResumeRollout'](arg1);
+}
+
+export function ResumeScheduledUpdate(arg1) {
+  return window['go']['main']['App']['ResumeScheduledUpdate'](arg1);
+}
+
+export function ResumeUpdateJob(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['ResumeUpdateJob'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function RollbackDevices(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RollbackDevices'](arg1, arg2, arg3);
+}
+
+export function RollbackRolloutWave(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['RollbackRolloutWave'](arg1, arg2, arg3, arg4);
+}
+
 export function SaveBackupSettings(arg1) {
   return window['go']['main']['App']['SaveBackupSettings'](arg1);
 }
 
+export function SaveDeviceSettings(arg1) {
+  return window['go']['main']['App']['SaveDeviceSettings'](arg1);
+}
+
 export function SaveExcelData(arg1) {
   return window['go']['main']['App']['SaveExcelData'](arg1);
 }
 
+export function SaveMaintenanceWindows(arg1) {
+  return window['go']['main']['App']['SaveMaintenanceWindows'](arg1);
+}
+
+export function SaveTransferSettings(arg1) {
+  return window['go']['main']['App']['SaveTransferSettings'](arg1);
+}
+
+export function SaveWorkspaceCredentials(arg1, arg2) {
+  return window['go']['main']['App']['SaveWorkspaceCredentials'](arg1, arg2);
+}
+
 export function ScanIPRange(arg1, arg2) {
   return window['go']['main']['App']['ScanIPRange'](arg1, arg2);
 }
 
+export function ScheduleUpdate(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8) {
+  return window['go']['main']['App']['ScheduleUpdate'](arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8);
+}
+
 export function SelectFolder() {
   return window['go']['main']['App']['SelectFolder']();
 }
 
+export function SelectUpdateFile(arg1) {
+  return window['go']['main']['App']['SelectUpdateFile'](arg1);
+}
+
 export function SetCameraIndex(arg1, arg2, arg3, arg4, arg5) {
   return window['go']['main']['App']['SetCameraIndex'](arg1, arg2, arg3, arg4, arg5);
 }
@@ -94,18 +302,54 @@ export function SetDeviceRegion(arg1, arg2) {
   return window['go']['main']['App']['SetDeviceRegion'](arg1, arg2);
 }
 
+export function SetDevicesMaintenance(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SetDevicesMaintenance'](arg1, arg2, arg3);
+}
+
 export function SetDevicesRegion(arg1, arg2) {
   return window['go']['main']['App']['SetDevicesRegion'](arg1, arg2);
 }
 
+export function SetPackageNotes(arg1, arg2) {
+  return window['go']['main']['App']['SetPackageNotes'](arg1, arg2);
+}
+
+export function SetPackagePinned(arg1, arg2) {
+  return window['go']['main']['App']['SetPackagePinned'](arg1, arg2);
+}
+
 export function SetRegionFilter(arg1) {
   return window['go']['main']['App']['SetRegionFilter'](arg1);
 }
 
+export function StartRollout(arg1) {
+  return window['go']['main']['App']['StartRollout'](arg1);
+}
+
+export function SwitchWorkspace(arg1) {
+  return window['go']['main']['App']['SwitchWorkspace'](arg1);
+}
+
 export function SyncDeviceTime(arg1, arg2, arg3) {
   return window['go']['main']['App']['SyncDeviceTime'](arg1, arg2, arg3);
 }
 
-export function UpdateDevicesFile(arg1, arg2, arg3, arg4, arg5, arg6, arg7) {
-  return window['go']['main']['App']['UpdateDevicesFile'](arg1, arg2, arg3, arg4, arg5, arg6, arg7);
+export function UnarchiveWorkspace(arg1) {
+  return window['go']['main']['App']['UnarchiveWorkspace'](arg1);
+}
+
+export function UpdateDeviceDetails(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['UpdateDeviceDetails'](arg1, arg2, arg3, arg4);
+}
+
+export function UpdateDevicesByBuildTarget(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['UpdateDevicesByBuildTarget'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function UpdateDevicesFile(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['UpdateDevicesFile'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
+export function UpdateDevicesFromPackage(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['UpdateDevicesFromPackage'](arg1, arg2, arg3, arg4, arg5);
 }
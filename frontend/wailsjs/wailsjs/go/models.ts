@@ -1,5 +1,41 @@
 export namespace models {
 	
+	export class ArtifactResult {
+	    name: string;
+	    delivery: string;
+	    status: string;
+	    message?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ArtifactResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.delivery = source["delivery"];
+	        this.status = source["status"];
+	        this.message = source["message"];
+	    }
+	}
+	export class AuditEntry {
+	    time: string;
+	    action: string;
+	    target: string;
+	    reason?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AuditEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.time = source["time"];
+	        this.action = source["action"];
+	        this.target = source["target"];
+	        this.reason = source["reason"];
+	    }
+	}
 	export class BackupResult {
 	    ip: string;
 	    success: boolean;
@@ -36,11 +72,185 @@ export namespace models {
 	        this.password = source["password"];
 	    }
 	}
+	export class BuildTarget {
+	    mode: string;
+	    build: string;
+	    targetBuild?: string;
+	    region?: string;
+	    tags?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new BuildTarget(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mode = source["mode"];
+	        this.build = source["build"];
+	        this.targetBuild = source["targetBuild"];
+	        this.region = source["region"];
+	        this.tags = source["tags"];
+	    }
+	}
+	export class Device {
+	    id: string;
+	    ip: string;
+	    buildTime: string;
+	    status: string;
+	    region?: string;
+	    deletedAt?: string;
+	    name?: string;
+	    notes?: string;
+	    tags?: string[];
+	    lastSeen?: string;
+	    maintenance?: boolean;
+	    maintenanceReason?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Device(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.ip = source["ip"];
+	        this.buildTime = source["buildTime"];
+	        this.status = source["status"];
+	        this.region = source["region"];
+	        this.deletedAt = source["deletedAt"];
+	        this.name = source["name"];
+	        this.notes = source["notes"];
+	        this.tags = source["tags"];
+	        this.lastSeen = source["lastSeen"];
+	        this.maintenance = source["maintenance"];
+	        this.maintenanceReason = source["maintenanceReason"];
+	    }
+	}
+	export class BuildTargetDevice {
+	    device: Device;
+	    comparison: string;
+	    selected: boolean;
+	    reason?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new BuildTargetDevice(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.device = this.convertValues(source["device"], Device);
+	        this.comparison = source["comparison"];
+	        this.selected = source["selected"];
+	        this.reason = source["reason"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class BuildTargetPreview {
+	    devices: BuildTargetDevice[];
+	    selected: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new BuildTargetPreview(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.devices = this.convertValues(source["devices"], BuildTargetDevice);
+	        this.selected = source["selected"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class BundleResult {
+	    ip: string;
+	    success: boolean;
+	    skipped?: boolean;
+	    message: string;
+	    outcome?: string;
+	    buildBefore?: string;
+	    buildAfter?: string;
+	    artifacts: ArtifactResult[];
+	    postInstall?: ArtifactResult[];
+	
+	    static createFrom(source: any = {}) {
+	        return new BundleResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ip = source["ip"];
+	        this.success = source["success"];
+	        this.skipped = source["skipped"];
+	        this.message = source["message"];
+	        this.outcome = source["outcome"];
+	        this.buildBefore = source["buildBefore"];
+	        this.buildAfter = source["buildAfter"];
+	        this.artifacts = this.convertValues(source["artifacts"], ArtifactResult);
+	        this.postInstall = this.convertValues(source["postInstall"], ArtifactResult);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class Camera {
 	    taskId: string;
 	    deviceName: string;
 	    url: string;
 	    types: number[];
+	    status: number;
+	    errorReason?: string;
+	    abilities?: string[];
+	    width: number;
+	    height: number;
+	    codeName?: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new Camera(source);
@@ -52,6 +262,26 @@ export namespace models {
 	        this.deviceName = source["deviceName"];
 	        this.url = source["url"];
 	        this.types = source["types"];
+	        this.status = source["status"];
+	        this.errorReason = source["errorReason"];
+	        this.abilities = source["abilities"];
+	        this.width = source["width"];
+	        this.height = source["height"];
+	        this.codeName = source["codeName"];
+	    }
+	}
+	export class CameraAlgorithms {
+	    types: number[];
+	    policy: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CameraAlgorithms(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.types = source["types"];
+	        this.policy = source["policy"];
 	    }
 	}
 	export class CameraConfigResult {
@@ -72,24 +302,272 @@ export namespace models {
 	        this.message = source["message"];
 	    }
 	}
-	export class Device {
+	export class CameraPlanEntry {
 	    id: string;
-	    ip: string;
-	    buildTime: string;
-	    status: string;
-	    region?: string;
+	    cameraName: string;
+	    change: string;
+	    taskExists: boolean;
+	    currentUrl?: string;
+	    desiredUrl: string;
+	    currentTypes?: number[];
+	    desiredTypes: number[];
+	    currentIndex?: string;
+	    desiredIndex: number;
+	    urlChanged?: boolean;
+	    typesChanged?: boolean;
+	    indexChanged?: boolean;
+	    message?: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new Device(source);
+	        return new CameraPlanEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.cameraName = source["cameraName"];
+	        this.change = source["change"];
+	        this.taskExists = source["taskExists"];
+	        this.currentUrl = source["currentUrl"];
+	        this.desiredUrl = source["desiredUrl"];
+	        this.currentTypes = source["currentTypes"];
+	        this.desiredTypes = source["desiredTypes"];
+	        this.currentIndex = source["currentIndex"];
+	        this.desiredIndex = source["desiredIndex"];
+	        this.urlChanged = source["urlChanged"];
+	        this.typesChanged = source["typesChanged"];
+	        this.indexChanged = source["indexChanged"];
+	        this.message = source["message"];
+	    }
+	}
+	export class CameraDevicePlan {
+	    deviceIp: string;
+	    error?: string;
+	    entries: CameraPlanEntry[];
+	
+	    static createFrom(source: any = {}) {
+	        return new CameraDevicePlan(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.deviceIp = source["deviceIp"];
+	        this.error = source["error"];
+	        this.entries = this.convertValues(source["entries"], CameraPlanEntry);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CameraPlan {
+	    id: string;
+	    createdAt: string;
+	    urlTemplate: string;
+	    algorithms: CameraAlgorithms;
+	    region: string;
+	    reconcile: boolean;
+	    devices: CameraDevicePlan[];
+	
+	    static createFrom(source: any = {}) {
+	        return new CameraPlan(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.id = source["id"];
+	        this.createdAt = source["createdAt"];
+	        this.urlTemplate = source["urlTemplate"];
+	        this.algorithms = this.convertValues(source["algorithms"], CameraAlgorithms);
+	        this.region = source["region"];
+	        this.reconcile = source["reconcile"];
+	        this.devices = this.convertValues(source["devices"], CameraDevicePlan);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	export class DeviceFilter {
+	    ids?: string[];
+	    ip?: string;
+	    region?: string;
+	    includeNoRegion?: boolean;
+	    status?: string;
+	    includeDeleted?: boolean;
+	    onlyDeleted?: boolean;
+	    search?: string;
+	    buildTimeFrom?: string;
+	    buildTimeTo?: string;
+	    tags?: string[];
+	    lastSeenFrom?: string;
+	    lastSeenTo?: string;
+	    excludeMaintenance?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new DeviceFilter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ids = source["ids"];
 	        this.ip = source["ip"];
-	        this.buildTime = source["buildTime"];
-	        this.status = source["status"];
 	        this.region = source["region"];
+	        this.includeNoRegion = source["includeNoRegion"];
+	        this.status = source["status"];
+	        this.includeDeleted = source["includeDeleted"];
+	        this.onlyDeleted = source["onlyDeleted"];
+	        this.search = source["search"];
+	        this.buildTimeFrom = source["buildTimeFrom"];
+	        this.buildTimeTo = source["buildTimeTo"];
+	        this.tags = source["tags"];
+	        this.lastSeenFrom = source["lastSeenFrom"];
+	        this.lastSeenTo = source["lastSeenTo"];
+	        this.excludeMaintenance = source["excludeMaintenance"];
+	    }
+	}
+	export class DeviceLock {
+	    ip: string;
+	    operation: string;
+	    since: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DeviceLock(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ip = source["ip"];
+	        this.operation = source["operation"];
+	        this.since = source["since"];
+	    }
+	}
+	export class DevicePage {
+	    devices: Device[];
+	    total: number;
+	    page: number;
+	    pageSize: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DevicePage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.devices = this.convertValues(source["devices"], Device);
+	        this.total = source["total"];
+	        this.page = source["page"];
+	        this.pageSize = source["pageSize"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DeviceQuery {
+	    filter: DeviceFilter;
+	    sortBy?: string;
+	    sortDesc?: boolean;
+	    page?: number;
+	    pageSize?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DeviceQuery(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.filter = this.convertValues(source["filter"], DeviceFilter);
+	        this.sortBy = source["sortBy"];
+	        this.sortDesc = source["sortDesc"];
+	        this.page = source["page"];
+	        this.pageSize = source["pageSize"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DeviceSettings {
+	    trashRetentionDays: number;
+	    snapshotKeep: number;
+	    binaryPath?: string;
+	    configPath?: string;
+	    serviceName?: string;
+	    rollbackPath?: string;
+	    protectedCameraTasks?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new DeviceSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.trashRetentionDays = source["trashRetentionDays"];
+	        this.snapshotKeep = source["snapshotKeep"];
+	        this.binaryPath = source["binaryPath"];
+	        this.configPath = source["configPath"];
+	        this.serviceName = source["serviceName"];
+	        this.rollbackPath = source["rollbackPath"];
+	        this.protectedCameraTasks = source["protectedCameraTasks"];
 	    }
 	}
 	export class ExcelRow {
@@ -98,6 +576,7 @@ export namespace models {
 	    cameraInfo: string;
 	    deviceIndex: number;
 	    selected: boolean;
+	    algorithmTypes?: number[];
 	
 	    static createFrom(source: any = {}) {
 	        return new ExcelRow(source);
@@ -110,8 +589,183 @@ export namespace models {
 	        this.cameraInfo = source["cameraInfo"];
 	        this.deviceIndex = source["deviceIndex"];
 	        this.selected = source["selected"];
+	        this.algorithmTypes = source["algorithmTypes"];
+	    }
+	}
+	export class PackageOverride {
+	    reason: string;
+	    approvedAt: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PackageOverride(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.reason = source["reason"];
+	        this.approvedAt = source["approvedAt"];
+	    }
+	}
+	export class PackageManifest {
+	    fileName: string;
+	    size: number;
+	    sha256: string;
+	    md5: string;
+	    targetBuild?: string;
+	    minBuild?: string;
+	    releaseNotes?: string;
+	    signedAt: string;
+	    keyId: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PackageManifest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.fileName = source["fileName"];
+	        this.size = source["size"];
+	        this.sha256 = source["sha256"];
+	        this.md5 = source["md5"];
+	        this.targetBuild = source["targetBuild"];
+	        this.minBuild = source["minBuild"];
+	        this.releaseNotes = source["releaseNotes"];
+	        this.signedAt = source["signedAt"];
+	        this.keyId = source["keyId"];
+	    }
+	}
+	export class FirmwarePackage {
+	    id: string;
+	    fileName: string;
+	    size: number;
+	    sha256: string;
+	    md5: string;
+	    md5FileName?: string;
+	    buildTime?: string;
+	    notes?: string;
+	    uploadedAt: string;
+	    lastUsedAt?: string;
+	    pinned?: boolean;
+	    manifest?: PackageManifest;
+	    signature?: string;
+	    override?: PackageOverride;
+	
+	    static createFrom(source: any = {}) {
+	        return new FirmwarePackage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.fileName = source["fileName"];
+	        this.size = source["size"];
+	        this.sha256 = source["sha256"];
+	        this.md5 = source["md5"];
+	        this.md5FileName = source["md5FileName"];
+	        this.buildTime = source["buildTime"];
+	        this.notes = source["notes"];
+	        this.uploadedAt = source["uploadedAt"];
+	        this.lastUsedAt = source["lastUsedAt"];
+	        this.pinned = source["pinned"];
+	        this.manifest = this.convertValues(source["manifest"], PackageManifest);
+	        this.signature = source["signature"];
+	        this.override = this.convertValues(source["override"], PackageOverride);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class MaintenanceWindow {
+	    region: string;
+	    days?: number[];
+	    start: string;
+	    end: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new MaintenanceWindow(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.region = source["region"];
+	        this.days = source["days"];
+	        this.start = source["start"];
+	        this.end = source["end"];
+	    }
+	}
+	
+	
+	export class PreflightCheck {
+	    name: string;
+	    status: string;
+	    message: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PreflightCheck(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.status = source["status"];
+	        this.message = source["message"];
 	    }
 	}
+	export class PreflightResult {
+	    deviceId: string;
+	    ip: string;
+	    name?: string;
+	    buildTime?: string;
+	    go: boolean;
+	    checks: PreflightCheck[];
+	
+	    static createFrom(source: any = {}) {
+	        return new PreflightResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.deviceId = source["deviceId"];
+	        this.ip = source["ip"];
+	        this.name = source["name"];
+	        this.buildTime = source["buildTime"];
+	        this.go = source["go"];
+	        this.checks = this.convertValues(source["checks"], PreflightCheck);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class RestoreResult {
 	    ip: string;
 	    success: boolean;
@@ -132,6 +786,346 @@ export namespace models {
 	        this.backupPath = source["backupPath"];
 	    }
 	}
+	export class RetryPolicy {
+	    maxAttempts?: number;
+	    initialDelaySeconds?: number;
+	    maxDelaySeconds?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new RetryPolicy(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.maxAttempts = source["maxAttempts"];
+	        this.initialDelaySeconds = source["initialDelaySeconds"];
+	        this.maxDelaySeconds = source["maxDelaySeconds"];
+	    }
+	}
+	export class UpdateResult {
+	    ip: string;
+	    success: boolean;
+	    skipped?: boolean;
+	    deferred?: boolean;
+	    message: string;
+	    outcome?: string;
+	    buildBefore?: string;
+	    buildAfter?: string;
+	    checksumVerified?: boolean;
+	    transport?: string;
+	    attempts?: number;
+	    retryable?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new UpdateResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ip = source["ip"];
+	        this.success = source["success"];
+	        this.skipped = source["skipped"];
+	        this.deferred = source["deferred"];
+	        this.message = source["message"];
+	        this.outcome = source["outcome"];
+	        this.buildBefore = source["buildBefore"];
+	        this.buildAfter = source["buildAfter"];
+	        this.checksumVerified = source["checksumVerified"];
+	        this.transport = source["transport"];
+	        this.attempts = source["attempts"];
+	        this.retryable = source["retryable"];
+	    }
+	}
+	export class RolloutWave {
+	    name: string;
+	    deviceIds: string[];
+	    status: string;
+	    startedAt?: string;
+	    soakUntil?: string;
+	    finishedAt?: string;
+	    results?: UpdateResult[];
+	    health?: UpdateResult[];
+	    rolledBackAt?: string;
+	    rollback?: UpdateResult[];
+	
+	    static createFrom(source: any = {}) {
+	        return new RolloutWave(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.deviceIds = source["deviceIds"];
+	        this.status = source["status"];
+	        this.startedAt = source["startedAt"];
+	        this.soakUntil = source["soakUntil"];
+	        this.finishedAt = source["finishedAt"];
+	        this.results = this.convertValues(source["results"], UpdateResult);
+	        this.health = this.convertValues(source["health"], UpdateResult);
+	        this.rolledBackAt = source["rolledBackAt"];
+	        this.rollback = this.convertValues(source["rollback"], UpdateResult);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class UpgradeOptions {
+	    expectedBuild?: string;
+	    skipVerify?: boolean;
+	    skipCurrent?: boolean;
+	    minBuild?: string;
+	    preflight?: boolean;
+	    snapshotBeforeUpload?: boolean;
+	    deferOffline?: boolean;
+	    deferExpiryHours?: number;
+	    restartWaitSeconds?: number;
+	    verifyTimeoutSeconds?: number;
+	    expectedSha256?: string;
+	    sshUsername?: string;
+	    sshPassword?: string;
+	    remoteFilePath?: string;
+	    transport?: string;
+	    sshFallback?: boolean;
+	    loginRetry: RetryPolicy;
+	    uploadRetry: RetryPolicy;
+	    verifyRetry: RetryPolicy;
+	
+	    static createFrom(source: any = {}) {
+	        return new UpgradeOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.expectedBuild = source["expectedBuild"];
+	        this.skipVerify = source["skipVerify"];
+	        this.skipCurrent = source["skipCurrent"];
+	        this.minBuild = source["minBuild"];
+	        this.preflight = source["preflight"];
+	        this.snapshotBeforeUpload = source["snapshotBeforeUpload"];
+	        this.deferOffline = source["deferOffline"];
+	        this.deferExpiryHours = source["deferExpiryHours"];
+	        this.restartWaitSeconds = source["restartWaitSeconds"];
+	        this.verifyTimeoutSeconds = source["verifyTimeoutSeconds"];
+	        this.expectedSha256 = source["expectedSha256"];
+	        this.sshUsername = source["sshUsername"];
+	        this.sshPassword = source["sshPassword"];
+	        this.remoteFilePath = source["remoteFilePath"];
+	        this.transport = source["transport"];
+	        this.sshFallback = source["sshFallback"];
+	        this.loginRetry = this.convertValues(source["loginRetry"], RetryPolicy);
+	        this.uploadRetry = this.convertValues(source["uploadRetry"], RetryPolicy);
+	        this.verifyRetry = this.convertValues(source["verifyRetry"], RetryPolicy);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RolloutPlan {
+	    deviceIds: string[];
+	    canaryIds?: string[];
+	    canaryCount?: number;
+	    strategy: string;
+	    wavePercents?: number[];
+	    soakMinutes: number;
+	    failureThresholdPercent: number;
+	    verify: UpgradeOptions;
+	
+	    static createFrom(source: any = {}) {
+	        return new RolloutPlan(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.deviceIds = source["deviceIds"];
+	        this.canaryIds = source["canaryIds"];
+	        this.canaryCount = source["canaryCount"];
+	        this.strategy = source["strategy"];
+	        this.wavePercents = source["wavePercents"];
+	        this.soakMinutes = source["soakMinutes"];
+	        this.failureThresholdPercent = source["failureThresholdPercent"];
+	        this.verify = this.convertValues(source["verify"], UpgradeOptions);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Rollout {
+	    id: string;
+	    name: string;
+	    createdAt: string;
+	    updatedAt: string;
+	    packageId?: string;
+	    fileName: string;
+	    md5FileName?: string;
+	    username: string;
+	    password: string;
+	    plan: RolloutPlan;
+	    waves: RolloutWave[];
+	    currentWave: number;
+	    status: string;
+	    message?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Rollout(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.createdAt = source["createdAt"];
+	        this.updatedAt = source["updatedAt"];
+	        this.packageId = source["packageId"];
+	        this.fileName = source["fileName"];
+	        this.md5FileName = source["md5FileName"];
+	        this.username = source["username"];
+	        this.password = source["password"];
+	        this.plan = this.convertValues(source["plan"], RolloutPlan);
+	        this.waves = this.convertValues(source["waves"], RolloutWave);
+	        this.currentWave = source["currentWave"];
+	        this.status = source["status"];
+	        this.message = source["message"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	export class ScheduledUpdate {
+	    id: string;
+	    name: string;
+	    createdAt: string;
+	    updatedAt: string;
+	    runAt?: string;
+	    useWindows?: boolean;
+	    graceMinutes?: number;
+	    deviceIds: string[];
+	    pending: string[];
+	    packageId?: string;
+	    fileName: string;
+	    filePath: string;
+	    md5FileName?: string;
+	    md5FilePath?: string;
+	    username: string;
+	    password: string;
+	    options: UpgradeOptions;
+	    status: string;
+	    message?: string;
+	    startedAt?: string;
+	    checkedAt?: string;
+	    jobIds?: string[];
+	    results?: UpdateResult[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ScheduledUpdate(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.createdAt = source["createdAt"];
+	        this.updatedAt = source["updatedAt"];
+	        this.runAt = source["runAt"];
+	        this.useWindows = source["useWindows"];
+	        this.graceMinutes = source["graceMinutes"];
+	        this.deviceIds = source["deviceIds"];
+	        this.pending = source["pending"];
+	        this.packageId = source["packageId"];
+	        this.fileName = source["fileName"];
+	        this.filePath = source["filePath"];
+	        this.md5FileName = source["md5FileName"];
+	        this.md5FilePath = source["md5FilePath"];
+	        this.username = source["username"];
+	        this.password = source["password"];
+	        this.options = this.convertValues(source["options"], UpgradeOptions);
+	        this.status = source["status"];
+	        this.message = source["message"];
+	        this.startedAt = source["startedAt"];
+	        this.checkedAt = source["checkedAt"];
+	        this.jobIds = source["jobIds"];
+	        this.results = this.convertValues(source["results"], UpdateResult);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class TimeSyncResult {
 	    ip: string;
 	    success: boolean;
@@ -150,22 +1144,234 @@ export namespace models {
 	        this.timestamp = source["timestamp"];
 	    }
 	}
-	export class UpdateResult {
+	export class TransferSettings {
+	    globalLimitKBps?: number;
+	    regionLimitsKBps?: Record<string, number>;
+	    maxConcurrent?: number;
+	    adaptive?: boolean;
+	    minConcurrent?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TransferSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.globalLimitKBps = source["globalLimitKBps"];
+	        this.regionLimitsKBps = source["regionLimitsKBps"];
+	        this.maxConcurrent = source["maxConcurrent"];
+	        this.adaptive = source["adaptive"];
+	        this.minConcurrent = source["minConcurrent"];
+	    }
+	}
+	export class TrustedKey {
+	    id: string;
+	    name: string;
+	    publicKey: string;
+	    addedAt: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new TrustedKey(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.publicKey = source["publicKey"];
+	        this.addedAt = source["addedAt"];
+	    }
+	}
+	export class UpdateJob {
+	    id: string;
+	    createdAt: string;
+	    updatedAt: string;
+	    status: string;
+	    packageId?: string;
+	    fileName: string;
+	    filePath: string;
+	    md5FileName?: string;
+	    md5FilePath?: string;
+	    sha256: string;
+	    username: string;
+	    password: string;
+	    options: UpgradeOptions;
+	    deviceIds: string[];
+	    pending: string[];
+	    results: UpdateResult[];
+	    deferred?: string[];
+	    deferredUntil?: string;
+	    preflight?: PreflightResult[];
+	
+	    static createFrom(source: any = {}) {
+	        return new UpdateJob(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.createdAt = source["createdAt"];
+	        this.updatedAt = source["updatedAt"];
+	        this.status = source["status"];
+	        this.packageId = source["packageId"];
+	        this.fileName = source["fileName"];
+	        this.filePath = source["filePath"];
+	        this.md5FileName = source["md5FileName"];
+	        this.md5FilePath = source["md5FilePath"];
+	        this.sha256 = source["sha256"];
+	        this.username = source["username"];
+	        this.password = source["password"];
+	        this.options = this.convertValues(source["options"], UpgradeOptions);
+	        this.deviceIds = source["deviceIds"];
+	        this.pending = source["pending"];
+	        this.results = this.convertValues(source["results"], UpdateResult);
+	        this.deferred = source["deferred"];
+	        this.deferredUntil = source["deferredUntil"];
+	        this.preflight = this.convertValues(source["preflight"], PreflightResult);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class UpdateJobDevice {
+	    deviceId: string;
 	    ip: string;
-	    success: boolean;
-	    message: string;
+	    name?: string;
+	    deferred?: boolean;
+	    message?: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new UpdateResult(source);
+	        return new UpdateJobDevice(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.deviceId = source["deviceId"];
 	        this.ip = source["ip"];
-	        this.success = source["success"];
+	        this.name = source["name"];
+	        this.deferred = source["deferred"];
 	        this.message = source["message"];
 	    }
 	}
+	export class UpdateJobReport {
+	    jobId: string;
+	    status: string;
+	    completed: UpdateJobDevice[];
+	    failed: UpdateJobDevice[];
+	    wrongBuild: UpdateJobDevice[];
+	    skipped: UpdateJobDevice[];
+	    pending: UpdateJobDevice[];
+	
+	    static createFrom(source: any = {}) {
+	        return new UpdateJobReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.jobId = source["jobId"];
+	        this.status = source["status"];
+	        this.completed = this.convertValues(source["completed"], UpdateJobDevice);
+	        this.failed = this.convertValues(source["failed"], UpdateJobDevice);
+	        this.wrongBuild = this.convertValues(source["wrongBuild"], UpdateJobDevice);
+	        this.skipped = this.convertValues(source["skipped"], UpdateJobDevice);
+	        this.pending = this.convertValues(source["pending"], UpdateJobDevice);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	export class WorkspaceCredentials {
+	    username: string;
+	    password: string;
+	    sshUsername: string;
+	    sshPassword: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new WorkspaceCredentials(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.username = source["username"];
+	        this.password = source["password"];
+	        this.sshUsername = source["sshUsername"];
+	        this.sshPassword = source["sshPassword"];
+	    }
+	}
+	export class Workspace {
+	    id: string;
+	    name: string;
+	    description: string;
+	    createdAt: string;
+	    archived: boolean;
+	    archivedAt?: string;
+	    credentials: WorkspaceCredentials;
+	
+	    static createFrom(source: any = {}) {
+	        return new Workspace(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.createdAt = source["createdAt"];
+	        this.archived = source["archived"];
+	        this.archivedAt = source["archivedAt"];
+	        this.credentials = this.convertValues(source["credentials"], WorkspaceCredentials);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 
 }
 
@@ -0,0 +1,33 @@
+package models
+
+// Build comparison modes used by version-targeted updates
+const (
+	BuildOlder     = "older"     // 设备版本早于比较版本
+	BuildEqual     = "equal"     // 设备版本等于比较版本
+	BuildDifferent = "different" // 设备版本与比较版本不同
+	BuildNewer     = "newer"     // 仅用于预览结果：设备版本晚于比较版本
+	BuildUnknown   = "unknown"   // 仅用于预览结果：buildTime无法解析，无法比较先后
+)
+
+// BuildTarget selects devices for an update by comparing their buildTime with a given build
+type BuildTarget struct {
+	Mode        string   `json:"mode"`                  // older、equal或different
+	Build       string   `json:"build"`                 // 用于比较的buildTime
+	TargetBuild string   `json:"targetBuild,omitempty"` // 更新后的buildTime，已运行该版本的设备会被跳过；为空时older和different模式使用Build
+	Region      string   `json:"region,omitempty"`      // 只选择该区域的设备
+	Tags        []string `json:"tags,omitempty"`        // 只选择包含所有标签的设备
+}
+
+// BuildTargetDevice is one candidate device in a targeting preview
+type BuildTargetDevice struct {
+	Device     Device `json:"device"`
+	Comparison string `json:"comparison"` // 设备版本相对于比较版本：older、equal、newer、different或unknown
+	Selected   bool   `json:"selected"`   // 是否会被更新
+	Reason     string `json:"reason,omitempty"`
+}
+
+// BuildTargetPreview lists which devices a BuildTarget would update
+type BuildTargetPreview struct {
+	Devices  []BuildTargetDevice `json:"devices"`
+	Selected int                 `json:"selected"` // 会被更新的设备数量
+}
@@ -0,0 +1,63 @@
+package models
+
+// Artifact delivery methods
+const (
+	DeliveryHTTP    = "http"    // 作为/api/system/upgrade的表单字段上传
+	DeliverySSH     = "ssh"     // 通过SSH复制到设备上的路径
+	DeliveryCommand = "command" // 通过SSH在设备上执行命令
+)
+
+// BundleArtifact is one file or command of a multi-artifact bundle
+type BundleArtifact struct {
+	Name     string `json:"name"`
+	Delivery string `json:"delivery"`
+	Order    int    `json:"order"`             // 安装顺序，从小到大，相同时按清单中的顺序
+	File     string `json:"file,omitempty"`    // 清单所在目录中的文件，command不需要
+	SHA256   string `json:"sha256,omitempty"`  // 文件的SHA-256，签名时自动填写
+	Field    string `json:"field,omitempty"`   // http: 表单字段，如binary或md5file
+	Path     string `json:"path,omitempty"`    // ssh: 设备上的目标路径(绝对路径)
+	Mode     string `json:"mode,omitempty"`    // ssh: 文件权限，默认0644
+	Command  string `json:"command,omitempty"` // command: 在设备上执行的命令
+}
+
+// BundleManifest describes a release that consists of several artifacts.
+// Signed bundles carry the key ID here, the signature covers the JSON encoding of this struct.
+type BundleManifest struct {
+	Name        string           `json:"name"`
+	TargetBuild string           `json:"targetBuild,omitempty"` // 安装后设备应返回的buildTime，为空时只要求buildTime发生变化
+	Artifacts   []BundleArtifact `json:"artifacts"`
+	PostInstall []string         `json:"postInstall,omitempty"` // 所有文件安装后依次执行的命令，如重启服务
+	SignedAt    string           `json:"signedAt,omitempty"`
+	KeyID       string           `json:"keyId,omitempty"`
+}
+
+// Artifact result statuses
+const (
+	ArtifactApplied    = "applied"
+	ArtifactFailed     = "failed"
+	ArtifactRolledBack = "rolledBack" // 已安装，因后续步骤失败已恢复原文件
+	ArtifactNotApplied = "notApplied" // 因之前的步骤失败未执行
+)
+
+// ArtifactResult is the outcome of one artifact or post-install step on one device
+type ArtifactResult struct {
+	Name     string `json:"name"`
+	Delivery string `json:"delivery"`
+	Status   string `json:"status"`
+	Message  string `json:"message,omitempty"`
+}
+
+// BundleResult is the outcome of applying a bundle to one device
+type BundleResult struct {
+	IP      string `json:"ip"`
+	Success bool   `json:"success"`
+	Skipped bool   `json:"skipped,omitempty"`
+	Message string `json:"message"`
+
+	Outcome     string `json:"outcome,omitempty"` // 包含HTTP升级时的验证结果
+	BuildBefore string `json:"buildBefore,omitempty"`
+	BuildAfter  string `json:"buildAfter,omitempty"`
+
+	Artifacts   []ArtifactResult `json:"artifacts"`
+	PostInstall []ArtifactResult `json:"postInstall,omitempty"`
+}
@@ -1,35 +1,45 @@
 package models
 
-// Camera represents a camera configuration
+// Camera represents a camera task on a device
 type Camera struct {
-	TaskID     string `json:"taskId"`
-	DeviceName string `json:"deviceName"`
-	URL        string `json:"url"`
-	Types      []int  `json:"types"`
+	TaskID      string   `json:"taskId"`
+	DeviceName  string   `json:"deviceName"`
+	URL         string   `json:"url"`
+	Types       []int    `json:"types"`
+	Status      int      `json:"status"`
+	ErrorReason string   `json:"errorReason,omitempty"`
+	Abilities   []string `json:"abilities,omitempty"`
+	Width       int      `json:"width"`
+	Height      int      `json:"height"`
+	CodeName    string   `json:"codeName,omitempty"`
+}
+
+// CameraTaskPage is one page of the device task list
+type CameraTaskPage struct {
+	Total     int      `json:"total"`
+	PageSize  int      `json:"pageSize"`
+	PageCount int      `json:"pageCount"`
+	PageNo    int      `json:"pageNo"`
+	Items     []Camera `json:"items"`
 }
 
 // CameraTaskResponse represents the task list response from device
 type CameraTaskResponse struct {
-	Code   int    `json:"code"`
-	Msg    string `json:"msg"`
-	Result struct {
-		Total     int `json:"total"`
-		PageSize  int `json:"pageSize"`
-		PageCount int `json:"pageCount"`
-		PageNo    int `json:"pageNo"`
-		Items     []struct {
-			TaskID      string   `json:"taskId"`
-			DeviceName  string   `json:"deviceName"`
-			URL         string   `json:"url"`
-			Status      int      `json:"status"`
-			ErrorReason string   `json:"errorReason"`
-			Abilities   []string `json:"abilities"`
-			Types       []int    `json:"types"`
-			Width       int      `json:"width"`
-			Height      int      `json:"height"`
-			CodeName    string   `json:"codeName"`
-		} `json:"items"`
-	} `json:"result"`
+	Code   int            `json:"code"`
+	Msg    string         `json:"msg"`
+	Result CameraTaskPage `json:"result"`
+}
+
+// Algorithm policies for tasks that already exist on the device
+const (
+	AlgorithmPolicyReplace = "replace" // 任务的算法类型替换为配置的算法
+	AlgorithmPolicyMerge   = "merge"   // 保留任务已有的算法，加入配置的算法
+)
+
+// CameraAlgorithms selects the algorithm types configured on camera tasks
+type CameraAlgorithms struct {
+	Types  []int  `json:"types"`  // Excel行没有算法列时使用
+	Policy string `json:"policy"` // 修改已有任务时的策略，为空时替换
 }
 
 // CameraConfigResult represents the result of camera configuration
@@ -67,30 +77,86 @@ type Algorithm struct {
 	DetectInfos    []DetectInfo `json:"DetectInfos"`
 	TripWire       interface{}  `json:"TripWire"`
 	ExtraConfig    ExtraConfig  `json:"ExtraConfig"`
+
+	raw rawObject // 设备返回的原始配置，包括DetectPoints等未建模的字段
+}
+
+// UnmarshalJSON keeps the fields the model does not know about
+func (a *Algorithm) UnmarshalJSON(data []byte) error {
+	type plain Algorithm
+	return a.raw.decode(data, (*plain)(a))
+}
+
+// MarshalJSON writes the changed fields over the configuration read from the device
+func (a Algorithm) MarshalJSON() ([]byte, error) {
+	type plain Algorithm
+	return a.raw.encode(plain(a))
 }
 
 // TargetSize represents the target size configuration
 type TargetSize struct {
 	MinDetect int `json:"MinDetect"`
 	MaxDetect int `json:"MaxDetect"`
+
+	raw rawObject
+}
+
+// UnmarshalJSON keeps the fields the model does not know about
+func (t *TargetSize) UnmarshalJSON(data []byte) error {
+	type plain TargetSize
+	return t.raw.decode(data, (*plain)(t))
+}
+
+// MarshalJSON writes the changed fields over the original object
+func (t TargetSize) MarshalJSON() ([]byte, error) {
+	type plain TargetSize
+	return t.raw.encode(plain(t))
 }
 
 // DetectInfo represents detection information
 type DetectInfo struct {
 	Id      int            `json:"Id"`
 	HotArea []HotAreaPoint `json:"HotArea"`
+
+	raw rawObject // 包括每个检测区域的ExtraConfig
+}
+
+// UnmarshalJSON keeps the fields the model does not know about
+func (d *DetectInfo) UnmarshalJSON(data []byte) error {
+	type plain DetectInfo
+	return d.raw.decode(data, (*plain)(d))
+}
+
+// MarshalJSON writes the changed fields over the original object
+func (d DetectInfo) MarshalJSON() ([]byte, error) {
+	type plain DetectInfo
+	return d.raw.encode(plain(d))
 }
 
 // HotAreaPoint represents a point in the hot area
 type HotAreaPoint struct {
-	X int `json:"X"`
-	Y int `json:"Y"`
+	X float64 `json:"X"` // 有的算法使用归一化的小数坐标
+	Y float64 `json:"Y"`
 }
 
 // ExtraConfig represents extra configuration for algorithms
 type ExtraConfig struct {
 	CameraIndex string     `json:"camera_index"`
 	Defs        []ExtraDef `json:"defs"`
+
+	raw rawObject // precision、interval等算法自己的参数
+}
+
+// UnmarshalJSON keeps the fields the model does not know about
+func (e *ExtraConfig) UnmarshalJSON(data []byte) error {
+	type plain ExtraConfig
+	return e.raw.decode(data, (*plain)(e))
+}
+
+// MarshalJSON writes the changed fields over the original object
+func (e ExtraConfig) MarshalJSON() ([]byte, error) {
+	type plain ExtraConfig
+	return e.raw.encode(plain(e))
 }
 
 // ExtraDef represents a definition in extra configuration
@@ -100,6 +166,20 @@ type ExtraDef struct {
 	Type    string `json:"Type"`
 	Unit    string `json:"Unit"`
 	Default string `json:"Default"`
+
+	raw rawObject
+}
+
+// UnmarshalJSON keeps the fields the model does not know about
+func (d *ExtraDef) UnmarshalJSON(data []byte) error {
+	type plain ExtraDef
+	return d.raw.decode(data, (*plain)(d))
+}
+
+// MarshalJSON writes the changed fields over the original object
+func (d ExtraDef) MarshalJSON() ([]byte, error) {
+	type plain ExtraDef
+	return d.raw.encode(plain(d))
 }
 
 // CameraConfigResponse represents the response for camera configuration
@@ -108,3 +188,52 @@ type CameraConfigResponse struct {
 	Msg    string       `json:"msg"`
 	Result CameraConfig `json:"result"`
 }
+
+// Camera plan changes
+const (
+	CameraChangeAdd       = "add"       // 设备上没有该任务，将添加
+	CameraChangeUpdate    = "update"    // URL或算法类型不同，将修改任务
+	CameraChangeIndex     = "index"     // 任务相同，只有camera_index不同
+	CameraChangeUnchanged = "unchanged" // 与设备当前状态一致
+	CameraChangeInvalid   = "invalid"   // Excel行无法解析，不会执行
+	CameraChangeDelete    = "delete"    // 对账模式下，设备上有但Excel中没有的任务，将删除
+	CameraChangeProtected = "protected" // 对账模式下Excel中没有、但在保护列表中的任务，不会删除
+)
+
+// CameraPlanEntry compares one Excel row with the task currently on the device
+type CameraPlanEntry struct {
+	ID         string `json:"id"` // 设备IP/任务ID，批准计划时使用
+	CameraName string `json:"cameraName"`
+	Change     string `json:"change"`
+
+	TaskExists   bool   `json:"taskExists"`
+	CurrentURL   string `json:"currentUrl,omitempty"`
+	DesiredURL   string `json:"desiredUrl"`
+	CurrentTypes []int  `json:"currentTypes,omitempty"`
+	DesiredTypes []int  `json:"desiredTypes"`
+	CurrentIndex string `json:"currentIndex,omitempty"` // 设备上算法配置的camera_index
+	DesiredIndex int    `json:"desiredIndex"`
+
+	URLChanged   bool   `json:"urlChanged,omitempty"`
+	TypesChanged bool   `json:"typesChanged,omitempty"`
+	IndexChanged bool   `json:"indexChanged,omitempty"`
+	Message      string `json:"message,omitempty"`
+}
+
+// CameraDevicePlan is the diff for one device
+type CameraDevicePlan struct {
+	DeviceIP string            `json:"deviceIp"`
+	Error    string            `json:"error,omitempty"` // 登录或读取设备失败时的原因，此时只列出Excel中的行
+	Entries  []CameraPlanEntry `json:"entries"`
+}
+
+// CameraPlan is a dry-run of a camera configuration, nothing is changed until entries are approved
+type CameraPlan struct {
+	ID          string             `json:"id"`
+	CreatedAt   string             `json:"createdAt"`
+	URLTemplate string             `json:"urlTemplate"`
+	Algorithms  CameraAlgorithms   `json:"algorithms"`
+	Region      string             `json:"region"`
+	Reconcile   bool               `json:"reconcile"` // 以Excel为准，列出设备上多余的任务
+	Devices     []CameraDevicePlan `json:"devices"`
+}
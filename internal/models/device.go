@@ -8,7 +8,87 @@ type Device struct {
 	IP        string `json:"ip"`
 	BuildTime string `json:"buildTime"`
 	Status    string `json:"status"`
-	Region    string `json:"region,omitempty"` // 添加区域字段，omitempty使得该字段在为空时不会出现在JSON中，保持向后兼容
+	Region    string `json:"region,omitempty"`    // 添加区域字段，omitempty使得该字段在为空时不会出现在JSON中，保持向后兼容
+	DeletedAt string `json:"deletedAt,omitempty"` // 软删除时间(UTC)，为空表示未删除
+
+	Name     string   `json:"name,omitempty"`     // 设备名称
+	Notes    string   `json:"notes,omitempty"`    // 备注
+	Tags     []string `json:"tags,omitempty"`     // 标签
+	LastSeen string   `json:"lastSeen,omitempty"` // 最后一次在线的时间(本地时间)
+
+	Maintenance       bool   `json:"maintenance,omitempty"`       // 维护模式，批量操作将跳过该设备
+	MaintenanceReason string `json:"maintenanceReason,omitempty"` // 进入维护模式的原因
+}
+
+// DeviceLock describes an operation currently holding a device
+type DeviceLock struct {
+	IP        string `json:"ip"`
+	Operation string `json:"operation"`
+	Since     string `json:"since"`
+}
+
+// DeviceFilter describes which devices a repository query should return.
+// Zero-valued fields do not restrict the result, except that soft-deleted
+// devices are excluded unless IncludeDeleted or OnlyDeleted is set.
+type DeviceFilter struct {
+	IDs             []string `json:"ids,omitempty"`
+	IP              string   `json:"ip,omitempty"`
+	Region          string   `json:"region,omitempty"`
+	IncludeNoRegion bool     `json:"includeNoRegion,omitempty"` // 按区域过滤时同时包含未分配区域的设备
+	Status          string   `json:"status,omitempty"`
+	IncludeDeleted  bool     `json:"includeDeleted,omitempty"` // 同时返回已删除的设备
+	OnlyDeleted     bool     `json:"onlyDeleted,omitempty"`    // 只返回已删除的设备(回收站)
+
+	Search        string   `json:"search,omitempty"`        // 在IP、名称和备注中模糊搜索
+	BuildTimeFrom string   `json:"buildTimeFrom,omitempty"` // buildTime下限(包含)
+	BuildTimeTo   string   `json:"buildTimeTo,omitempty"`   // buildTime上限(包含)
+	Tags          []string `json:"tags,omitempty"`          // 必须包含所有指定标签
+	LastSeenFrom  string   `json:"lastSeenFrom,omitempty"`  // 最后在线时间下限(包含)，格式2006-01-02 15:04:05
+	LastSeenTo    string   `json:"lastSeenTo,omitempty"`    // 最后在线时间上限(包含)，格式2006-01-02 15:04:05
+
+	ExcludeMaintenance bool `json:"excludeMaintenance,omitempty"` // 排除维护模式中的设备
+}
+
+// Sort keys accepted by DeviceQuery.SortBy
+const (
+	DeviceSortIP        = "ip"
+	DeviceSortName      = "name"
+	DeviceSortRegion    = "region"
+	DeviceSortStatus    = "status"
+	DeviceSortBuildTime = "buildTime"
+	DeviceSortLastSeen  = "lastSeen"
+)
+
+// DeviceQuery is a filtered, sorted and paginated device query
+type DeviceQuery struct {
+	Filter   DeviceFilter `json:"filter"`
+	SortBy   string       `json:"sortBy,omitempty"` // 排序字段，为空时按添加顺序
+	SortDesc bool         `json:"sortDesc,omitempty"`
+	Page     int          `json:"page,omitempty"`     // 页码，从1开始
+	PageSize int          `json:"pageSize,omitempty"` // 每页数量，小于等于0时返回全部
+}
+
+// DevicePage is one page of a device query result
+type DevicePage struct {
+	Devices  []Device `json:"devices"`
+	Total    int      `json:"total"` // 满足过滤条件的设备总数
+	Page     int      `json:"page"`
+	PageSize int      `json:"pageSize"`
+}
+
+// DeviceSettings stores persistent settings for device management
+type DeviceSettings struct {
+	TrashRetentionDays int `json:"trashRetentionDays"` // 回收站中设备的保留天数，超过后自动清除
+	SnapshotKeep       int `json:"snapshotKeep"`       // 保留的数据库快照数量
+
+	// 设备上application-web的位置，用于升级前快照和回滚
+	BinaryPath   string `json:"binaryPath,omitempty"`   // 程序文件路径
+	ConfigPath   string `json:"configPath,omitempty"`   // 配置文件或目录，设备上不存在时跳过
+	ServiceName  string `json:"serviceName,omitempty"`  // systemd服务名
+	RollbackPath string `json:"rollbackPath,omitempty"` // 设备上保存快照的目录
+
+	// 对账时不会删除的摄像头任务，格式为"任务ID"(所有设备)或"设备IP/任务ID"
+	ProtectedCameraTasks []string `json:"protectedCameraTasks,omitempty"`
 }
 
 // 根据区域和IP创建设备ID
@@ -36,9 +116,85 @@ type BuildTimeResponse struct {
 
 // UpdateResult represents the update operation result
 type UpdateResult struct {
-	IP      string `json:"ip"`
-	Success bool   `json:"success"`
-	Message string `json:"message"`
+	IP       string `json:"ip"`
+	Success  bool   `json:"success"`
+	Skipped  bool   `json:"skipped,omitempty"`  // 设备未参与更新(如处于维护模式)
+	Deferred bool   `json:"deferred,omitempty"` // 设备离线，已加入等待队列，上线后自动更新
+	Message  string `json:"message"`
+
+	Outcome     string `json:"outcome,omitempty"`     // 验证结果: upgraded、unchanged或unreachable，未验证时为空
+	BuildBefore string `json:"buildBefore,omitempty"` // 更新前的buildTime
+	BuildAfter  string `json:"buildAfter,omitempty"`  // 更新后的buildTime
+
+	ChecksumVerified bool   `json:"checksumVerified,omitempty"` // 已通过SSH确认设备上的文件MD5一致
+	Transport        string `json:"transport,omitempty"`        // 实际使用的升级方式: http或ssh
+
+	Attempts  int  `json:"attempts,omitempty"`  // 登录、上传和验证的总尝试次数
+	Retryable bool `json:"retryable,omitempty"` // 失败原因是暂时性的(如网络中断)，可以重试
+}
+
+// UploadProgress reports how much of an update has been sent to one device
+type UploadProgress struct {
+	IP         string `json:"ip"`
+	BytesSent  int64  `json:"bytesSent"`
+	TotalBytes int64  `json:"totalBytes"`
+}
+
+// Upgrade verification outcomes
+const (
+	OutcomeUpgraded        = "upgraded"         // 设备已重启并运行新版本
+	OutcomeUnchanged       = "unchanged"        // 设备在线但仍运行更新前的版本
+	OutcomeUnexpectedBuild = "unexpected_build" // 设备版本已变化，但不是预期的版本
+	OutcomeUnreachable     = "unreachable"      // 超时前设备未恢复在线
+)
+
+// Upgrade transports
+const (
+	TransportHTTP = "http" // 通过设备的/api/system/upgrade接口上传
+	TransportSSH  = "ssh"  // 通过SSH复制程序文件，原子替换后重启服务
+)
+
+// UpgradeOptions controls how an update is verified after upload
+type UpgradeOptions struct {
+	ExpectedBuild        string `json:"expectedBuild,omitempty"`        // 更新包对应的buildTime，为空时只要求buildTime发生变化
+	SkipVerify           bool   `json:"skipVerify,omitempty"`           // 跳过更新后的验证
+	SkipCurrent          bool   `json:"skipCurrent,omitempty"`          // 跳过已运行ExpectedBuild的设备
+	MinBuild             string `json:"minBuild,omitempty"`             // 低于该buildTime的设备不能直接升级，记为跳过
+	Preflight            bool   `json:"preflight,omitempty"`            // 上传前对所有设备做升级前检查，未通过的设备记为跳过
+	SnapshotBeforeUpload bool   `json:"snapshotBeforeUpload,omitempty"` // 上传前通过SSH在设备上保存当前程序和配置，用于回滚
+	DeferOffline         bool   `json:"deferOffline,omitempty"`         // 离线设备加入等待队列，上线后自动更新，否则记为跳过
+	DeferExpiryHours     int    `json:"deferExpiryHours,omitempty"`     // 等待离线设备上线的期限，默认24小时
+	RestartWaitSeconds   int    `json:"restartWaitSeconds,omitempty"`   // 上传后开始轮询前等待设备重启的时间
+	VerifyTimeoutSeconds int    `json:"verifyTimeoutSeconds,omitempty"` // 轮询buildTime的超时时间
+
+	ExpectedSHA256 string `json:"expectedSha256,omitempty"` // 更新文件应有的SHA-256，不一致时拒绝上传
+	SSHUsername    string `json:"sshUsername,omitempty"`    // 用于校验设备上文件的SSH账号，为空时不做远程校验
+	SSHPassword    string `json:"sshPassword,omitempty"`
+	RemoteFilePath string `json:"remoteFilePath,omitempty"` // 设备保存上传文件的路径，为空时不做远程校验
+
+	Transport   string `json:"transport,omitempty"`   // 升级方式: http(默认)或ssh，ssh需要SSH账号
+	SSHFallback bool   `json:"sshFallback,omitempty"` // HTTP升级失败时自动改用SSH升级
+
+	LoginRetry  RetryPolicy `json:"loginRetry"`  // 登录失败的重试策略
+	UploadRetry RetryPolicy `json:"uploadRetry"` // 上传失败的重试策略
+	VerifyRetry RetryPolicy `json:"verifyRetry"` // 设备未恢复在线时重新验证的策略
+}
+
+// RetryPolicy controls how often a failed step is retried with exponential backoff.
+// Zero values use the step's default, MaxAttempts 1 disables retries.
+type RetryPolicy struct {
+	MaxAttempts         int `json:"maxAttempts,omitempty"`         // 最大尝试次数(包含首次)
+	InitialDelaySeconds int `json:"initialDelaySeconds,omitempty"` // 第一次重试前的等待时间，之后每次翻倍
+	MaxDelaySeconds     int `json:"maxDelaySeconds,omitempty"`     // 等待时间上限
+}
+
+// TransferSettings limits the bandwidth and parallelism of firmware uploads and database restores
+type TransferSettings struct {
+	GlobalLimitKBps  int            `json:"globalLimitKBps,omitempty"`  // 所有传输合计的带宽上限(KB/s)，0表示不限制
+	RegionLimitsKBps map[string]int `json:"regionLimitsKBps,omitempty"` // 每个区域的带宽上限(KB/s)
+	MaxConcurrent    int            `json:"maxConcurrent,omitempty"`    // 同时上传的设备数，默认8
+	Adaptive         bool           `json:"adaptive,omitempty"`         // 出现错误、吞吐量明显下降或延迟明显升高时自动降低并发数
+	MinConcurrent    int            `json:"minConcurrent,omitempty"`    // 自适应模式下的最小并发数，默认1
 }
 
 // TimeSyncResult represents the result of a time sync operation
@@ -13,4 +13,7 @@ type ExcelRow struct {
 	CameraInfo  string `json:"cameraInfo"`
 	DeviceIndex int    `json:"deviceIndex"`
 	Selected    bool   `json:"selected"`
+
+	// 该摄像头的算法类型，来自Excel的算法列，为空时使用配置参数中的算法
+	AlgorithmTypes []int `json:"algorithmTypes,omitempty"`
 }
@@ -0,0 +1,26 @@
+package models
+
+// FirmwarePackage is an immutable update binary stored in the local package repository
+type FirmwarePackage struct {
+	ID          string `json:"id"` // 二进制文件的SHA-256，相同内容只保存一份
+	FileName    string `json:"fileName"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	MD5         string `json:"md5"`
+	MD5FileName string `json:"md5FileName,omitempty"` // 随包导入的MD5文件
+	BuildTime   string `json:"buildTime,omitempty"`   // 安装后设备应返回的buildTime
+	Notes       string `json:"notes,omitempty"`
+	UploadedAt  string `json:"uploadedAt"`
+	LastUsedAt  string `json:"lastUsedAt,omitempty"`
+	Pinned      bool   `json:"pinned,omitempty"` // 固定的包不会被垃圾回收
+
+	Manifest  *PackageManifest `json:"manifest,omitempty"`  // 签名包的清单，未签名的包为空
+	Signature string           `json:"signature,omitempty"` // 清单的ed25519签名(base64)
+	Override  *PackageOverride `json:"override,omitempty"`  // 未签名或签名无法验证时的放行记录
+}
+
+// PackageReference is a schedule, update job or rollout that still needs a package
+type PackageReference struct {
+	PackageID string `json:"packageId,omitempty"` // 来源于本地包仓库时的包ID
+	FilePath  string `json:"filePath,omitempty"`  // 没有包ID时的更新文件路径
+}
@@ -0,0 +1,35 @@
+package models
+
+// Pre-flight check names
+const (
+	CheckLogin   = "login"   // HTTP登录
+	CheckBuild   = "build"   // 读取当前buildTime
+	CheckDisk    = "disk"    // 程序所在分区的可用空间
+	CheckService = "service" // systemctl is-active
+	CheckClock   = "clock"   // 设备时钟与本机的偏差
+)
+
+// Pre-flight check states, only a failed check makes a device no-go
+const (
+	CheckPassed  = "pass"
+	CheckWarning = "warn"
+	CheckFailed  = "fail"
+	CheckSkipped = "skip" // 缺少SSH账号等原因未检查
+)
+
+// PreflightCheck is the result of one check on one device
+type PreflightCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// PreflightResult is one row of the go/no-go table shown before an update
+type PreflightResult struct {
+	DeviceID  string           `json:"deviceId"`
+	IP        string           `json:"ip"`
+	Name      string           `json:"name,omitempty"`
+	BuildTime string           `json:"buildTime,omitempty"` // 检查时读取到的buildTime
+	Go        bool             `json:"go"`                  // 没有未通过的检查，可以升级
+	Checks    []PreflightCheck `json:"checks"`
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// rawObject keeps the JSON object a model was decoded from, so that fields the model
+// does not know about, and values it cannot represent exactly, survive a round trip.
+// When the model is encoded again only the known fields that were changed are written
+// over the original object.
+type rawObject struct {
+	fields map[string]json.RawMessage // 原始对象的所有字段
+	known  map[string]json.RawMessage // 解码后已知字段重新编码的值，用于判断是否被修改
+}
+
+// decode decodes data into v, which must be a pointer to a type without custom
+// unmarshaling, and remembers the original object
+func (o *rawObject) decode(data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &o.fields); err != nil {
+		return err
+	}
+	known, err := objectFields(v)
+	if err != nil {
+		return err
+	}
+	o.known = known
+	return nil
+}
+
+// encode encodes v, a type without custom marshaling, over the original object
+func (o rawObject) encode(v interface{}) ([]byte, error) {
+	if o.fields == nil {
+		return json.Marshal(v)
+	}
+	current, err := objectFields(v)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]json.RawMessage, len(o.fields)+len(current))
+	names := make(map[string]string, len(o.fields))
+	for name, value := range o.fields {
+		merged[name] = value
+		names[strings.ToLower(name)] = name
+	}
+	for name, value := range current {
+		// 未修改的字段保留原始值，原始对象中没有的字段也不添加
+		if original, ok := o.known[name]; ok && bytes.Equal(original, value) {
+			continue
+		}
+		// 解码时字段名不区分大小写，写回设备使用的字段名
+		if originalName, ok := names[strings.ToLower(name)]; ok {
+			name = originalName
+		}
+		merged[name] = value
+	}
+	return json.Marshal(merged)
+}
+
+// objectFields encodes v and splits the resulting object into its fields
+func objectFields(v interface{}) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
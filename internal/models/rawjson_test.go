@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+const deviceAlgorithm = `{
+	"Type": 6,
+	"TrackInterval": 1,
+	"DetectInterval": 2,
+	"AlarmInterval": 60,
+	"Threshold": 0,
+	"TargetSize": {"MinDetect": 10, "MaxDetect": 500, "MinTrack": 5},
+	"DetectPoints": [{"X": 0.25, "Y": 0.5}],
+	"DetectInfos": [{"Id": 1, "HotArea": [{"X": 0.1, "Y": 0.2}], "ExtraConfig": {"nozzle": "3"}}],
+	"TripWire": {"Points": [1, 2]},
+	"ExtraConfig": {
+		"camera_index": "1",
+		"precision": "0.85",
+		"interval": 30,
+		"defs": [{"Name": "precision", "Desc": "精度", "Type": "float", "Unit": "", "Default": "0.8", "Min": 0}]
+	}
+}`
+
+// decodeObject 解码为通用对象，便于比较
+func decodeObject(t *testing.T, data []byte) map[string]interface{} {
+	t.Helper()
+	var object map[string]interface{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return object
+}
+
+func TestAlgorithmRoundTripIsLossless(t *testing.T) {
+	var algorithm Algorithm
+	if err := json.Unmarshal([]byte(deviceAlgorithm), &algorithm); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	data, err := json.Marshal(algorithm)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	if got, want := decodeObject(t, data), decodeObject(t, []byte(deviceAlgorithm)); !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip changed the configuration:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestAlgorithmIndexUpdateKeepsSettings(t *testing.T) {
+	var algorithm Algorithm
+	if err := json.Unmarshal([]byte(deviceAlgorithm), &algorithm); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	algorithm.ExtraConfig.CameraIndex = "2"
+	algorithm.Threshold = 40
+	data, err := json.Marshal(algorithm)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	want := decodeObject(t, []byte(deviceAlgorithm))
+	want["Threshold"] = float64(40)
+	want["ExtraConfig"].(map[string]interface{})["camera_index"] = "2"
+	if got := decodeObject(t, data); !reflect.DeepEqual(got, want) {
+		t.Errorf("index update changed other settings:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestNewAlgorithmEncodesKnownFields(t *testing.T) {
+	data, err := json.Marshal(ExtraConfig{CameraIndex: "3"})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if got := decodeObject(t, data); got["camera_index"] != "3" {
+		t.Errorf("camera_index = %v, want 3", got["camera_index"])
+	}
+}
@@ -0,0 +1,71 @@
+package models
+
+// Rollout strategies for splitting the non-canary devices into waves
+const (
+	RolloutByPercent = "percent" // 按百分比分批
+	RolloutByRegion  = "region"  // 按区域分批
+)
+
+// Rollout statuses
+const (
+	RolloutPending   = "pending"
+	RolloutRunning   = "running"
+	RolloutPaused    = "paused"
+	RolloutCompleted = "completed"
+	RolloutAborted   = "aborted"
+)
+
+// Rollout wave statuses
+const (
+	WavePending   = "pending"
+	WaveUploading = "uploading"
+	WaveSoaking   = "soaking"
+	WaveCompleted = "completed"
+	WaveFailed    = "failed" // 失败率超过阈值，继续执行时将进入下一批
+)
+
+// RolloutPlan describes how a firmware update is staged across devices
+type RolloutPlan struct {
+	DeviceIDs               []string `json:"deviceIds"`               // 参与发布的设备
+	CanaryIDs               []string `json:"canaryIds,omitempty"`     // 金丝雀设备，为空时取前CanaryCount个设备
+	CanaryCount             int      `json:"canaryCount,omitempty"`   // 未指定金丝雀设备时的数量
+	Strategy                string   `json:"strategy"`                // percent或region
+	WavePercents            []int    `json:"wavePercents,omitempty"`  // 每批占剩余设备的百分比，不足100时剩余设备作为最后一批
+	SoakMinutes             int      `json:"soakMinutes"`             // 每批完成后的观察时间
+	FailureThresholdPercent int      `json:"failureThresholdPercent"` // 单批失败率超过该值时自动暂停
+
+	Verify UpgradeOptions `json:"verify"` // 每台设备上传后的验证方式
+}
+
+// RolloutWave is one batch of devices in a rollout
+type RolloutWave struct {
+	Name       string         `json:"name"`
+	DeviceIDs  []string       `json:"deviceIds"`
+	Status     string         `json:"status"`
+	StartedAt  string         `json:"startedAt,omitempty"`
+	SoakUntil  string         `json:"soakUntil,omitempty"`
+	FinishedAt string         `json:"finishedAt,omitempty"`
+	Results    []UpdateResult `json:"results,omitempty"` // 上传结果
+	Health     []UpdateResult `json:"health,omitempty"`  // 观察期结束后的健康检查结果
+
+	RolledBackAt string         `json:"rolledBackAt,omitempty"`
+	Rollback     []UpdateResult `json:"rollback,omitempty"` // 回滚结果
+}
+
+// Rollout is a persisted staged firmware update
+type Rollout struct {
+	ID          string        `json:"id"`
+	Name        string        `json:"name"`
+	CreatedAt   string        `json:"createdAt"`
+	UpdatedAt   string        `json:"updatedAt"`
+	PackageID   string        `json:"packageId,omitempty"` // 来源于本地包仓库时的包ID
+	FileName    string        `json:"fileName"`
+	MD5FileName string        `json:"md5FileName,omitempty"`
+	Username    string        `json:"username"`
+	Password    string        `json:"password"`
+	Plan        RolloutPlan   `json:"plan"`
+	Waves       []RolloutWave `json:"waves"`
+	CurrentWave int           `json:"currentWave"`
+	Status      string        `json:"status"`
+	Message     string        `json:"message,omitempty"` // 暂停或中止的原因
+}
@@ -0,0 +1,49 @@
+package models
+
+// Scheduled update statuses
+const (
+	ScheduleWaiting   = "scheduled"
+	ScheduleRunning   = "running"
+	SchedulePaused    = "paused" // 错过执行时间或维护窗口，需要手动继续
+	ScheduleCompleted = "completed"
+	ScheduleCancelled = "cancelled"
+)
+
+// MaintenanceWindow is a recurring period in which scheduled updates may run for a region
+type MaintenanceWindow struct {
+	Region string `json:"region"`         // 区域，空字符串表示未分配区域的设备
+	Days   []int  `json:"days,omitempty"` // 星期几(0为周日)，为空表示每天
+	Start  string `json:"start"`          // 开始时间，格式15:04
+	End    string `json:"end"`            // 结束时间，早于开始时间表示跨越午夜
+}
+
+// ScheduledUpdate is an update job that runs at a given time or inside the devices' maintenance windows
+type ScheduledUpdate struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+
+	RunAt        string `json:"runAt,omitempty"`        // 最早执行时间(本地时间，格式2006-01-02 15:04)，为空表示立即
+	UseWindows   bool   `json:"useWindows,omitempty"`   // 只在设备所在区域的维护窗口内执行
+	GraceMinutes int    `json:"graceMinutes,omitempty"` // 不使用维护窗口时，超过执行时间多久仍未开始视为错过，默认30分钟
+
+	DeviceIDs []string `json:"deviceIds"`
+	Pending   []string `json:"pending"` // 尚未更新的设备ID
+
+	PackageID   string         `json:"packageId,omitempty"`
+	FileName    string         `json:"fileName"`
+	FilePath    string         `json:"filePath"`
+	MD5FileName string         `json:"md5FileName,omitempty"`
+	MD5FilePath string         `json:"md5FilePath,omitempty"`
+	Username    string         `json:"username"`
+	Password    string         `json:"password"` // 计划完成或取消后清除
+	Options     UpgradeOptions `json:"options"`
+
+	Status    string         `json:"status"`
+	Message   string         `json:"message,omitempty"`
+	StartedAt string         `json:"startedAt,omitempty"`
+	CheckedAt string         `json:"checkedAt,omitempty"` // 应用最后一次检查该计划的时间，用于发现应用未运行期间错过的维护窗口
+	JobIDs    []string       `json:"jobIds,omitempty"`    // 执行时创建的更新任务
+	Results   []UpdateResult `json:"results,omitempty"`
+}
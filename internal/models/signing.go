@@ -0,0 +1,37 @@
+package models
+
+// PackageManifest describes the binary inside a signed package. The ed25519 signature
+// covers the JSON encoding of this struct, so the binary's checksums are signed as well.
+type PackageManifest struct {
+	FileName     string `json:"fileName"`
+	Size         int64  `json:"size"`
+	SHA256       string `json:"sha256"`
+	MD5          string `json:"md5"`
+	TargetBuild  string `json:"targetBuild,omitempty"`  // 安装后设备应返回的buildTime
+	MinBuild     string `json:"minBuild,omitempty"`     // 可以直接升级到该包的最低buildTime
+	ReleaseNotes string `json:"releaseNotes,omitempty"` // 发布说明
+	SignedAt     string `json:"signedAt"`
+	KeyID        string `json:"keyId"` // 签名公钥的ID
+}
+
+// TrustedKey is an ed25519 public key whose signatures are accepted
+type TrustedKey struct {
+	ID        string `json:"id"` // 公钥SHA-256的前16位十六进制
+	Name      string `json:"name"`
+	PublicKey string `json:"publicKey"` // base64编码的公钥
+	AddedAt   string `json:"addedAt"`
+}
+
+// PackageOverride records that a package without a valid signature was approved for deployment
+type PackageOverride struct {
+	Reason     string `json:"reason"`
+	ApprovedAt string `json:"approvedAt"`
+}
+
+// AuditEntry is one record of the audit log
+type AuditEntry struct {
+	Time   string `json:"time"`
+	Action string `json:"action"`
+	Target string `json:"target"`           // 操作对象，如包文件名和ID
+	Reason string `json:"reason,omitempty"` // 操作人填写的原因
+}
@@ -0,0 +1,57 @@
+package models
+
+// Update job statuses
+const (
+	UpdateJobRunning     = "running"
+	UpdateJobInterrupted = "interrupted" // 应用在更新过程中退出，可以继续剩余的设备
+	UpdateJobWaiting     = "waiting"     // 在线设备已处理完，等待离线设备上线后自动更新
+	UpdateJobCompleted   = "completed"
+)
+
+// UpdateJob is a persisted batch update that can be resumed on the remaining devices
+type UpdateJob struct {
+	ID        string `json:"id"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+	Status    string `json:"status"`
+
+	PackageID   string `json:"packageId,omitempty"` // 来源于本地包仓库时的包ID
+	FileName    string `json:"fileName"`
+	FilePath    string `json:"filePath"`
+	MD5FileName string `json:"md5FileName,omitempty"`
+	MD5FilePath string `json:"md5FilePath,omitempty"`
+	SHA256      string `json:"sha256"` // 继续任务前校验文件未被修改
+
+	Username string         `json:"username"`
+	Password string         `json:"password"`
+	Options  UpgradeOptions `json:"options"`
+
+	DeviceIDs []string       `json:"deviceIds"`
+	Pending   []string       `json:"pending"` // 尚未得到结果的设备ID
+	Results   []UpdateResult `json:"results"` // 每台设备最近一次的结果
+
+	Deferred      []string `json:"deferred,omitempty"`      // 离线、等待上线后自动更新的设备ID
+	DeferredUntil string   `json:"deferredUntil,omitempty"` // 超过该时间仍未上线的设备记为跳过
+
+	Preflight []PreflightResult `json:"preflight,omitempty"` // 上传前的检查结果，未开启检查时为空
+}
+
+// UpdateJobReport groups the devices of an update job by outcome
+type UpdateJobReport struct {
+	JobID      string            `json:"jobId"`
+	Status     string            `json:"status"`
+	Completed  []UpdateJobDevice `json:"completed"` // 已更新
+	Failed     []UpdateJobDevice `json:"failed"`
+	WrongBuild []UpdateJobDevice `json:"wrongBuild"` // 已安装，但运行的不是预期版本
+	Skipped    []UpdateJobDevice `json:"skipped"`    // 维护模式、已运行目标版本、离线超过期限等原因未更新
+	Pending    []UpdateJobDevice `json:"pending"`    // 尚未执行，包括等待上线的设备
+}
+
+// UpdateJobDevice is one device in an update job report
+type UpdateJobDevice struct {
+	DeviceID string `json:"deviceId"`
+	IP       string `json:"ip"`
+	Name     string `json:"name,omitempty"`
+	Deferred bool   `json:"deferred,omitempty"` // 等待设备上线
+	Message  string `json:"message,omitempty"`
+}
@@ -0,0 +1,20 @@
+package models
+
+// Workspace represents a customer project with its own device inventory and settings
+type Workspace struct {
+	ID          string               `json:"id"`
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	CreatedAt   string               `json:"createdAt"`
+	Archived    bool                 `json:"archived"`
+	ArchivedAt  string               `json:"archivedAt,omitempty"`
+	Credentials WorkspaceCredentials `json:"credentials"`
+}
+
+// WorkspaceCredentials stores the default device credentials of a workspace
+type WorkspaceCredentials struct {
+	Username    string `json:"username"`    // 设备Web登录用户名
+	Password    string `json:"password"`    // 设备Web登录密码
+	SSHUsername string `json:"sshUsername"` // 设备SSH用户名
+	SSHPassword string `json:"sshPassword"` // 设备SSH密码
+}
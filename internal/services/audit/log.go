@@ -0,0 +1,97 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"application-updater/internal/models"
+	"application-updater/internal/utils"
+)
+
+const (
+	logFile    = "audit.log"
+	timeLayout = "2006-01-02 15:04:05"
+)
+
+// Actions recorded in the audit log
+const (
+	ActionTrustKey        = "trust-key"
+	ActionUntrustKey      = "untrust-key"
+	ActionOverridePackage = "override-package"  // 放行未签名或签名无法验证的包
+	ActionDeployOverride  = "deploy-overridden" // 部署了依靠放行记录的包
+)
+
+// Log is an append-only record of security relevant decisions, one JSON entry per line
+type Log struct {
+	mutex sync.Mutex
+	path  string
+}
+
+// NewLog opens the audit log in dir
+func NewLog(dir string) *Log {
+	if err := utils.EnsureDirExists(dir); err != nil {
+		fmt.Printf("Failed to create audit log directory: %v\n", err)
+	}
+	return &Log{path: filepath.Join(dir, logFile)}
+}
+
+// Record appends an entry; callers must not go ahead with the action if this fails
+func (l *Log) Record(action, target, reason string) error {
+	entry := models.AuditEntry{Time: time.Now().Format(timeLayout), Action: action, Target: target, Reason: reason}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return file.Sync()
+}
+
+// List returns all entries, newest first
+func (l *Log) List() ([]models.AuditEntry, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	entries := []models.AuditEntry{}
+	file, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry models.AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
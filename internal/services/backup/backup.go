@@ -12,6 +12,7 @@ import (
 
 	"application-updater/internal/models"
 	"application-updater/internal/services/device"
+	"application-updater/internal/services/lock"
 
 	"golang.org/x/crypto/ssh"
 )
@@ -19,13 +20,15 @@ import (
 // Service handles backup operations for device configurations and databases
 type Service struct {
 	deviceService *device.Service
+	settingsDir   string
 	mutex         sync.Mutex
 }
 
-// NewService creates a new backup service
-func NewService(deviceService *device.Service) *Service {
+// NewService creates a new backup service storing its settings in settingsDir
+func NewService(deviceService *device.Service, settingsDir string) *Service {
 	return &Service{
 		deviceService: deviceService,
+		settingsDir:   settingsDir,
 	}
 }
 
@@ -42,8 +45,19 @@ func (s *Service) BackupDevices(backupSettings *models.BackupSettings, username
 		password = "admin" // Default password
 	}
 
+	// Devices in maintenance mode are left out of batch backups
+	maintenance := s.deviceService.MaintenanceReasons(selectIps)
+
 	var results []models.BackupResult
 	for _, ip := range selectIps {
+		if reason, ok := maintenance[ip]; ok {
+			results = append(results, models.BackupResult{
+				Success: false,
+				Message: device.MaintenanceMessage(reason),
+				IP:      ip,
+			})
+			continue
+		}
 
 		result, err := s.backupSingleDevice(context.Background(), ip, filepath.Join(backupSettings.BackupPath, backupSettings.AreaPath, ip), username, password)
 		if err != nil {
@@ -72,8 +86,15 @@ func (s *Service) backupSingleDevice(ctx context.Context, ip string, backupPath
 		return nil, fmt.Errorf("backup path is empty")
 	}
 
+	// Stopping application-web must not race with an upgrade or restore on the same device
+	release, err := lock.Default().TryAcquire(ip, lock.OperationBackup)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	// Ensure backup directory exists
-	err := os.MkdirAll(backupPath, 0755)
+	err = os.MkdirAll(backupPath, 0755)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create backup directory: %w", err)
 	}
@@ -141,12 +162,11 @@ func (s *Service) SaveBackupSettings(settings *models.BackupSettings) error {
 		return fmt.Errorf("failed to marshal backup settings: %w", err)
 	}
 
-	configDir := "configs"
-	if err := os.MkdirAll(configDir, 0755); err != nil {
+	if err := os.MkdirAll(s.settingsDir, 0755); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	settingsPath := filepath.Join(configDir, "backup_settings.json")
+	settingsPath := filepath.Join(s.settingsDir, "backup_settings.json")
 	if err := ioutil.WriteFile(settingsPath, data, 0644); err != nil {
 		return fmt.Errorf("failed to write backup settings: %w", err)
 	}
@@ -160,14 +180,13 @@ func (s *Service) GetBackupSettings() (*models.BackupSettings, error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	configDir := "configs"
-	settingsPath := filepath.Join(configDir, "backup_settings.json")
+	settingsPath := filepath.Join(s.settingsDir, "backup_settings.json")
 
 	_, err := os.Stat(settingsPath)
 	if os.IsNotExist(err) {
 		// Default settings if file doesn't exist
 		defaultSettings := &models.BackupSettings{
-			BackupPath: filepath.Join("backups"),
+			BackupPath: filepath.Join(s.settingsDir, "backups"),
 			AreaPath:   "area1",
 			Username:   "root",
 			Password:   "ematech",
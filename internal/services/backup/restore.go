@@ -2,8 +2,12 @@ package backup
 
 import (
 	"application-updater/internal/models"
+	"application-updater/internal/services/device"
+	"application-updater/internal/services/lock"
+	"application-updater/internal/utils"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
@@ -16,8 +20,19 @@ func (s *Service) RestoreDevicesDB(username, password, storageDir, areaDir strin
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
+	// Devices in maintenance mode are left out of batch restores
+	maintenance := s.deviceService.MaintenanceReasons(selectIps)
+
 	var results []models.RestoreResult
 	for _, ip := range selectIps {
+		if reason, ok := maintenance[ip]; ok {
+			results = append(results, models.RestoreResult{
+				Success: false,
+				Message: device.MaintenanceMessage(reason),
+				IP:      ip,
+			})
+			continue
+		}
 
 		result, err := s.RestoreDeviceDB(context.Background(), ip, username, password, filepath.Join(storageDir, areaDir, ip))
 		if err != nil {
@@ -36,8 +51,14 @@ func (s *Service) RestoreDevicesDB(username, password, storageDir, areaDir strin
 
 // RestoreDeviceDB restores database for a single device
 func (s *Service) RestoreDeviceDB(ctx context.Context, ip string, username, password string, backupDir string) (*models.RestoreResult, error) {
+	release, err := lock.Default().TryAcquire(ip, lock.OperationRestore)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	// Validate backup point exists
-	_, err := os.Stat(backupDir)
+	_, err = os.Stat(backupDir)
 	if os.IsNotExist(err) {
 		return nil, fmt.Errorf("backup directory does not exist: %s", backupDir)
 	}
@@ -91,8 +112,10 @@ func (s *Service) RestoreDeviceDB(ctx context.Context, ip string, username, pass
 		return nil, fmt.Errorf("failed to backup existing database on device: %w", err)
 	}
 
-	// 3. Copy the backup database to the device
-	err = scpFileToRemote(client, dbFilePath, remoteDbPath)
+	// 3. Copy the backup database to the device within the transfer bandwidth caps
+	err = utils.SCPFileToRemote(client, dbFilePath, remoteDbPath, func(r io.Reader) io.Reader {
+		return s.deviceService.LimitReader(ip, r)
+	})
 	if err != nil {
 		// Try to restore from backup and restart the service
 		restoreCmd := fmt.Sprintf("if [ -f %s.bak.* ]; then cp %s.bak.* %s; fi",
@@ -20,12 +20,12 @@ func NewCameraServiceAdapter(service *Service, auth *device.Auth) *CameraService
 }
 
 // ConfigureCamerasFromData adapts the ConfigureCamerasFromData method to match the excel.CameraService interface
-func (a *CameraServiceAdapter) ConfigureCamerasFromData(deviceConfigs []models.ExcelRow, username, password, urlTemplate string, algorithmType int, region string) []models.CameraConfigResult {
+func (a *CameraServiceAdapter) ConfigureCamerasFromData(deviceConfigs []models.ExcelRow, username, password, urlTemplate string, algorithms models.CameraAlgorithms, region string) []models.CameraConfigResult {
 	// Create a function to get token that can be passed to the original method
 	getTokenFunc := func(ip, user, pass string) (string, error) {
 		return a.auth.LoginToDevice(ip, user, pass)
 	}
 
 	// Call the original method with the token function and region
-	return a.service.Config.ConfigureCamerasFromData(deviceConfigs, getTokenFunc, username, password, urlTemplate, algorithmType, region)
+	return a.service.Config.ConfigureCamerasFromData(deviceConfigs, getTokenFunc, username, password, urlTemplate, algorithms, region)
 }
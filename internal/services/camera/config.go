@@ -12,29 +12,45 @@ import (
 
 	"application-updater/internal/models"
 	"application-updater/internal/services/device"
+	"application-updater/internal/services/lock"
 )
 
 // Config 摄像头配置结构体
 type Config struct {
 	client        *http.Client
-	DeviceService *device.Service
+	deviceService func() *device.Service // 返回当前工作区的设备服务，切换工作区后随之变化
+
+	planMutex sync.Mutex
+	plans     map[string]models.CameraPlan // 等待批准的配置计划
 }
 
 // NewConfig 创建配置服务实例
 func NewConfig(client *http.Client) *Config {
 	return &Config{
 		client: client,
+		plans:  make(map[string]models.CameraPlan),
 	}
 }
 
-// SetDeviceManager 设置设备管理器
-func (c *Config) SetDeviceService(service *device.Service) {
-	c.DeviceService = service
+// SetDeviceService 设置获取当前设备服务的函数
+func (c *Config) SetDeviceService(service func() *device.Service) {
+	c.deviceService = service
 }
 
-// ConfigureCamera 配置摄像头
-func (c *Config) ConfigureCamera(ip, token, cameraName, cameraURL string, algorithmType int, existingCamera bool) (bool, string) {
-	fmt.Printf("DEBUG: 开始配置摄像头: IP=%s, 摄像头名称=%s, URL=%s, 算法类型=%d\n", ip, cameraName, cameraURL, algorithmType)
+// devices 返回当前的设备服务，未设置时返回nil
+func (c *Config) devices() *device.Service {
+	if c.deviceService == nil {
+		return nil
+	}
+	return c.deviceService()
+}
+
+// ConfigureCamera 配置摄像头，types为任务的全部算法类型
+func (c *Config) ConfigureCamera(ip, token, cameraName, cameraURL string, types []int, existingCamera bool) (bool, string) {
+	fmt.Printf("DEBUG: 开始配置摄像头: IP=%s, 摄像头名称=%s, URL=%s, 算法类型=%v\n", ip, cameraName, cameraURL, types)
+	if len(types) == 0 {
+		return false, "未选择算法类型"
+	}
 
 	// 根据摄像头是否存在，选择添加或修改
 	var url string
@@ -52,7 +68,7 @@ func (c *Config) ConfigureCamera(ip, token, cameraName, cameraURL string, algori
 		"taskId":     cameraName,
 		"deviceName": cameraName,
 		"url":        cameraURL,
-		"types":      []int{algorithmType},
+		"types":      types,
 	}
 	requestBody, err := json.Marshal(requestData)
 	if err != nil {
@@ -188,39 +204,48 @@ func (c *Config) GetCameraConfig(ip, token, taskId string) (*models.CameraConfig
 	return &response.Result, nil
 }
 
-// SetCameraIndex 设置摄像头索引
+// SetCameraIndex 设置摄像头索引，修改任务中每个算法的camera_index
 func (c *Config) SetCameraIndex(ip, token, taskId string, config *models.CameraConfig, index int) (bool, string) {
 	fmt.Printf("DEBUG: 开始设置摄像头索引: IP=%s, 任务ID=%s, 索引=%d\n", ip, taskId, index)
 
-	// 修改摄像头索引
-	var modified bool
+	// 每个算法有自己的配置，只提交需要修改的算法
+	modified := 0
 	for i := range config.Algorithms {
-		// 修改ExtraConfig中的camera_index
-		if config.Algorithms[i].ExtraConfig.CameraIndex != fmt.Sprintf("%d", index) {
-			fmt.Printf("DEBUG: 更新算法 %d 的摄像头索引: %s -> %d\n", i, config.Algorithms[i].ExtraConfig.CameraIndex, index)
-			config.Algorithms[i].ExtraConfig.CameraIndex = fmt.Sprintf("%d", index)
-			modified = true
+		if config.Algorithms[i].ExtraConfig.CameraIndex == fmt.Sprintf("%d", index) {
+			continue
 		}
+		fmt.Printf("DEBUG: 更新算法 %d 的摄像头索引: %s -> %d\n", config.Algorithms[i].Type, config.Algorithms[i].ExtraConfig.CameraIndex, index)
+		config.Algorithms[i].ExtraConfig.CameraIndex = fmt.Sprintf("%d", index)
+		if err := c.modifyAlgorithmConfig(ip, token, taskId, config.Algorithms[i]); err != nil {
+			return false, fmt.Sprintf("设置算法 %d 的摄像头索引失败: %v", config.Algorithms[i].Type, err)
+		}
+		modified++
 	}
 
-	if !modified {
+	if modified == 0 {
 		fmt.Printf("DEBUG: 摄像头索引已经是正确的值 %d，无需修改\n", index)
 		return true, "摄像头索引已经是正确的值，无需修改"
 	}
 
+	fmt.Printf("DEBUG: 成功将 %d 个算法的摄像头索引设置为 %d\n", modified, index)
+	return true, fmt.Sprintf("成功将 %d 个算法的摄像头索引设置为 %d", modified, index)
+}
+
+// modifyAlgorithmConfig 通过config/mod提交一个算法的配置
+func (c *Config) modifyAlgorithmConfig(ip, token, taskId string, algorithm models.Algorithm) error {
 	url := fmt.Sprintf("http://%s:8089/api/config/mod", ip)
 	fmt.Printf("DEBUG: 请求URL: %s\n", url)
 
 	// 按照FEATURE.md中的示例格式构造请求载荷
 	requestData := map[string]interface{}{
 		"TaskID":    taskId,
-		"Algorithm": config.Algorithms[0], // 使用第一个算法（通常只有一个）
+		"Algorithm": algorithm,
 	}
 
 	requestBody, err := json.Marshal(requestData)
 	if err != nil {
 		fmt.Printf("ERROR: 创建请求失败: %v\n", err)
-		return false, fmt.Sprintf("创建请求失败: %v", err)
+		return fmt.Errorf("创建请求失败: %w", err)
 	}
 
 	// 打印请求体，但限制长度以避免日志过大
@@ -235,19 +260,18 @@ func (c *Config) SetCameraIndex(ip, token, taskId string, config *models.CameraC
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
 	if err != nil {
 		fmt.Printf("ERROR: 创建HTTP请求失败: %v\n", err)
-		return false, fmt.Sprintf("创建HTTP请求失败: %v", err)
+		return fmt.Errorf("创建HTTP请求失败: %w", err)
 	}
 
 	// 设置header
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Token", token)
-	fmt.Printf("DEBUG: 请求头: Content-Type=%s, Token=%s\n", req.Header.Get("Content-Type"), req.Header.Get("Token"))
 
 	// 发送请求
 	resp, err := c.client.Do(req)
 	if err != nil {
 		fmt.Printf("ERROR: 发送请求失败: %v\n", err)
-		return false, fmt.Sprintf("发送请求失败: %v", err)
+		return fmt.Errorf("发送请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -257,45 +281,35 @@ func (c *Config) SetCameraIndex(ip, token, taskId string, config *models.CameraC
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		fmt.Printf("ERROR: 读取响应体失败: %v\n", err)
-		return false, fmt.Sprintf("读取响应体失败: %v", err)
+		return fmt.Errorf("读取响应体失败: %w", err)
 	}
 
 	fmt.Printf("DEBUG: 响应体: %s\n", string(respBody))
 
-	// 将读取的响应体转回io.Reader用于解析
-	resp.Body = io.NopCloser(bytes.NewBuffer(respBody))
-
 	// 读取响应
 	var response struct {
 		Code int    `json:"code"`
 		Msg  string `json:"msg"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+	if err := json.Unmarshal(respBody, &response); err != nil {
 		fmt.Printf("ERROR: 解析响应失败: %v\n", err)
-		return false, fmt.Sprintf("解析响应失败: %v", err)
+		return fmt.Errorf("解析响应失败: %w", err)
 	}
 
 	if response.Code != 0 {
-		fmt.Printf("ERROR: 设置摄像头索引失败: 代码=%d, 消息=%s\n", response.Code, response.Msg)
-		return false, fmt.Sprintf("设置摄像头索引失败: %s", response.Msg)
+		fmt.Printf("ERROR: 修改算法配置失败: 代码=%d, 消息=%s\n", response.Code, response.Msg)
+		return fmt.Errorf("%s", response.Msg)
 	}
-
-	fmt.Printf("DEBUG: 成功将摄像头索引设置为 %d\n", index)
-	return true, fmt.Sprintf("成功将摄像头索引设置为 %d", index)
+	return nil
 }
 
 // ConfigureCamerasFromData 批量配置摄像头
-func (c *Config) ConfigureCamerasFromData(deviceConfigs []models.ExcelRow, getTokenFunc func(string, string, string) (string, error), username, password, urlTemplate string, algorithmType int, region string) []models.CameraConfigResult {
+func (c *Config) ConfigureCamerasFromData(deviceConfigs []models.ExcelRow, getTokenFunc func(string, string, string) (string, error), username, password, urlTemplate string, algorithms models.CameraAlgorithms, region string) []models.CameraConfigResult {
 	// 创建一个带缓冲的结果通道，用于收集所有设备的结果
 	resultChan := make(chan []models.CameraConfigResult, len(deviceConfigs))
 
 	// 按设备IP分组
-	deviceGroups := make(map[string][]models.ExcelRow)
-	for _, config := range deviceConfigs {
-		if config.DeviceIP != "" && config.CameraName != "" && config.CameraInfo != "/" {
-			deviceGroups[config.DeviceIP] = append(deviceGroups[config.DeviceIP], config)
-		}
-	}
+	deviceGroups, _ := configurableRows(deviceConfigs)
 
 	// 控制最大并发数量
 	maxConcurrent := 8 // 最多同时处理8个设备
@@ -322,7 +336,7 @@ func (c *Config) ConfigureCamerasFromData(deviceConfigs []models.ExcelRow, getTo
 
 			for deviceIP := range deviceIPChan {
 				configs := deviceGroups[deviceIP]
-				deviceResults := c.configureCamerasForDevice(deviceIP, configs, getTokenFunc, username, password, urlTemplate, algorithmType, workerId, region)
+				deviceResults := c.configureCamerasForDevice(deviceIP, configs, getTokenFunc, username, password, urlTemplate, algorithms, workerId, region)
 				resultChan <- deviceResults
 			}
 		}(i)
@@ -344,10 +358,120 @@ func (c *Config) ConfigureCamerasFromData(deviceConfigs []models.ExcelRow, getTo
 	return results
 }
 
+// desiredCamera 根据Excel行和URL模板计算摄像头URL和设备内索引，索引未填写时为1
+func desiredCamera(row models.ExcelRow, urlTemplate string) (string, int, bool) {
+	cameraIndex := row.DeviceIndex
+	if cameraIndex <= 0 {
+		cameraIndex = 1
+	}
+
+	// 从摄像头信息中提取IP，使用模板替换IP
+	parts := strings.Split(row.CameraInfo, "/")
+	if len(parts) < 1 || parts[0] == "" {
+		return "", cameraIndex, false
+	}
+	return strings.Replace(urlTemplate, "<ip>", parts[0], -1), cameraIndex, true
+}
+
+// TaskTypes 计算任务应配置的算法类型。Excel行有算法列时使用该列，否则使用配置的算法；
+// 任务已存在且策略为合并时保留任务已有的算法
+func TaskTypes(row models.ExcelRow, algorithms models.CameraAlgorithms, current []int, exists bool) []int {
+	desired := row.AlgorithmTypes
+	if len(desired) == 0 {
+		desired = algorithms.Types
+	}
+
+	var types []int
+	seen := make(map[int]bool)
+	add := func(list []int) {
+		for _, t := range list {
+			if !seen[t] {
+				seen[t] = true
+				types = append(types, t)
+			}
+		}
+	}
+	if exists && algorithms.Policy == models.AlgorithmPolicyMerge {
+		add(current)
+	}
+	add(desired)
+	return types
+}
+
+// configurableRows 按设备IP分组可以配置的Excel行
+func configurableRows(rows []models.ExcelRow) (map[string][]models.ExcelRow, []string) {
+	groups := make(map[string][]models.ExcelRow)
+	var order []string
+	for _, row := range rows {
+		if row.DeviceIP != "" && row.CameraName != "" && row.CameraInfo != "/" {
+			if _, ok := groups[row.DeviceIP]; !ok {
+				order = append(order, row.DeviceIP)
+			}
+			groups[row.DeviceIP] = append(groups[row.DeviceIP], row)
+		}
+	}
+	return groups, order
+}
+
+// registerDevice 将设备添加到设备管理中，已存在时不做修改
+func (c *Config) registerDevice(deviceIP, region string, workerId int) {
+	devices := c.devices()
+	if devices == nil {
+		return
+	}
+
+	// 检查设备是否已存在
+	if _, exists := devices.GetDeviceByRegionAndIP(region, deviceIP); exists {
+		fmt.Printf("INFO: [Worker-%d] 设备 %s 已存在于设备管理中\n", workerId, deviceIP)
+		return
+	}
+
+	// 设备不存在，添加设备
+	deviceInfo := models.Device{
+		ID:        models.GenerateDeviceID(region, deviceIP),
+		IP:        deviceIP,
+		Status:    "online",
+		Region:    region,
+		BuildTime: time.Now().Format("2006-01-02 15:04:05"),
+	}
+	if _, err := devices.AddDevice(deviceInfo); err != nil {
+		fmt.Printf("WARN: [Worker-%d] 将设备 %s 添加到设备管理失败: %v\n", workerId, deviceIP, err)
+	} else {
+		fmt.Printf("INFO: [Worker-%d] 已将设备 %s 添加到设备管理中，区域: %s\n", workerId, deviceIP, region)
+	}
+}
+
 // configureCamerasForDevice 处理单个设备的所有摄像头配置
-func (c *Config) configureCamerasForDevice(deviceIP string, configs []models.ExcelRow, getTokenFunc func(string, string, string) (string, error), username, password, urlTemplate string, algorithmType int, workerId int, region string) []models.CameraConfigResult {
+func (c *Config) configureCamerasForDevice(deviceIP string, configs []models.ExcelRow, getTokenFunc func(string, string, string) (string, error), username, password, urlTemplate string, algorithms models.CameraAlgorithms, workerId int, region string) []models.CameraConfigResult {
 	results := make([]models.CameraConfigResult, 0, len(configs))
 
+	// failAll 将此设备下的所有摄像头标记为失败
+	failAll := func(message string) []models.CameraConfigResult {
+		for _, config := range configs {
+			results = append(results, models.CameraConfigResult{
+				DeviceIP:   deviceIP,
+				CameraName: config.CameraName,
+				Success:    false,
+				Message:    message,
+			})
+		}
+		return results
+	}
+
+	// 维护模式中的设备不参与批量配置
+	if devices := c.devices(); devices != nil {
+		if reason, ok := devices.MaintenanceReasons([]string{deviceIP})[deviceIP]; ok {
+			return failAll(device.MaintenanceMessage(reason))
+		}
+	}
+
+	// 同一设备上的其他操作进行中时立即失败
+	release, err := lock.Default().TryAcquire(deviceIP, lock.OperationCameraConfig)
+	if err != nil {
+		return failAll(err.Error())
+	}
+	defer release()
+
 	// 为每个设备只获取一次token
 	fmt.Printf("DEBUG: [Worker-%d] 开始为设备 %s 配置摄像头，共 %d 个\n", workerId, deviceIP, len(configs))
 	token, err := getTokenFunc(deviceIP, username, password)
@@ -367,32 +491,7 @@ func (c *Config) configureCamerasForDevice(deviceIP string, configs []models.Exc
 	fmt.Printf("DEBUG: [Worker-%d] 成功登录设备 %s，获取到Token\n", workerId, deviceIP)
 
 	// 尝试将设备添加到设备管理中
-	if c.DeviceService != nil {
-		// 检查设备是否已存在
-		_, exists := c.DeviceService.GetDeviceByRegionAndIP(region, deviceIP)
-		if !exists {
-			// 设备不存在，测试并添加设备
-			deviceInfo := &models.Device{
-				IP:        deviceIP,
-				Status:    "online",
-				Region:    region,
-				BuildTime: time.Now().Format("2006-01-02 15:04:05"),
-			}
-
-			// 生成设备ID
-			deviceInfo.ID = models.GenerateDeviceID(region, deviceIP)
-
-			// 添加设备
-			_, err := c.DeviceService.AddDevice(*deviceInfo)
-			if err != nil {
-				fmt.Printf("WARN: [Worker-%d] 将设备 %s 添加到设备管理失败: %v\n", workerId, deviceIP, err)
-			} else {
-				fmt.Printf("INFO: [Worker-%d] 已将设备 %s 添加到设备管理中，区域: %s\n", workerId, deviceIP, region)
-			}
-		} else {
-			fmt.Printf("INFO: [Worker-%d] 设备 %s 已存在于设备管理中\n", workerId, deviceIP)
-		}
-	}
+	c.registerDevice(deviceIP, region, workerId)
 
 	// 获取摄像头任务列表
 	tasksClient := NewTasks(c.client)
@@ -412,33 +511,21 @@ func (c *Config) configureCamerasForDevice(deviceIP string, configs []models.Exc
 	}
 
 	for _, config := range configs {
-		// 使用传入的设备内索引，如果没有则使用默认值1
-		cameraIndex := config.DeviceIndex
-		if cameraIndex <= 0 {
-			cameraIndex = 1
-		}
-
-		// 从摄像头信息中提取IP
-		parts := strings.Split(config.CameraInfo, "/")
-		if len(parts) >= 1 {
-			cameraIP := parts[0]
-
-			// 使用模板替换IP
-			cameraURL := strings.Replace(urlTemplate, "<ip>", cameraIP, -1)
-
+		cameraURL, cameraIndex, ok := desiredCamera(config, urlTemplate)
+		if ok {
 			// 检查摄像头是否已存在
 			existingCamera := false
-			for _, camera := range cameras {
-				if camera.TaskID == config.CameraName {
-					existingCamera = true
-					fmt.Printf("DEBUG: [Worker-%d] 找到已存在的摄像头任务: %s\n", workerId, config.CameraName)
-					break
-				}
+			var currentTypes []int
+			if task := findTask(cameras, config.CameraName); task != nil {
+				existingCamera = true
+				currentTypes = task.Types
+				fmt.Printf("DEBUG: [Worker-%d] 找到已存在的摄像头任务: %s\n", workerId, config.CameraName)
 			}
 
 			// 配置摄像头，使用已获取的token
 			fmt.Printf("DEBUG: [Worker-%d] 配置摄像头: %s 在设备 %s\n", workerId, config.CameraName, deviceIP)
-			success, message := c.ConfigureCamera(deviceIP, token, config.CameraName, cameraURL, algorithmType, existingCamera)
+			types := TaskTypes(config, algorithms, currentTypes, existingCamera)
+			success, message := c.ConfigureCamera(deviceIP, token, config.CameraName, cameraURL, types, existingCamera)
 
 			// 如果配置成功，设置摄像头索引
 			if success {
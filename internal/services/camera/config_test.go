@@ -0,0 +1,68 @@
+package camera
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"application-updater/internal/models"
+)
+
+func TestTaskTypes(t *testing.T) {
+	replace := models.CameraAlgorithms{Types: []int{6, 7}, Policy: models.AlgorithmPolicyReplace}
+	merge := models.CameraAlgorithms{Types: []int{6, 7}, Policy: models.AlgorithmPolicyMerge}
+	fromExcel := models.ExcelRow{AlgorithmTypes: []int{9}}
+
+	tests := []struct {
+		name       string
+		row        models.ExcelRow
+		algorithms models.CameraAlgorithms
+		current    []int
+		exists     bool
+		want       []int
+	}{
+		{"new task", models.ExcelRow{}, merge, nil, false, []int{6, 7}},
+		{"replace", models.ExcelRow{}, replace, []int{3, 6}, true, []int{6, 7}},
+		{"merge", models.ExcelRow{}, merge, []int{3, 6}, true, []int{3, 6, 7}},
+		{"excel column", fromExcel, replace, []int{3}, true, []int{9}},
+		{"excel column merged", fromExcel, merge, []int{3}, true, []int{3, 9}},
+		{"default policy replaces", models.ExcelRow{}, models.CameraAlgorithms{Types: []int{6}}, []int{3}, true, []int{6}},
+	}
+	for _, tt := range tests {
+		if got := TaskTypes(tt.row, tt.algorithms, tt.current, tt.exists); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSetCameraIndexModifiesEveryAlgorithm(t *testing.T) {
+	var posted []int
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+		var body struct {
+			Algorithm models.Algorithm `json:"Algorithm"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		posted = append(posted, body.Algorithm.Type)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewBufferString(`{"code":0}`)), Header: make(http.Header)}
+	})}
+	config := NewConfig(client)
+
+	cameraConfig := &models.CameraConfig{Algorithms: make([]models.Algorithm, 3)}
+	for i, algorithmType := range []int{6, 7, 9} {
+		cameraConfig.Algorithms[i].Type = algorithmType
+		cameraConfig.Algorithms[i].ExtraConfig.CameraIndex = "1"
+	}
+	cameraConfig.Algorithms[1].ExtraConfig.CameraIndex = "2"
+
+	if ok, message := config.SetCameraIndex("10.0.0.1", "token", "cam1", cameraConfig, 2); !ok {
+		t.Fatalf("SetCameraIndex: %s", message)
+	}
+	if !reflect.DeepEqual(posted, []int{6, 9}) {
+		t.Errorf("posted algorithms %v, want [6 9]", posted)
+	}
+}
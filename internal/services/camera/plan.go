@@ -0,0 +1,405 @@
+package camera
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"application-updater/internal/models"
+	"application-updater/internal/services/device"
+	"application-updater/internal/services/lock"
+
+	"github.com/google/uuid"
+)
+
+// planConcurrency 生成和执行计划时同时处理的设备数
+const planConcurrency = 8
+
+// PlanCameraConfiguration 登录每台设备读取任务列表和算法配置，返回Excel与设备当前状态的差异，不修改设备。
+// 只比较选中的行，没有选中行的设备不会读取。reconcile为true时以Excel为准，设备上有但Excel中
+// 没有的任务(包括未选中的行)列为删除，保护列表中的任务除外。
+// 计划保存在内存中，批准后由ApplyCameraPlan执行
+func (c *Config) PlanCameraConfiguration(rows []models.ExcelRow, getTokenFunc func(string, string, string) (string, error), username, password, urlTemplate string, algorithms models.CameraAlgorithms, region string, reconcile bool) models.CameraPlan {
+	groups, order := selectedDevices(configurableRows(rows))
+	sheet := sheetTasks(rows)
+	plan := models.CameraPlan{
+		ID:          uuid.New().String(),
+		CreatedAt:   time.Now().Format("2006-01-02 15:04:05"),
+		URLTemplate: urlTemplate,
+		Algorithms:  algorithms,
+		Region:      region,
+		Reconcile:   reconcile,
+		Devices:     make([]models.CameraDevicePlan, len(order)),
+	}
+	protected := c.protectedTasks()
+
+	slots := make(chan struct{}, planConcurrency)
+	var wg sync.WaitGroup
+	for i, deviceIP := range order {
+		wg.Add(1)
+		go func(i int, deviceIP string) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			plan.Devices[i] = c.planDevice(deviceIP, groups[deviceIP], getTokenFunc, username, password, urlTemplate, algorithms, reconcile, sheet[deviceIP], protected)
+		}(i, deviceIP)
+	}
+	wg.Wait()
+
+	c.planMutex.Lock()
+	c.plans[plan.ID] = plan
+	c.planMutex.Unlock()
+	return plan
+}
+
+// selectedDevices 去掉没有选中行的设备
+func selectedDevices(groups map[string][]models.ExcelRow, order []string) (map[string][]models.ExcelRow, []string) {
+	var selected []string
+	for _, deviceIP := range order {
+		for _, row := range groups[deviceIP] {
+			if row.Selected {
+				selected = append(selected, deviceIP)
+				break
+			}
+		}
+	}
+	return groups, selected
+}
+
+// sheetTasks 按设备IP列出Excel中出现的所有任务名，包括摄像头信息为"/"等不会配置的行
+func sheetTasks(rows []models.ExcelRow) map[string]map[string]bool {
+	tasks := make(map[string]map[string]bool)
+	for _, row := range rows {
+		if row.DeviceIP == "" || row.CameraName == "" {
+			continue
+		}
+		if tasks[row.DeviceIP] == nil {
+			tasks[row.DeviceIP] = make(map[string]bool)
+		}
+		tasks[row.DeviceIP][row.CameraName] = true
+	}
+	return tasks
+}
+
+// protectedTasks 返回当前工作区的摄像头任务保护列表
+func (c *Config) protectedTasks() []string {
+	devices := c.devices()
+	if devices == nil {
+		return nil
+	}
+	return devices.GetSettings().ProtectedCameraTasks
+}
+
+// isProtected 检查任务是否在保护列表中，列表项为任务ID或设备IP/任务ID
+func isProtected(protected []string, deviceIP, taskID string) bool {
+	for _, item := range protected {
+		item = strings.TrimSpace(item)
+		if item == taskID || item == deviceIP+"/"+taskID {
+			return true
+		}
+	}
+	return false
+}
+
+// planDevice 读取一台设备的任务和算法配置并与Excel行比较
+func (c *Config) planDevice(deviceIP string, rows []models.ExcelRow, getTokenFunc func(string, string, string) (string, error), username, password, urlTemplate string, algorithms models.CameraAlgorithms, reconcile bool, inSheet map[string]bool, protected []string) models.CameraDevicePlan {
+	devicePlan := models.CameraDevicePlan{DeviceIP: deviceIP}
+
+	var cameras []models.Camera
+	token, err := getTokenFunc(deviceIP, username, password)
+	if err != nil {
+		devicePlan.Error = fmt.Sprintf("登录设备失败: %v", err)
+	} else if cameras, err = NewTasks(c.client).GetCameraTasksWithToken(deviceIP, token); err != nil {
+		devicePlan.Error = fmt.Sprintf("获取摄像头任务列表失败: %v", err)
+	}
+
+	for _, row := range rows {
+		if !row.Selected {
+			continue
+		}
+		if devicePlan.Error != "" {
+			entry := planEntry(deviceIP, row, urlTemplate, algorithms, nil, nil)
+			entry.Change = models.CameraChangeInvalid
+			entry.Message = "无法读取设备状态"
+			devicePlan.Entries = append(devicePlan.Entries, entry)
+			continue
+		}
+
+		task := findTask(cameras, row.CameraName)
+		var config *models.CameraConfig
+		configErr := error(nil)
+		if task != nil {
+			config, configErr = c.GetCameraConfig(deviceIP, token, row.CameraName)
+		}
+		entry := planEntry(deviceIP, row, urlTemplate, algorithms, task, config)
+		if configErr != nil {
+			entry.Message = fmt.Sprintf("读取算法配置失败，将重新设置camera_index: %v", configErr)
+		}
+		devicePlan.Entries = append(devicePlan.Entries, entry)
+	}
+
+	if reconcile && devicePlan.Error == "" {
+		devicePlan.Entries = append(devicePlan.Entries, orphanEntries(deviceIP, inSheet, cameras, protected)...)
+	}
+	return devicePlan
+}
+
+// orphanEntries 列出设备上有但不在inSheet中的任务。未选中和不会配置的行也算在Excel中，不会被删除
+func orphanEntries(deviceIP string, inSheet map[string]bool, cameras []models.Camera, protected []string) []models.CameraPlanEntry {
+	var entries []models.CameraPlanEntry
+	for _, camera := range cameras {
+		if inSheet[camera.TaskID] {
+			continue
+		}
+		entry := models.CameraPlanEntry{
+			ID:           deviceIP + "/" + camera.TaskID,
+			CameraName:   camera.TaskID,
+			Change:       models.CameraChangeDelete,
+			TaskExists:   true,
+			CurrentURL:   camera.URL,
+			CurrentTypes: camera.Types,
+			Message:      "Excel中没有该任务",
+		}
+		if isProtected(protected, deviceIP, camera.TaskID) {
+			entry.Change = models.CameraChangeProtected
+			entry.Message = "任务在保护列表中，不会删除"
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// findTask 按任务ID查找设备上的任务，不存在时返回nil
+func findTask(cameras []models.Camera, taskID string) *models.Camera {
+	for i := range cameras {
+		if cameras[i].TaskID == taskID {
+			return &cameras[i]
+		}
+	}
+	return nil
+}
+
+// planEntry 比较Excel行与设备上的任务和算法配置。task为nil表示任务不存在，config为nil表示配置未知
+func planEntry(deviceIP string, row models.ExcelRow, urlTemplate string, algorithms models.CameraAlgorithms, task *models.Camera, config *models.CameraConfig) models.CameraPlanEntry {
+	cameraURL, cameraIndex, ok := desiredCamera(row, urlTemplate)
+	entry := models.CameraPlanEntry{
+		ID:           deviceIP + "/" + row.CameraName,
+		CameraName:   row.CameraName,
+		DesiredURL:   cameraURL,
+		DesiredIndex: cameraIndex,
+	}
+	if task != nil {
+		entry.DesiredTypes = TaskTypes(row, algorithms, task.Types, true)
+	} else {
+		entry.DesiredTypes = TaskTypes(row, algorithms, nil, false)
+	}
+	if !ok {
+		entry.Change = models.CameraChangeInvalid
+		entry.Message = "摄像头信息格式错误"
+		return entry
+	}
+	if task == nil {
+		entry.Change = models.CameraChangeAdd
+		entry.IndexChanged = true
+		return entry
+	}
+
+	entry.TaskExists = true
+	entry.CurrentURL = task.URL
+	entry.CurrentTypes = task.Types
+	entry.URLChanged = task.URL != cameraURL
+	entry.TypesChanged = !equalTypes(task.Types, entry.DesiredTypes)
+	entry.CurrentIndex, entry.IndexChanged = currentIndex(config, cameraIndex)
+
+	switch {
+	case entry.URLChanged || entry.TypesChanged:
+		entry.Change = models.CameraChangeUpdate
+	case entry.IndexChanged:
+		entry.Change = models.CameraChangeIndex
+	default:
+		entry.Change = models.CameraChangeUnchanged
+	}
+	return entry
+}
+
+// currentIndex 返回算法配置中的camera_index，以及是否有算法需要修改为目标索引
+func currentIndex(config *models.CameraConfig, desired int) (string, bool) {
+	if config == nil {
+		return "", true
+	}
+	current, changed := "", false
+	for _, algorithm := range config.Algorithms {
+		if current == "" {
+			current = algorithm.ExtraConfig.CameraIndex
+		}
+		if algorithm.ExtraConfig.CameraIndex != fmt.Sprintf("%d", desired) {
+			changed = true
+		}
+	}
+	return current, changed
+}
+
+// equalTypes 比较两个算法类型列表，顺序无关
+func equalTypes(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := make(map[int]int)
+	for _, t := range a {
+		counts[t]++
+	}
+	for _, t := range b {
+		counts[t]--
+		if counts[t] < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ApplyCameraPlan 执行计划中已批准的变更。执行前重新读取任务列表，生成计划后被修改过的任务不会执行。
+// 批准了删除任务时必须设置confirmDelete，否则不执行并保留计划。计划只能执行一次
+func (c *Config) ApplyCameraPlan(planID string, approvedIDs []string, confirmDelete bool, getTokenFunc func(string, string, string) (string, error), username, password string) ([]models.CameraConfigResult, error) {
+	approved := make(map[string]bool, len(approvedIDs))
+	for _, id := range approvedIDs {
+		approved[id] = true
+	}
+
+	c.planMutex.Lock()
+	plan, ok := c.plans[planID]
+	if ok && !confirmDelete && countDeletes(plan, approved) > 0 {
+		c.planMutex.Unlock()
+		return nil, fmt.Errorf("计划中有 %d 个任务将被删除，请确认后再执行", countDeletes(plan, approved))
+	}
+	delete(c.plans, planID)
+	c.planMutex.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("计划不存在或已执行，请重新生成计划")
+	}
+
+	var mutex sync.Mutex
+	var results []models.CameraConfigResult
+	slots := make(chan struct{}, planConcurrency)
+	var wg sync.WaitGroup
+	for _, devicePlan := range plan.Devices {
+		var entries []models.CameraPlanEntry
+		for _, entry := range devicePlan.Entries {
+			switch entry.Change {
+			case models.CameraChangeAdd, models.CameraChangeUpdate, models.CameraChangeIndex, models.CameraChangeDelete:
+				if approved[entry.ID] {
+					entries = append(entries, entry)
+				}
+			}
+		}
+		if len(entries) == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func(deviceIP string, entries []models.CameraPlanEntry) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			deviceResults := c.applyDevicePlan(deviceIP, entries, plan, getTokenFunc, username, password)
+
+			mutex.Lock()
+			results = append(results, deviceResults...)
+			mutex.Unlock()
+		}(devicePlan.DeviceIP, entries)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// countDeletes 统计已批准的删除
+func countDeletes(plan models.CameraPlan, approved map[string]bool) int {
+	count := 0
+	for _, devicePlan := range plan.Devices {
+		for _, entry := range devicePlan.Entries {
+			if entry.Change == models.CameraChangeDelete && approved[entry.ID] {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// applyDevicePlan 在一台设备上执行已批准的变更
+func (c *Config) applyDevicePlan(deviceIP string, entries []models.CameraPlanEntry, plan models.CameraPlan, getTokenFunc func(string, string, string) (string, error), username, password string) []models.CameraConfigResult {
+	results := make([]models.CameraConfigResult, 0, len(entries))
+	result := func(entry models.CameraPlanEntry, success bool, message string) {
+		results = append(results, models.CameraConfigResult{DeviceIP: deviceIP, CameraName: entry.CameraName, Success: success, Message: message})
+	}
+	failAll := func(message string) []models.CameraConfigResult {
+		for _, entry := range entries {
+			result(entry, false, message)
+		}
+		return results
+	}
+
+	// 维护模式中的设备不参与批量配置
+	if devices := c.devices(); devices != nil {
+		if reason, ok := devices.MaintenanceReasons([]string{deviceIP})[deviceIP]; ok {
+			return failAll(device.MaintenanceMessage(reason))
+		}
+	}
+	release, err := lock.Default().TryAcquire(deviceIP, lock.OperationCameraConfig)
+	if err != nil {
+		return failAll(err.Error())
+	}
+	defer release()
+
+	token, err := getTokenFunc(deviceIP, username, password)
+	if err != nil {
+		return failAll(fmt.Sprintf("登录设备失败: %v", err))
+	}
+	c.registerDevice(deviceIP, plan.Region, 0)
+
+	cameras, err := NewTasks(c.client).GetCameraTasksWithToken(deviceIP, token)
+	if err != nil {
+		return failAll(fmt.Sprintf("获取摄像头任务列表失败: %v", err))
+	}
+
+	for _, entry := range entries {
+		// 任务在生成计划后被修改过时，按计划执行可能覆盖别人的修改
+		task := findTask(cameras, entry.CameraName)
+		if (task != nil) != entry.TaskExists || (task != nil && (task.URL != entry.CurrentURL || !equalTypes(task.Types, entry.CurrentTypes))) {
+			result(entry, false, "设备上的任务在生成计划后已变化，请重新生成计划")
+			continue
+		}
+
+		if entry.Change == models.CameraChangeDelete {
+			// 保护列表可能在生成计划后被修改
+			if isProtected(c.protectedTasks(), deviceIP, entry.CameraName) {
+				result(entry, false, "任务已加入保护列表，未删除")
+			} else if err := NewTasks(c.client).DeleteCameraTask(deviceIP, token, entry.CameraName); err != nil {
+				result(entry, false, err.Error())
+			} else {
+				result(entry, true, "删除摄像头任务成功")
+			}
+			continue
+		}
+
+		message := ""
+		if entry.Change != models.CameraChangeIndex {
+			success, configureMessage := c.ConfigureCamera(deviceIP, token, entry.CameraName, entry.DesiredURL, entry.DesiredTypes, entry.TaskExists)
+			if !success {
+				result(entry, false, configureMessage)
+				continue
+			}
+			message = configureMessage + ". "
+			// 等待500毫秒，确保摄像头任务已初始化
+			time.Sleep(500 * time.Millisecond)
+		}
+
+		cameraConfig, err := c.GetCameraConfig(deviceIP, token, entry.CameraName)
+		if err != nil {
+			result(entry, false, message+fmt.Sprintf("获取摄像头配置失败: %v", err))
+			continue
+		}
+		success, indexMessage := c.SetCameraIndex(deviceIP, token, entry.CameraName, cameraConfig, entry.DesiredIndex)
+		result(entry, success, message+indexMessage)
+	}
+	return results
+}
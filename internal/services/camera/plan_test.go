@@ -0,0 +1,107 @@
+package camera
+
+import (
+	"net/http"
+	"testing"
+
+	"application-updater/internal/models"
+)
+
+func TestPlanEntry(t *testing.T) {
+	row := models.ExcelRow{DeviceIP: "10.0.0.1", CameraName: "cam1", CameraInfo: "192.168.1.10/admin", DeviceIndex: 2}
+	template := "rtsp://<ip>/stream"
+	config := func(index string) *models.CameraConfig {
+		var cfg models.CameraConfig
+		cfg.Algorithms = make([]models.Algorithm, 1)
+		cfg.Algorithms[0].ExtraConfig.CameraIndex = index
+		return &cfg
+	}
+
+	tests := []struct {
+		name   string
+		row    models.ExcelRow
+		task   *models.Camera
+		config *models.CameraConfig
+		want   string
+	}{
+		{"missing task", row, nil, nil, models.CameraChangeAdd},
+		{"url changed", row, &models.Camera{TaskID: "cam1", URL: "rtsp://192.168.1.11/stream", Types: []int{5}}, config("2"), models.CameraChangeUpdate},
+		{"types changed", row, &models.Camera{TaskID: "cam1", URL: "rtsp://192.168.1.10/stream", Types: []int{3}}, config("2"), models.CameraChangeUpdate},
+		{"index changed", row, &models.Camera{TaskID: "cam1", URL: "rtsp://192.168.1.10/stream", Types: []int{5}}, config("1"), models.CameraChangeIndex},
+		{"config unknown", row, &models.Camera{TaskID: "cam1", URL: "rtsp://192.168.1.10/stream", Types: []int{5}}, nil, models.CameraChangeIndex},
+		{"unchanged", row, &models.Camera{TaskID: "cam1", URL: "rtsp://192.168.1.10/stream", Types: []int{5}}, config("2"), models.CameraChangeUnchanged},
+		{"invalid info", models.ExcelRow{DeviceIP: "10.0.0.1", CameraName: "cam1", CameraInfo: "/x"}, nil, nil, models.CameraChangeInvalid},
+	}
+	for _, tt := range tests {
+		entry := planEntry(tt.row.DeviceIP, tt.row, template, models.CameraAlgorithms{Types: []int{5}}, tt.task, tt.config)
+		if entry.Change != tt.want {
+			t.Errorf("%s: change = %s, want %s", tt.name, entry.Change, tt.want)
+		}
+		if entry.ID != "10.0.0.1/cam1" {
+			t.Errorf("%s: id = %s", tt.name, entry.ID)
+		}
+	}
+}
+
+func TestApplyCameraPlanIsSingleUse(t *testing.T) {
+	config := NewConfig(&http.Client{})
+	config.plans["plan"] = models.CameraPlan{ID: "plan"}
+
+	if _, err := config.ApplyCameraPlan("plan", nil, false, nil, "", ""); err != nil {
+		t.Fatalf("ApplyCameraPlan: %v", err)
+	}
+	if _, err := config.ApplyCameraPlan("plan", nil, false, nil, "", ""); err == nil {
+		t.Errorf("expected a plan to be applied only once")
+	}
+}
+
+func TestOrphanEntries(t *testing.T) {
+	rows := []models.ExcelRow{
+		{DeviceIP: "10.0.0.1", CameraName: "cam1", CameraInfo: "192.168.1.10/24", Selected: true},
+		{DeviceIP: "10.0.0.1", CameraName: "cam2", CameraInfo: "192.168.1.11/24", Selected: false},
+		// 摄像头信息为"/"的行不会配置，但任务仍在Excel中
+		{DeviceIP: "10.0.0.1", CameraName: "cam3", CameraInfo: "/", Selected: true},
+		{DeviceIP: "10.0.0.2", CameraName: "other", CameraInfo: "192.168.1.12/24", Selected: true},
+	}
+	cameras := []models.Camera{{TaskID: "cam1"}, {TaskID: "cam2"}, {TaskID: "cam3"}, {TaskID: "other"}, {TaskID: "old"}, {TaskID: "keep"}, {TaskID: "local"}}
+	protected := []string{"keep", "10.0.0.1/local", "10.0.0.2/old"}
+
+	entries := orphanEntries("10.0.0.1", sheetTasks(rows)["10.0.0.1"], cameras, protected)
+	changes := make(map[string]string)
+	for _, entry := range entries {
+		changes[entry.CameraName] = entry.Change
+	}
+	want := map[string]string{
+		"other": models.CameraChangeDelete,
+		"old":   models.CameraChangeDelete,
+		"keep":  models.CameraChangeProtected,
+		"local": models.CameraChangeProtected,
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("orphans = %v, want %v", changes, want)
+	}
+	for name, change := range want {
+		if changes[name] != change {
+			t.Errorf("%s: change = %s, want %s", name, changes[name], change)
+		}
+	}
+}
+
+func TestApplyCameraPlanRequiresDeleteConfirmation(t *testing.T) {
+	config := NewConfig(&http.Client{})
+	config.plans["plan"] = models.CameraPlan{ID: "plan", Devices: []models.CameraDevicePlan{{
+		DeviceIP: "10.0.0.1",
+		Entries:  []models.CameraPlanEntry{{ID: "10.0.0.1/old", CameraName: "old", Change: models.CameraChangeDelete, TaskExists: true}},
+	}}}
+
+	if _, err := config.ApplyCameraPlan("plan", []string{"10.0.0.1/old"}, false, nil, "", ""); err == nil {
+		t.Fatalf("expected deleting without confirmation to fail")
+	}
+	if _, ok := config.plans["plan"]; !ok {
+		t.Errorf("expected the plan to be kept until deletes are confirmed")
+	}
+	// 不批准删除时不需要确认
+	if _, err := config.ApplyCameraPlan("plan", nil, false, nil, "", ""); err != nil {
+		t.Errorf("ApplyCameraPlan without deletes: %v", err)
+	}
+}
@@ -22,8 +22,8 @@ func NewService(client *http.Client) *Service {
 	}
 }
 
-// SetDeviceManager 设置设备管理器
-func (s *Service) SetDeviceService(service *device.Service) {
+// SetDeviceService 设置获取当前设备服务的函数
+func (s *Service) SetDeviceService(service func() *device.Service) {
 	s.Config.SetDeviceService(service)
 }
 
@@ -38,8 +38,8 @@ func (s *Service) GetCameraTasksWithToken(ip, token string) ([]models.Camera, er
 }
 
 // ConfigureCamera 配置摄像头
-func (s *Service) ConfigureCamera(ip, token, cameraName, cameraURL string, algorithmType int, existingCamera bool) (bool, string) {
-	return s.Config.ConfigureCamera(ip, token, cameraName, cameraURL, algorithmType, existingCamera)
+func (s *Service) ConfigureCamera(ip, token, cameraName, cameraURL string, types []int, existingCamera bool) (bool, string) {
+	return s.Config.ConfigureCamera(ip, token, cameraName, cameraURL, types, existingCamera)
 }
 
 // GetCameraConfig 获取摄像头配置
@@ -53,6 +53,21 @@ func (s *Service) SetCameraIndex(ip, token, taskId string, config *models.Camera
 }
 
 // ConfigureCamerasFromData 批量配置摄像头
-func (s *Service) ConfigureCamerasFromData(deviceConfigs []models.ExcelRow, getTokenFunc func(string, string, string) (string, error), username, password, urlTemplate string, algorithmType int, region string) []models.CameraConfigResult {
-	return s.Config.ConfigureCamerasFromData(deviceConfigs, getTokenFunc, username, password, urlTemplate, algorithmType, region)
+func (s *Service) ConfigureCamerasFromData(deviceConfigs []models.ExcelRow, getTokenFunc func(string, string, string) (string, error), username, password, urlTemplate string, algorithms models.CameraAlgorithms, region string) []models.CameraConfigResult {
+	return s.Config.ConfigureCamerasFromData(deviceConfigs, getTokenFunc, username, password, urlTemplate, algorithms, region)
+}
+
+// PlanCameraConfiguration 生成摄像头配置计划，不修改设备
+func (s *Service) PlanCameraConfiguration(deviceConfigs []models.ExcelRow, getTokenFunc func(string, string, string) (string, error), username, password, urlTemplate string, algorithms models.CameraAlgorithms, region string, reconcile bool) models.CameraPlan {
+	return s.Config.PlanCameraConfiguration(deviceConfigs, getTokenFunc, username, password, urlTemplate, algorithms, region, reconcile)
+}
+
+// ApplyCameraPlan 执行摄像头配置计划中已批准的变更
+func (s *Service) ApplyCameraPlan(planID string, approvedIDs []string, confirmDelete bool, getTokenFunc func(string, string, string) (string, error), username, password string) ([]models.CameraConfigResult, error) {
+	return s.Config.ApplyCameraPlan(planID, approvedIDs, confirmDelete, getTokenFunc, username, password)
+}
+
+// DeleteCameraTask 删除摄像头任务
+func (s *Service) DeleteCameraTask(ip, token, taskId string) error {
+	return s.Tasks.DeleteCameraTask(ip, token, taskId)
 }
@@ -37,18 +37,62 @@ func (t *Tasks) GetCameraTasks(ip, username, password string, getTokenFunc func(
 	return t.GetCameraTasksWithToken(ip, token)
 }
 
-// GetCameraTasksWithToken 使用已有的token获取摄像头任务列表
+// taskPageSize 每次请求任务列表的数量
+const taskPageSize = 100
+
+// GetCameraTasksWithToken 使用已有的token获取摄像头任务列表，按pageCount/total读取所有分页
 func (t *Tasks) GetCameraTasksWithToken(ip, token string) ([]models.Camera, error) {
 	fmt.Printf("DEBUG: 开始使用Token获取摄像头任务列表: IP=%s\n", ip)
 
+	var cameras []models.Camera
+	seen := make(map[string]bool)
+	for pageNo := 1; ; pageNo++ {
+		result, err := t.getTaskPage(ip, token, pageNo)
+		if err != nil {
+			return nil, err
+		}
+
+		// 读取期间任务有增删时分页会移动，按任务ID去重
+		for _, item := range result.Items {
+			if !seen[item.TaskID] {
+				seen[item.TaskID] = true
+				cameras = append(cameras, item)
+			}
+		}
+
+		// 有的固件不返回pageCount，此时根据total和设备实际使用的每页数量判断，设备可能限制每页数量
+		pageCount := result.PageCount
+		if pageCount == 0 && result.Total > 0 {
+			pageSize := result.PageSize
+			if pageSize <= 0 {
+				pageSize = taskPageSize
+			}
+			pageCount = (result.Total + pageSize - 1) / pageSize
+		}
+		if len(result.Items) == 0 || pageNo >= pageCount {
+			break
+		}
+	}
+
+	fmt.Printf("DEBUG: 成功获取到 %d 个摄像头任务\n", len(cameras))
+	// 打印每个任务的ID
+	for i, camera := range cameras {
+		fmt.Printf("DEBUG: 任务 %d: ID=%s, 设备名=%s\n", i+1, camera.TaskID, camera.DeviceName)
+	}
+
+	return cameras, nil
+}
+
+// getTaskPage 读取任务列表的一页
+func (t *Tasks) getTaskPage(ip, token string, pageNo int) (*models.CameraTaskPage, error) {
 	// 获取摄像头任务列表
 	url := fmt.Sprintf("http://%s:8089/api/task/list", ip)
 	fmt.Printf("DEBUG: 请求URL: %s\n", url)
 
 	// 创建请求体
 	requestData := map[string]interface{}{
-		"pageNo":   1,
-		"pageSize": 100,
+		"pageNo":   pageNo,
+		"pageSize": taskPageSize,
 	}
 	requestBody, err := json.Marshal(requestData)
 	if err != nil {
@@ -67,7 +111,6 @@ func (t *Tasks) GetCameraTasksWithToken(ip, token string) ([]models.Camera, erro
 	// 设置header
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Token", token)
-	fmt.Printf("DEBUG: 请求头: Content-Type=%s, Token=%s\n", req.Header.Get("Content-Type"), req.Header.Get("Token"))
 
 	// 发送请求
 	resp, err := t.client.Do(req)
@@ -94,12 +137,9 @@ func (t *Tasks) GetCameraTasksWithToken(ip, token string) ([]models.Camera, erro
 		fmt.Printf("DEBUG: 响应体: %s\n", respBodyStr)
 	}
 
-	// 将读取的响应体转回io.Reader用于解析
-	resp.Body = io.NopCloser(bytes.NewBuffer(respBody))
-
 	// 解析响应
 	var response models.CameraTaskResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+	if err := json.Unmarshal(respBody, &response); err != nil {
 		fmt.Printf("ERROR: 解析响应失败: %v\n", err)
 		return nil, fmt.Errorf("解析响应失败: %w", err)
 	}
@@ -108,23 +148,51 @@ func (t *Tasks) GetCameraTasksWithToken(ip, token string) ([]models.Camera, erro
 		fmt.Printf("ERROR: 获取任务列表失败: 代码=%d, 消息=%s\n", response.Code, response.Msg)
 		return nil, fmt.Errorf("获取任务列表失败: %s", response.Msg)
 	}
+	return &response.Result, nil
+}
+
+// DeleteCameraTask 删除设备上的摄像头任务
+func (t *Tasks) DeleteCameraTask(ip, token, taskId string) error {
+	fmt.Printf("DEBUG: 开始删除摄像头任务: IP=%s, 任务ID=%s\n", ip, taskId)
 
-	// 转换为Camera结构体
-	cameras := make([]models.Camera, 0, len(response.Result.Items))
-	for _, item := range response.Result.Items {
-		cameras = append(cameras, models.Camera{
-			TaskID:     item.TaskID,
-			DeviceName: item.DeviceName,
-			URL:        item.URL,
-			Types:      item.Types,
-		})
+	url := fmt.Sprintf("http://%s:8089/api/task/delete", ip)
+	requestBody, err := json.Marshal(map[string]interface{}{
+		"taskId": taskId,
+	})
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
 	}
 
-	fmt.Printf("DEBUG: 成功获取到 %d 个摄像头任务\n", len(cameras))
-	// 打印每个任务的ID
-	for i, camera := range cameras {
-		fmt.Printf("DEBUG: 任务 %d: ID=%s, 设备名=%s\n", i+1, camera.TaskID, camera.DeviceName)
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return fmt.Errorf("创建HTTP请求失败: %w", err)
 	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Token", token)
 
-	return cameras, nil
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应体失败: %w", err)
+	}
+	fmt.Printf("DEBUG: 响应状态码: %d, 响应体: %s\n", resp.StatusCode, string(respBody))
+
+	var response struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	}
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	if response.Code != 0 {
+		return fmt.Errorf("删除任务失败: %s", response.Msg)
+	}
+
+	fmt.Printf("DEBUG: 成功删除摄像头任务: %s\n", taskId)
+	return nil
 }
@@ -0,0 +1,103 @@
+package camera
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"application-updater/internal/models"
+)
+
+// roundTripFunc 用函数模拟设备的HTTP接口
+type roundTripFunc func(*http.Request) *http.Response
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req), nil
+}
+
+// fakeTaskList 模拟有total个任务的设备任务列表，withPageCount为false时不返回pageCount，
+// maxPageSize大于0时设备每页最多返回maxPageSize个任务
+func fakeTaskList(t *testing.T, total int, withPageCount bool, maxPageSize int, requests *int) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) *http.Response {
+		*requests++
+		var body struct {
+			PageNo   int `json:"pageNo"`
+			PageSize int `json:"pageSize"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if maxPageSize > 0 && body.PageSize > maxPageSize {
+			body.PageSize = maxPageSize
+		}
+
+		var response models.CameraTaskResponse
+		response.Result.Total = total
+		response.Result.PageNo = body.PageNo
+		response.Result.PageSize = body.PageSize
+		if withPageCount {
+			response.Result.PageCount = (total + body.PageSize - 1) / body.PageSize
+		}
+		for i := (body.PageNo - 1) * body.PageSize; i < total && i < body.PageNo*body.PageSize; i++ {
+			response.Result.Items = append(response.Result.Items, models.Camera{
+				TaskID:   fmt.Sprintf("cam%d", i),
+				Status:   1,
+				Width:    1920,
+				Height:   1080,
+				CodeName: "h264",
+			})
+		}
+		data, _ := json.Marshal(response)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(data)), Header: make(http.Header)}
+	})}
+}
+
+func TestGetCameraTasksReadsAllPages(t *testing.T) {
+	for _, withPageCount := range []bool{true, false} {
+		requests := 0
+		tasks := NewTasks(fakeTaskList(t, 250, withPageCount, 0, &requests))
+
+		cameras, err := tasks.GetCameraTasksWithToken("10.0.0.1", "token")
+		if err != nil {
+			t.Fatalf("GetCameraTasksWithToken: %v", err)
+		}
+		if len(cameras) != 250 {
+			t.Errorf("pageCount=%v: got %d tasks, want 250", withPageCount, len(cameras))
+		}
+		if requests != 3 {
+			t.Errorf("pageCount=%v: made %d requests, want 3", withPageCount, requests)
+		}
+		if cameras[249].TaskID != "cam249" || cameras[249].Width != 1920 || cameras[249].CodeName != "h264" {
+			t.Errorf("task fields not kept: %+v", cameras[249])
+		}
+	}
+}
+
+func TestGetCameraTasksFollowsDevicePageSize(t *testing.T) {
+	requests := 0
+	tasks := NewTasks(fakeTaskList(t, 120, false, 50, &requests))
+
+	cameras, err := tasks.GetCameraTasksWithToken("10.0.0.1", "token")
+	if err != nil {
+		t.Fatalf("GetCameraTasksWithToken: %v", err)
+	}
+	if len(cameras) != 120 || requests != 3 {
+		t.Errorf("got %d tasks in %d requests, want 120 in 3", len(cameras), requests)
+	}
+}
+
+func TestGetCameraTasksEmptyDevice(t *testing.T) {
+	requests := 0
+	tasks := NewTasks(fakeTaskList(t, 0, true, 0, &requests))
+
+	cameras, err := tasks.GetCameraTasksWithToken("10.0.0.1", "token")
+	if err != nil {
+		t.Fatalf("GetCameraTasksWithToken: %v", err)
+	}
+	if len(cameras) != 0 || requests != 1 {
+		t.Errorf("got %d tasks in %d requests, want 0 in 1", len(cameras), requests)
+	}
+}
@@ -0,0 +1,425 @@
+package device
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"application-updater/internal/models"
+	"application-updater/internal/services/lock"
+	"application-updater/internal/services/signing"
+	"application-updater/internal/utils"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// 文件先复制到目标路径旁的临时文件，安装时原文件另存一份，失败时恢复
+	bundleStagingSuffix = ".bundle-new"
+	bundleBackupSuffix  = ".bundle-old"
+
+	defaultArtifactMode = "0644"
+)
+
+var artifactModePattern = regexp.MustCompile(`^0?[0-7]{3}$`)
+
+// remoteShell 在设备上执行命令和复制文件
+type remoteShell interface {
+	Run(command string) (string, error)
+	Copy(localPath, remotePath string, limit func(io.Reader) io.Reader) error
+	Close() error
+}
+
+// sshShell 基于SSH连接的remoteShell
+type sshShell struct {
+	client *ssh.Client
+}
+
+func (sh *sshShell) Run(command string) (string, error) {
+	output, err := utils.ExecuteSSHCommand(sh.client, command)
+	if err != nil {
+		return output, fmt.Errorf("执行命令失败: %w, 输出: %s", err, strings.TrimSpace(output))
+	}
+	return output, nil
+}
+
+func (sh *sshShell) Copy(localPath, remotePath string, limit func(io.Reader) io.Reader) error {
+	return utils.SCPFileToRemote(sh.client, localPath, remotePath, limit)
+}
+
+func (sh *sshShell) Close() error {
+	return sh.client.Close()
+}
+
+// dialShell 建立到设备的SSH连接，测试中替换为模拟实现
+var dialShell = func(ip, username, password string) (remoteShell, error) {
+	client, err := utils.CreateSSHClient(ip, username, password, 22)
+	if err != nil {
+		return nil, fmt.Errorf("SSH连接失败: %w", err)
+	}
+	return &sshShell{client: client}, nil
+}
+
+// bundleStep 更新包中的一个安装步骤
+type bundleStep struct {
+	artifact  models.BundleArtifact
+	localPath string
+	md5       string
+}
+
+// planBundle 检查清单并返回按安装顺序排列的步骤，Order相同时保持清单中的顺序
+func planBundle(manifest models.BundleManifest, dir string) ([]bundleStep, error) {
+	if len(manifest.Artifacts) == 0 {
+		return nil, fmt.Errorf("更新包中没有文件或命令")
+	}
+
+	steps := make([]bundleStep, 0, len(manifest.Artifacts))
+	fields := make(map[string]bool)
+	for _, artifact := range manifest.Artifacts {
+		if artifact.Name == "" {
+			artifact.Name = artifact.File + artifact.Command
+		}
+		switch artifact.Delivery {
+		case models.DeliveryHTTP:
+			if artifact.Field == "" || fields[artifact.Field] {
+				return nil, fmt.Errorf("%s: HTTP字段为空或重复", artifact.Name)
+			}
+			fields[artifact.Field] = true
+		case models.DeliverySSH:
+			if !path.IsAbs(artifact.Path) {
+				return nil, fmt.Errorf("%s: 设备上的路径必须是绝对路径", artifact.Name)
+			}
+			if artifact.Mode == "" {
+				artifact.Mode = defaultArtifactMode
+			}
+			if !artifactModePattern.MatchString(artifact.Mode) {
+				return nil, fmt.Errorf("%s: 无效的文件权限 %s", artifact.Name, artifact.Mode)
+			}
+		case models.DeliveryCommand:
+			if strings.TrimSpace(artifact.Command) == "" {
+				return nil, fmt.Errorf("%s: 命令为空", artifact.Name)
+			}
+			steps = append(steps, bundleStep{artifact: artifact})
+			continue
+		default:
+			return nil, fmt.Errorf("%s: 未知的安装方式 %q", artifact.Name, artifact.Delivery)
+		}
+
+		localPath, err := signing.ArtifactPath(dir, artifact.File)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", artifact.Name, err)
+		}
+		md5sum, _, err := utils.FileChecksums(localPath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: 无法读取文件: %w", artifact.Name, err)
+		}
+		steps = append(steps, bundleStep{artifact: artifact, localPath: localPath, md5: md5sum})
+	}
+
+	sort.SliceStable(steps, func(i, j int) bool {
+		return steps[i].artifact.Order < steps[j].artifact.Order
+	})
+	return steps, nil
+}
+
+// bundleNeedsSSH 判断更新包是否包含需要SSH的步骤
+func bundleNeedsSSH(manifest models.BundleManifest, steps []bundleStep) bool {
+	if len(manifest.PostInstall) > 0 {
+		return true
+	}
+	for _, step := range steps {
+		if step.artifact.Delivery != models.DeliveryHTTP {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyBundle 按清单将多个文件和命令安装到设备。每台设备先暂存所有SSH文件，全部成功后才修改设备；
+// 之后任一步骤失败时恢复已安装的文件。命令和HTTP升级无法撤销，应放在清单的最后。
+func (s *Service) ApplyBundle(deviceIds []string, manifest models.BundleManifest, dir, username, password string, options models.UpgradeOptions) ([]models.BundleResult, error) {
+	steps, err := planBundle(manifest, dir)
+	if err != nil {
+		return nil, err
+	}
+	if bundleNeedsSSH(manifest, steps) && options.SSHUsername == "" {
+		return nil, fmt.Errorf("更新包包含通过SSH安装的文件或命令，需要SSH账号")
+	}
+
+	online, offline, err := s.updateTargets(deviceIds)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]models.BundleResult, len(online))
+	slots := s.transfers.NewConcurrency()
+	var wg sync.WaitGroup
+	for i, device := range online {
+		if device.Maintenance {
+			results[i] = skippedBundleResult(device, steps, MaintenanceMessage(device.MaintenanceReason))
+			continue
+		}
+		wg.Add(1)
+		go func(i int, device models.Device) {
+			defer wg.Done()
+			slots.Acquire()
+			results[i] = s.applyBundle(device, manifest, steps, username, password, options)
+			// 安装时间包含命令和验证，不能反映吞吐量，只按失败调整并发数
+			var applyErr error
+			if !results[i].Success {
+				applyErr = fmt.Errorf("%s", results[i].Message)
+			}
+			slots.Release(applyErr, 0, 0, 0)
+		}(i, device)
+	}
+	wg.Wait()
+
+	for _, device := range offline {
+		results = append(results, skippedBundleResult(device, steps, "设备离线，未更新"))
+	}
+	return results, nil
+}
+
+// skippedBundleResult 未参与安装的设备的结果
+func skippedBundleResult(device models.Device, steps []bundleStep, message string) models.BundleResult {
+	return models.BundleResult{IP: device.IP, Skipped: true, Message: message, Artifacts: pendingArtifacts(steps)}
+}
+
+// pendingArtifacts 所有步骤均未执行的结果列表
+func pendingArtifacts(steps []bundleStep) []models.ArtifactResult {
+	artifacts := make([]models.ArtifactResult, len(steps))
+	for i, step := range steps {
+		artifacts[i] = models.ArtifactResult{Name: step.artifact.Name, Delivery: step.artifact.Delivery, Status: models.ArtifactNotApplied}
+	}
+	return artifacts
+}
+
+// applyBundle 在单台设备上安装更新包：暂存、按顺序安装、执行安装后命令并验证版本，任一环节失败时恢复已安装的文件
+func (s *Service) applyBundle(device models.Device, manifest models.BundleManifest, steps []bundleStep, username, password string, options models.UpgradeOptions) models.BundleResult {
+	ip := device.IP
+	result := models.BundleResult{IP: ip, Artifacts: pendingArtifacts(steps)}
+
+	release, err := lock.Default().TryAcquire(ip, lock.OperationUpgrade)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	defer release()
+	result.BuildBefore = s.readBuildTime(ip)
+
+	var shell remoteShell
+	if bundleNeedsSSH(manifest, steps) {
+		if shell, err = dialShell(ip, options.SSHUsername, options.SSHPassword); err != nil {
+			result.Message = err.Error()
+			return result
+		}
+		// 验证失败时可能换用新的连接，关闭最终使用的连接
+		defer func() { shell.Close() }()
+	}
+	limit := func(r io.Reader) io.Reader {
+		return s.transfers.LimitReader(device.Region, r)
+	}
+
+	// 暂存所有SSH文件，失败时设备上只留下被删除的临时文件
+	for i, step := range steps {
+		if step.artifact.Delivery != models.DeliverySSH {
+			continue
+		}
+		if err := stageArtifact(shell, step, limit); err != nil {
+			result.Artifacts[i].Status = models.ArtifactFailed
+			result.Artifacts[i].Message = err.Error()
+			discardStaged(shell, steps)
+			result.Message = fmt.Sprintf("暂存 %s 失败，设备未做任何修改: %v", step.artifact.Name, err)
+			return result
+		}
+	}
+
+	// 按顺序安装，所有HTTP字段在第一个HTTP步骤的位置一起上传
+	installed := make([]bool, len(steps))
+	uploaded := false
+	for i, step := range steps {
+		var err error
+		switch step.artifact.Delivery {
+		case models.DeliverySSH:
+			_, err = shell.Run(installArtifactCommand(step.artifact))
+		case models.DeliveryCommand:
+			_, err = shell.Run(step.artifact.Command)
+		case models.DeliveryHTTP:
+			if !uploaded {
+				err = s.uploadBundleFields(device, steps, username, password, options)
+				uploaded = true
+			}
+		}
+		if err != nil {
+			result.Artifacts[i].Status = models.ArtifactFailed
+			result.Artifacts[i].Message = err.Error()
+			result.Message = fmt.Sprintf("安装 %s 失败: %v；%s", step.artifact.Name, err, rollbackBundle(shell, steps, installed, &result))
+			return result
+		}
+		installed[i] = true
+		result.Artifacts[i].Status = models.ArtifactApplied
+	}
+
+	for _, command := range manifest.PostInstall {
+		step := models.ArtifactResult{Name: command, Delivery: models.DeliveryCommand, Status: models.ArtifactApplied}
+		if _, err := shell.Run(command); err != nil {
+			step.Status = models.ArtifactFailed
+			step.Message = err.Error()
+			result.PostInstall = append(result.PostInstall, step)
+			result.Message = fmt.Sprintf("安装后命令失败: %v；%s", err, rollbackBundle(shell, steps, installed, &result))
+			return result
+		}
+		result.PostInstall = append(result.PostInstall, step)
+	}
+
+	result.Success = true
+	result.Message = fmt.Sprintf("已安装 %d 个步骤", len(steps))
+	if uploaded && !options.SkipVerify {
+		s.verifyBundle(&result, manifest, options)
+		if !result.Success {
+			// HTTP升级后设备会重启，原来的SSH连接可能已断开
+			if shell != nil {
+				if fresh, err := dialShell(ip, options.SSHUsername, options.SSHPassword); err == nil {
+					shell.Close()
+					shell = fresh
+				}
+			}
+			result.Message += "；" + rollbackBundle(shell, steps, installed, &result)
+			return result
+		}
+	}
+
+	// 安装并验证成功，删除原文件的备份
+	for _, step := range steps {
+		if step.artifact.Delivery == models.DeliverySSH {
+			shell.Run("rm -f " + utils.EscapeShellArg(step.artifact.Path+bundleBackupSuffix))
+		}
+	}
+	return result
+}
+
+// stageArtifact 将文件复制到目标路径旁的临时文件并校验MD5
+func stageArtifact(shell remoteShell, step bundleStep, limit func(io.Reader) io.Reader) error {
+	target := step.artifact.Path
+	staging := target + bundleStagingSuffix
+	prepare := fmt.Sprintf("mkdir -p %s && rm -f %s %s", utils.EscapeShellArg(path.Dir(target)),
+		utils.EscapeShellArg(staging), utils.EscapeShellArg(target+bundleBackupSuffix))
+	if _, err := shell.Run(prepare); err != nil {
+		return err
+	}
+	if err := shell.Copy(step.localPath, staging, limit); err != nil {
+		return fmt.Errorf("复制文件失败: %w", err)
+	}
+	output, err := shell.Run("md5sum " + utils.EscapeShellArg(staging))
+	if err != nil {
+		return err
+	}
+	if fields := strings.Fields(output); len(fields) == 0 || !strings.EqualFold(fields[0], step.md5) {
+		return fmt.Errorf("设备上的文件MD5与本地不一致")
+	}
+	return nil
+}
+
+// installArtifactCommand 生成安装一个文件的命令：保存原文件后将临时文件原子替换到目标路径。
+// 任一命令失败时目标文件保持不变
+func installArtifactCommand(artifact models.BundleArtifact) string {
+	target := utils.EscapeShellArg(artifact.Path)
+	staging := utils.EscapeShellArg(artifact.Path + bundleStagingSuffix)
+	backup := utils.EscapeShellArg(artifact.Path + bundleBackupSuffix)
+	return fmt.Sprintf("set -e; if [ -e %[1]s ]; then cp -a %[1]s %[3]s; fi; chmod %[4]s %[2]s; mv -f %[2]s %[1]s",
+		target, staging, backup, artifact.Mode)
+}
+
+// restoreArtifactCommand 生成恢复一个已安装文件的命令，安装前不存在的文件被删除
+func restoreArtifactCommand(artifact models.BundleArtifact) string {
+	target := utils.EscapeShellArg(artifact.Path)
+	backup := utils.EscapeShellArg(artifact.Path + bundleBackupSuffix)
+	return fmt.Sprintf("if [ -e %[2]s ]; then mv -f %[2]s %[1]s; else rm -f %[1]s; fi", target, backup)
+}
+
+// discardStaged 删除尚未安装的临时文件
+func discardStaged(shell remoteShell, steps []bundleStep) {
+	for _, step := range steps {
+		if step.artifact.Delivery == models.DeliverySSH {
+			staging := utils.EscapeShellArg(step.artifact.Path + bundleStagingSuffix)
+			if _, err := shell.Run("rm -f " + staging); err != nil {
+				fmt.Printf("删除临时文件 %s 失败: %v\n", step.artifact.Path+bundleStagingSuffix, err)
+			}
+		}
+	}
+}
+
+// rollbackBundle 按相反顺序恢复已安装的文件并删除其余临时文件，返回回滚情况的说明
+func rollbackBundle(shell remoteShell, steps []bundleStep, installed []bool, result *models.BundleResult) string {
+	restored, irreversible := 0, 0
+	var failures []string
+	for i := len(steps) - 1; i >= 0; i-- {
+		if !installed[i] {
+			continue
+		}
+		if steps[i].artifact.Delivery != models.DeliverySSH {
+			irreversible++
+			result.Artifacts[i].Message = "已执行，无法回滚"
+			continue
+		}
+		if _, err := shell.Run(restoreArtifactCommand(steps[i].artifact)); err != nil {
+			failures = append(failures, steps[i].artifact.Name)
+			result.Artifacts[i].Message = "恢复失败: " + err.Error()
+			continue
+		}
+		restored++
+		result.Artifacts[i].Status = models.ArtifactRolledBack
+	}
+	if shell != nil {
+		discardStaged(shell, steps)
+	}
+
+	message := fmt.Sprintf("已恢复 %d 个文件", restored)
+	if irreversible > 0 {
+		message += fmt.Sprintf("，%d 个命令或HTTP升级无法回滚", irreversible)
+	}
+	if len(failures) > 0 {
+		message += "，恢复失败: " + strings.Join(failures, ", ")
+	}
+	return message
+}
+
+// uploadBundleFields 登录设备并将所有HTTP步骤的文件作为表单字段一次上传
+func (s *Service) uploadBundleFields(device models.Device, steps []bundleStep, username, password string, options models.UpgradeOptions) error {
+	var parts []uploadPart
+	for _, step := range steps {
+		if step.artifact.Delivery == models.DeliveryHTTP {
+			parts = append(parts, uploadPart{field: step.artifact.Field, fileName: filepath.Base(step.localPath), path: step.localPath})
+		}
+	}
+
+	var token string
+	_, err := retryStep("登录", device.IP, withDefaults(options.LoginRetry, defaultLoginRetry), func() error {
+		var loginErr error
+		token, loginErr = s.Auth.LoginToDevice(device.IP, username, password)
+		return loginErr
+	})
+	if err != nil {
+		return fmt.Errorf("登录设备失败: %w", err)
+	}
+	_, err = retryStep("上传", device.IP, withDefaults(options.UploadRetry, defaultUploadRetry), func() error {
+		return s.uploadParts(device, token, append([]uploadPart{}, parts...))
+	})
+	return err
+}
+
+// verifyBundle 包含HTTP升级时等待设备重启并确认运行的版本
+func (s *Service) verifyBundle(result *models.BundleResult, manifest models.BundleManifest, options models.UpgradeOptions) {
+	options.ExpectedBuild = manifest.TargetBuild
+	verified := models.UpdateResult{IP: result.IP, BuildBefore: result.BuildBefore}
+	s.verifyWithRetry(&verified, withDefaults(options.VerifyRetry, defaultVerifyRetry), options)
+	result.Outcome = verified.Outcome
+	result.BuildAfter = verified.BuildAfter
+	result.Success = verified.Success
+	result.Message += "；" + verified.Message
+}
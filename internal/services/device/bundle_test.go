@@ -0,0 +1,216 @@
+package device
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"application-updater/internal/models"
+	"application-updater/internal/utils"
+)
+
+// fakeShell 记录执行的命令，md5sum返回复制到该路径的本地文件的MD5
+type fakeShell struct {
+	commands []string
+	copied   map[string]string
+	failOn   string
+}
+
+func (f *fakeShell) Run(command string) (string, error) {
+	f.commands = append(f.commands, command)
+	if f.failOn != "" && strings.Contains(command, f.failOn) {
+		return "", fmt.Errorf("exit status 1")
+	}
+	if remote, ok := strings.CutPrefix(command, "md5sum "); ok {
+		remote = strings.Trim(remote, "'")
+		md5sum, _, err := utils.FileChecksums(f.copied[remote])
+		return md5sum + "  " + remote + "\n", err
+	}
+	return "", nil
+}
+
+func (f *fakeShell) Copy(localPath, remotePath string, limit func(io.Reader) io.Reader) error {
+	f.commands = append(f.commands, "copy "+remotePath)
+	f.copied[remotePath] = localPath
+	return nil
+}
+
+func (f *fakeShell) Close() error { return nil }
+
+func bundleFixture(t *testing.T) (*Service, *fakeShell, models.BundleManifest, string) {
+	t.Helper()
+	service := NewServiceWithRepository(t.TempDir(), NewMemoryRepository())
+	if err := service.repo.Upsert(models.Device{ID: "d1", IP: "127.0.0.1", Status: "online"}); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "app.yaml"), []byte("config"), 0644)
+	os.WriteFile(filepath.Join(dir, "model.bin"), []byte("model"), 0644)
+	manifest := models.BundleManifest{
+		Name: "release",
+		Artifacts: []models.BundleArtifact{
+			{Name: "model", Delivery: models.DeliverySSH, Order: 2, File: "model.bin", Path: "/opt/models/model.bin"},
+			{Name: "config", Delivery: models.DeliverySSH, Order: 1, File: "app.yaml", Path: "/etc/app.yaml"},
+			{Name: "migrate", Delivery: models.DeliveryCommand, Order: 3, Command: "/opt/migrate.sh"},
+		},
+		PostInstall: []string{"systemctl restart application-web"},
+	}
+
+	shell := &fakeShell{copied: make(map[string]string)}
+	original := dialShell
+	dialShell = func(ip, username, password string) (remoteShell, error) { return shell, nil }
+	t.Cleanup(func() { dialShell = original })
+	return service, shell, manifest, dir
+}
+
+func TestPlanBundleValidates(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "app"), []byte("binary"), 0644)
+
+	for name, artifact := range map[string]models.BundleArtifact{
+		"relative path": {Delivery: models.DeliverySSH, File: "app", Path: "opt/app"},
+		"bad mode":      {Delivery: models.DeliverySSH, File: "app", Path: "/opt/app", Mode: "rwx"},
+		"escaping file": {Delivery: models.DeliveryHTTP, File: "../app", Field: "binary"},
+		"missing field": {Delivery: models.DeliveryHTTP, File: "app"},
+		"empty command": {Delivery: models.DeliveryCommand},
+		"unknown":       {Delivery: "ftp", File: "app"},
+	} {
+		if _, err := planBundle(models.BundleManifest{Artifacts: []models.BundleArtifact{artifact}}, dir); err == nil {
+			t.Errorf("%s: expected the manifest to be rejected", name)
+		}
+	}
+}
+
+func TestApplyBundleInstallsInOrder(t *testing.T) {
+	service, shell, manifest, dir := bundleFixture(t)
+
+	results, err := service.ApplyBundle(nil, manifest, dir, "admin", "admin", models.UpgradeOptions{SSHUsername: "root"})
+	if err != nil {
+		t.Fatalf("ApplyBundle failed: %v", err)
+	}
+	if len(results) != 1 || !results[0].Success {
+		t.Fatalf("Expected the bundle to be applied, got %+v", results)
+	}
+	names := []string{}
+	for _, artifact := range results[0].Artifacts {
+		if artifact.Status != models.ArtifactApplied {
+			t.Errorf("Expected %s to be applied, got %+v", artifact.Name, artifact)
+		}
+		names = append(names, artifact.Name)
+	}
+	if strings.Join(names, ",") != "config,model,migrate" {
+		t.Errorf("Expected artifacts in install order, got %v", names)
+	}
+
+	// 所有文件暂存完成后才开始安装
+	log := strings.Join(shell.commands, "\n")
+	lastCopy := strings.LastIndex(log, "copy ")
+	firstInstall := strings.Index(log, "mv -f '/etc/app.yaml.bundle-new'")
+	if lastCopy < 0 || firstInstall < lastCopy {
+		t.Errorf("Expected all files to be staged before installing, got:\n%s", log)
+	}
+	if !strings.Contains(log, "rm -f '/etc/app.yaml.bundle-old'") {
+		t.Errorf("Expected backups to be removed after success, got:\n%s", log)
+	}
+}
+
+func TestApplyBundleRollsBackOnFailure(t *testing.T) {
+	service, shell, manifest, dir := bundleFixture(t)
+	shell.failOn = "/opt/migrate.sh"
+
+	results, err := service.ApplyBundle(nil, manifest, dir, "admin", "admin", models.UpgradeOptions{SSHUsername: "root"})
+	if err != nil {
+		t.Fatalf("ApplyBundle failed: %v", err)
+	}
+	result := results[0]
+	if result.Success || len(result.PostInstall) != 0 {
+		t.Fatalf("Expected the failed command to stop the bundle, got %+v", result)
+	}
+	want := []string{models.ArtifactRolledBack, models.ArtifactRolledBack, models.ArtifactFailed}
+	for i, artifact := range result.Artifacts {
+		if artifact.Status != want[i] {
+			t.Errorf("Expected %s to be %s, got %+v", artifact.Name, want[i], artifact)
+		}
+	}
+
+	// 按相反顺序恢复
+	log := strings.Join(shell.commands, "\n")
+	model := strings.Index(log, "mv -f '/opt/models/model.bin.bundle-old' '/opt/models/model.bin'")
+	config := strings.Index(log, "mv -f '/etc/app.yaml.bundle-old' '/etc/app.yaml'")
+	if model < 0 || config < model {
+		t.Errorf("Expected files to be restored in reverse order, got:\n%s", log)
+	}
+}
+
+func TestApplyBundleStagingFailureChangesNothing(t *testing.T) {
+	service, shell, manifest, dir := bundleFixture(t)
+	shell.failOn = "md5sum '/opt/models/model.bin.bundle-new'"
+
+	results, err := service.ApplyBundle(nil, manifest, dir, "admin", "admin", models.UpgradeOptions{SSHUsername: "root"})
+	if err != nil {
+		t.Fatalf("ApplyBundle failed: %v", err)
+	}
+	if results[0].Success || results[0].Artifacts[1].Status != models.ArtifactFailed || results[0].Artifacts[0].Status != models.ArtifactNotApplied {
+		t.Fatalf("Expected staging failure to leave artifacts unapplied, got %+v", results[0])
+	}
+	for _, command := range shell.commands {
+		if strings.Contains(command, "mv -f") || strings.Contains(command, "/opt/migrate.sh") {
+			t.Errorf("Expected nothing to be installed after a staging failure, got %q", command)
+		}
+	}
+}
+
+// deviceTransport 模拟设备的登录和升级接口
+type deviceTransport struct{}
+
+func (deviceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := `{"status":0}`
+	if strings.HasSuffix(req.URL.Path, "/api/login") {
+		body = `{"code":0,"result":{"token":"token"}}`
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+}
+
+func TestApplyBundleRollsBackWhenVerificationFails(t *testing.T) {
+	service, shell, manifest, dir := bundleFixture(t)
+	defaultRestartWait, verifyPollInterval = 0, time.Millisecond
+	original := http.DefaultTransport
+	http.DefaultTransport = deviceTransport{}
+	defer func() {
+		defaultRestartWait, verifyPollInterval = 10*time.Second, 5*time.Second
+		http.DefaultTransport = original
+	}()
+
+	// 升级后设备仍运行旧版本
+	service.Scanner = &scriptedScanner{builds: []string{"old", "old", "old", "old"}}
+	os.WriteFile(filepath.Join(dir, "app.bin"), []byte("binary"), 0644)
+	manifest.TargetBuild = "new"
+	manifest.Artifacts = append(manifest.Artifacts, models.BundleArtifact{Name: "binary", Delivery: models.DeliveryHTTP, Order: 4, File: "app.bin", Field: "binary"})
+
+	options := models.UpgradeOptions{SSHUsername: "root", VerifyTimeoutSeconds: 1, VerifyRetry: models.RetryPolicy{MaxAttempts: 1}}
+	results, err := service.ApplyBundle(nil, manifest, dir, "admin", "admin", options)
+	if err != nil {
+		t.Fatalf("ApplyBundle failed: %v", err)
+	}
+	result := results[0]
+	if result.Success || result.Outcome != models.OutcomeUnchanged {
+		t.Fatalf("Expected the bundle to fail verification, got %+v", result)
+	}
+	if result.Artifacts[0].Status != models.ArtifactRolledBack || result.Artifacts[1].Status != models.ArtifactRolledBack {
+		t.Errorf("Expected the SSH files to be restored, got %+v", result.Artifacts)
+	}
+
+	log := strings.Join(shell.commands, "\n")
+	if strings.Contains(log, "rm -f '/etc/app.yaml.bundle-old'") {
+		t.Errorf("Expected backups to be kept until verification passes, got:\n%s", log)
+	}
+	if !strings.Contains(log, "mv -f '/etc/app.yaml.bundle-old' '/etc/app.yaml'") {
+		t.Errorf("Expected the backup to be restored, got:\n%s", log)
+	}
+}
@@ -0,0 +1,120 @@
+package device
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"application-updater/internal/models"
+	"application-updater/internal/utils"
+)
+
+// md5Pattern 匹配32位十六进制MD5值
+var md5Pattern = regexp.MustCompile(`^[0-9a-fA-F]{32}$`)
+
+// checkedUpdate 校验通过的更新文件
+type checkedUpdate struct {
+	md5FileName string
+	md5FilePath string
+	md5         string
+	sha256      string
+	size        int64
+	cleanup     func()
+}
+
+// parseMD5File 从MD5文件内容中取出校验值，支持纯MD5和md5sum输出格式("<md5>  <文件名>")
+func parseMD5File(content []byte) (string, error) {
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 || !md5Pattern.MatchString(fields[0]) {
+		return "", fmt.Errorf("MD5文件格式不正确")
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// checkUpdateFile 计算更新文件的MD5和SHA-256，与提供的MD5文件及预期SHA-256比对。
+// 未提供MD5文件时按md5sum格式生成一个临时MD5文件，调用方需在上传完成后调用cleanup。
+func checkUpdateFile(fileName, filePath, md5FileName, md5FilePath string, options models.UpgradeOptions) (checkedUpdate, error) {
+	checked := checkedUpdate{md5FileName: md5FileName, md5FilePath: md5FilePath, cleanup: func() {}}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return checked, fmt.Errorf("无法读取更新文件: %w", err)
+	}
+	if info.Size() == 0 {
+		return checked, fmt.Errorf("更新文件为空")
+	}
+	checked.size = info.Size()
+
+	checked.md5, checked.sha256, err = utils.FileChecksums(filePath)
+	if err != nil {
+		return checked, fmt.Errorf("计算更新文件校验值失败: %w", err)
+	}
+	fmt.Printf("更新文件 %s: %d 字节, MD5 %s, SHA-256 %s\n", fileName, info.Size(), checked.md5, checked.sha256)
+
+	if options.ExpectedSHA256 != "" && !strings.EqualFold(options.ExpectedSHA256, checked.sha256) {
+		return checked, fmt.Errorf("更新文件SHA-256不一致(预期 %s，实际 %s)，文件可能已损坏", options.ExpectedSHA256, checked.sha256)
+	}
+
+	if md5FilePath != "" {
+		content, err := os.ReadFile(md5FilePath)
+		if err != nil {
+			return checked, fmt.Errorf("无法读取MD5文件: %w", err)
+		}
+		expected, err := parseMD5File(content)
+		if err != nil {
+			return checked, err
+		}
+		if expected != checked.md5 {
+			return checked, fmt.Errorf("更新文件MD5与MD5文件不一致(预期 %s，实际 %s)，文件可能不完整", expected, checked.md5)
+		}
+		return checked, nil
+	}
+
+	// 未提供MD5文件时生成设备需要的MD5文件
+	dir, err := os.MkdirTemp("", "md5-*")
+	if err != nil {
+		return checked, fmt.Errorf("创建临时MD5文件失败: %w", err)
+	}
+	checked.md5FileName = fileName + ".md5"
+	checked.md5FilePath = filepath.Join(dir, checked.md5FileName)
+	checked.cleanup = func() { os.RemoveAll(dir) }
+
+	content := fmt.Sprintf("%s  %s\n", checked.md5, fileName)
+	if err := os.WriteFile(checked.md5FilePath, []byte(content), 0644); err != nil {
+		checked.cleanup()
+		return checked, fmt.Errorf("写入临时MD5文件失败: %w", err)
+	}
+	return checked, nil
+}
+
+// verifyRemoteChecksum 通过SSH在设备上执行md5sum，确认上传的文件完整。
+// 未配置SSH账号或远程路径时不做校验；无法校验时返回说明，MD5不一致时返回错误。
+func verifyRemoteChecksum(result *models.UpdateResult, md5 string, options models.UpgradeOptions) (string, error) {
+	if options.SSHUsername == "" || options.RemoteFilePath == "" {
+		return "", nil
+	}
+
+	client, err := utils.CreateSSHClient(result.IP, options.SSHUsername, options.SSHPassword, 22)
+	if err != nil {
+		return "未能通过SSH校验设备上的文件: " + err.Error(), nil
+	}
+	defer client.Close()
+
+	output, err := utils.ExecuteSSHCommand(client, "md5sum "+utils.EscapeShellArg(options.RemoteFilePath))
+	if err != nil {
+		return "未能在设备上执行md5sum: " + err.Error(), nil
+	}
+
+	remote, err := parseMD5File([]byte(output))
+	if err != nil {
+		return "md5sum输出无法解析", nil
+	}
+	if remote != md5 {
+		return "", fmt.Errorf("设备上的文件MD5不一致(预期 %s，实际 %s)，上传可能不完整", md5, remote)
+	}
+
+	result.ChecksumVerified = true
+	return "", nil
+}
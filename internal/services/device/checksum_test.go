@@ -0,0 +1,38 @@
+package device
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"application-updater/internal/models"
+)
+
+func TestCheckUpdateFile(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "app.bin")
+	os.WriteFile(filePath, []byte("binary"), 0644)
+
+	// 未提供MD5文件时生成md5sum格式的文件
+	checked, err := checkUpdateFile("app.bin", filePath, "", "", models.UpgradeOptions{})
+	if err != nil {
+		t.Fatalf("checkUpdateFile failed: %v", err)
+	}
+	defer checked.cleanup()
+	content, _ := os.ReadFile(checked.md5FilePath)
+	if checked.md5FileName != "app.bin.md5" || string(content) != "9d7183f16acce70658f686ae7f1a4d20  app.bin\n" {
+		t.Errorf("Unexpected generated MD5 file %s: %q", checked.md5FileName, content)
+	}
+
+	// 提供的MD5与文件不符时拒绝上传
+	md5Path := filepath.Join(dir, "app.md5")
+	os.WriteFile(md5Path, []byte(strings.Repeat("0", 32)), 0644)
+	if _, err := checkUpdateFile("app.bin", filePath, "app.md5", md5Path, models.UpgradeOptions{}); err == nil {
+		t.Errorf("Expected mismatching MD5 file to be rejected")
+	}
+
+	if _, err := checkUpdateFile("app.bin", filePath, "", "", models.UpgradeOptions{ExpectedSHA256: "abc"}); err == nil {
+		t.Errorf("Expected mismatching SHA-256 to be rejected")
+	}
+}
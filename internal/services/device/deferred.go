@@ -0,0 +1,296 @@
+package device
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"application-updater/internal/models"
+)
+
+// defaultDeferExpiryHours 等待离线设备上线的默认期限
+const defaultDeferExpiryHours = 24
+
+// deferredPollInterval 检查等待上线设备的间隔，测试中缩短
+var deferredPollInterval = time.Minute
+
+// trashPurgeInterval 清除超过保留期的回收站设备的间隔
+var trashPurgeInterval = time.Hour
+
+// offlineResult 离线设备未参与更新时的结果
+func offlineResult(device models.Device) models.UpdateResult {
+	return models.UpdateResult{IP: device.IP, Skipped: true, Message: "设备离线，未更新"}
+}
+
+// mergeJobResult 用设备最新的结果替换任务中的旧结果，调用方需持有jobMutex
+func mergeJobResult(job *models.UpdateJob, result models.UpdateResult) {
+	for i := range job.Results {
+		if job.Results[i].IP == result.IP {
+			job.Results[i] = result
+			return
+		}
+	}
+	job.Results = append(job.Results, result)
+}
+
+// deferOfflineLocked 将离线设备加入任务：开启DeferOffline时等待设备上线，否则记为跳过。调用方需持有jobMutex
+func (s *Service) deferOfflineLocked(job *models.UpdateJob, offline []models.Device) {
+	if len(offline) == 0 {
+		return
+	}
+	for _, device := range offline {
+		if !containsString(job.DeviceIDs, device.ID) {
+			job.DeviceIDs = append(job.DeviceIDs, device.ID)
+		}
+	}
+
+	if !job.Options.DeferOffline {
+		for _, device := range offline {
+			mergeJobResult(job, offlineResult(device))
+		}
+		return
+	}
+
+	hours := job.Options.DeferExpiryHours
+	if hours <= 0 {
+		hours = defaultDeferExpiryHours
+	}
+	// 时间格式按字典序即按时间排序，重复加入时只延长期限
+	until := time.Now().Add(time.Duration(hours) * time.Hour).Format(lastSeenLayout)
+	if until > job.DeferredUntil {
+		job.DeferredUntil = until
+	}
+	for _, device := range offline {
+		if !containsString(job.Deferred, device.ID) {
+			job.Deferred = append(job.Deferred, device.ID)
+		}
+	}
+	s.openJobs[job.ID] = job
+	fmt.Printf("更新任务 %s: %d 台离线设备等待上线，截止 %s\n", job.ID, len(job.Deferred), job.DeferredUntil)
+}
+
+// onDeviceChange 设备上线时继续等待该设备的更新任务。刷新设备状态、测试设备等所有更新设备状态的操作都会触发
+func (s *Service) onDeviceChange(change DeviceChange) {
+	device := change.Device
+	if change.Type == ChangeRemoved || device.Status != "online" || device.Maintenance {
+		return
+	}
+
+	s.jobMutex.Lock()
+	if len(s.openJobs) == 0 {
+		s.jobMutex.Unlock()
+		return
+	}
+	s.expireDeferredLocked(time.Now())
+
+	var jobs []*models.UpdateJob
+	for _, job := range s.openJobs {
+		if containsString(job.Deferred, device.ID) {
+			job.Deferred = removeString(job.Deferred, device.ID)
+			job.Pending = append(job.Pending, device.ID)
+			s.saveJob(job)
+			jobs = append(jobs, job)
+		}
+	}
+	if len(jobs) > 0 {
+		s.deferredRuns++
+	}
+	s.jobMutex.Unlock()
+
+	if len(jobs) > 0 {
+		sort.Slice(jobs, func(i, j int) bool {
+			return jobs[i].CreatedAt < jobs[j].CreatedAt
+		})
+		go s.runDeferred(jobs, device)
+	}
+}
+
+// runDeferred 依次执行设备上线后等待它的更新任务，同一设备不能同时进行两次更新
+func (s *Service) runDeferred(jobs []*models.UpdateJob, device models.Device) {
+	defer func() {
+		s.jobMutex.Lock()
+		s.deferredRuns--
+		s.jobMutex.Unlock()
+	}()
+
+	for _, job := range jobs {
+		fmt.Printf("设备 %s 已上线，继续更新任务 %s\n", device.IP, job.ID)
+		if _, err := s.runJob(job, []models.Device{device}); err != nil {
+			fmt.Printf("更新任务 %s 更新设备 %s 失败: %v\n", job.ID, device.IP, err)
+		}
+	}
+}
+
+// watchDeferred 定期测试等待上线的设备并清理超时的设备，同时清除超过保留期的回收站设备，直到服务关闭。
+// 没有其他操作刷新设备状态时，等待的任务也能在设备上线后继续
+func (s *Service) watchDeferred(pollInterval, purgeInterval time.Duration) {
+	defer close(s.watchDone)
+
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+	purge := time.NewTicker(purgeInterval)
+	defer purge.Stop()
+	for {
+		select {
+		case <-s.stopWatch:
+			return
+		case <-poll.C:
+			s.pollDeferred()
+		case <-purge.C:
+			if _, err := s.PurgeExpiredDevices(); err != nil {
+				fmt.Printf("清除过期的回收站设备失败: %v\n", err)
+			}
+		}
+	}
+}
+
+// pollDeferred 测试等待上线的设备，在线设备写回状态后由onDeviceChange继续执行
+func (s *Service) pollDeferred() {
+	s.jobMutex.Lock()
+	s.expireDeferredLocked(time.Now())
+	waiting := []string{}
+	for _, job := range s.openJobs {
+		for _, deviceID := range job.Deferred {
+			if !containsString(waiting, deviceID) {
+				waiting = append(waiting, deviceID)
+			}
+		}
+	}
+	s.jobMutex.Unlock()
+
+	for _, deviceID := range waiting {
+		select {
+		case <-s.stopWatch:
+			return
+		default:
+		}
+
+		device, err := s.repo.Get(deviceID)
+		if err != nil || device.Maintenance || device.DeletedAt != "" {
+			continue
+		}
+		tested, err := s.Scanner.TestDevice(device.IP)
+		if err != nil {
+			continue
+		}
+		device.Status = tested.Status
+		device.BuildTime = tested.BuildTime
+		if err := s.repo.Upsert(device); err != nil {
+			fmt.Printf("更新设备 %s 状态失败: %v\n", device.IP, err)
+		}
+	}
+}
+
+// expireDeferredLocked 将超过等待期限仍未上线的设备记为跳过，调用方需持有jobMutex
+func (s *Service) expireDeferredLocked(now time.Time) {
+	for id, job := range s.openJobs {
+		if len(job.Deferred) == 0 {
+			continue
+		}
+		until, err := time.ParseInLocation(lastSeenLayout, job.DeferredUntil, time.Local)
+		if err == nil && now.Before(until) {
+			continue
+		}
+
+		for _, deviceID := range job.Deferred {
+			result := models.UpdateResult{IP: deviceID, Skipped: true, Message: fmt.Sprintf("设备在 %s 前未上线，已跳过", job.DeferredUntil)}
+			if device, err := s.repo.Get(deviceID); err == nil {
+				result.IP = device.IP
+			}
+			mergeJobResult(job, result)
+		}
+		fmt.Printf("更新任务 %s: %d 台设备等待上线超时，已跳过\n", id, len(job.Deferred))
+		job.Deferred = nil
+
+		if s.jobRuns[id] == 0 {
+			job.Status = models.UpdateJobCompleted
+			if len(job.Pending) > 0 {
+				job.Status = models.UpdateJobInterrupted
+			}
+			delete(s.openJobs, id)
+		}
+		s.saveJob(job)
+	}
+}
+
+// JobResults 返回任务的更新结果，等待上线的设备以Deferred结果列出
+func (s *Service) JobResults(job models.UpdateJob) []models.UpdateResult {
+	results := append([]models.UpdateResult{}, job.Results...)
+	for _, deviceID := range job.Deferred {
+		result := models.UpdateResult{IP: deviceID, Deferred: true, Message: fmt.Sprintf("设备离线，将在 %s 前上线后自动更新", job.DeferredUntil)}
+		if device, err := s.repo.Get(deviceID); err == nil {
+			result.IP = device.IP
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// GetUpdateJobReport 按已完成、失败、版本不符、跳过和待执行列出任务中的设备
+func (s *Service) GetUpdateJobReport(id string) (models.UpdateJobReport, error) {
+	s.jobMutex.Lock()
+	s.expireDeferredLocked(time.Now())
+	current, err := s.jobLocked(id)
+	if err != nil {
+		s.jobMutex.Unlock()
+		return models.UpdateJobReport{}, err
+	}
+	job := copyJob(current)
+	s.jobMutex.Unlock()
+
+	report := models.UpdateJobReport{
+		JobID:      job.ID,
+		Status:     job.Status,
+		Completed:  []models.UpdateJobDevice{},
+		Failed:     []models.UpdateJobDevice{},
+		WrongBuild: []models.UpdateJobDevice{},
+		Skipped:    []models.UpdateJobDevice{},
+		Pending:    []models.UpdateJobDevice{},
+	}
+	results := make(map[string]models.UpdateResult, len(job.Results))
+	for _, result := range job.Results {
+		results[result.IP] = result
+	}
+
+	for _, deviceID := range job.DeviceIDs {
+		entry := models.UpdateJobDevice{DeviceID: deviceID, IP: deviceID}
+		if device, err := s.repo.Get(deviceID); err == nil {
+			entry.IP, entry.Name = device.IP, device.Name
+		}
+
+		result, ok := results[entry.IP]
+		switch {
+		case containsString(job.Deferred, deviceID):
+			entry.Deferred = true
+			entry.Message = "等待设备上线，截止 " + job.DeferredUntil
+			report.Pending = append(report.Pending, entry)
+		case containsString(job.Pending, deviceID) || !ok:
+			entry.Message = "尚未执行"
+			report.Pending = append(report.Pending, entry)
+		case result.Success:
+			entry.Message = result.Message
+			report.Completed = append(report.Completed, entry)
+		case result.Skipped:
+			entry.Message = result.Message
+			report.Skipped = append(report.Skipped, entry)
+		case result.Outcome == models.OutcomeUnexpectedBuild:
+			entry.Message = result.Message
+			report.WrongBuild = append(report.WrongBuild, entry)
+		default:
+			entry.Message = result.Message
+			report.Failed = append(report.Failed, entry)
+		}
+	}
+	return report, nil
+}
+
+// copyJob 复制任务，返回给调用方后不受后续执行的影响，副本不包含设备密码
+func copyJob(job *models.UpdateJob) models.UpdateJob {
+	copied := *job
+	clearJobCredentials(&copied)
+	copied.DeviceIDs = append([]string{}, job.DeviceIDs...)
+	copied.Pending = append([]string{}, job.Pending...)
+	copied.Deferred = append([]string(nil), job.Deferred...)
+	copied.Results = append([]models.UpdateResult{}, job.Results...)
+	return copied
+}
@@ -0,0 +1,154 @@
+package device
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"application-updater/internal/models"
+)
+
+func TestOfflineDevicesAreDeferred(t *testing.T) {
+	retryUnit = time.Millisecond
+	defer func() { retryUnit = time.Second }()
+
+	service := NewServiceWithRepository(t.TempDir(), NewMemoryRepository())
+	for _, device := range []models.Device{
+		{ID: "d1", IP: "127.0.0.1", Status: "offline"},
+		{ID: "d2", IP: "127.0.0.2", Status: "offline"},
+	} {
+		if err := service.repo.Upsert(device); err != nil {
+			t.Fatal(err)
+		}
+	}
+	filePath := filepath.Join(t.TempDir(), "application-web")
+	if err := os.WriteFile(filePath, []byte("firmware"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	options := models.UpgradeOptions{DeferOffline: true, SkipVerify: true, LoginRetry: models.RetryPolicy{MaxAttempts: 1}}
+	job, err := service.StartUpdateJob([]string{"d1", "d2"}, "", "application-web", filePath, "", "", "admin", "admin", options)
+	if err != nil {
+		t.Fatalf("StartUpdateJob failed: %v", err)
+	}
+	if job.Status != models.UpdateJobWaiting || len(job.Deferred) != 2 {
+		t.Fatalf("Expected both offline devices to wait, got %+v", job)
+	}
+
+	// 设备上线后自动执行，登录失败也会得到结果
+	service.repo.UpdateStatus("d1", "online")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		report, err := service.GetUpdateJobReport(job.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Failed) == 1 && report.Status == models.UpdateJobWaiting {
+			if report.Failed[0].DeviceID != "d1" || len(report.Pending) != 1 || !report.Pending[0].Deferred {
+				t.Fatalf("Expected d1 to fail and d2 to keep waiting, got %+v", report)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the deferred update to run when d1 came online, got %+v", report)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 超过期限仍未上线的设备记为跳过
+	service.jobMutex.Lock()
+	service.openJobs[job.ID].DeferredUntil = time.Now().Add(-time.Minute).Format(lastSeenLayout)
+	service.jobMutex.Unlock()
+
+	report, err := service.GetUpdateJobReport(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Status != models.UpdateJobCompleted || len(report.Skipped) != 1 || report.Skipped[0].DeviceID != "d2" || len(report.Pending) != 0 {
+		t.Errorf("Expected d2 to be skipped after the expiry, got %+v", report)
+	}
+}
+
+// reachableScanner 只有列出的IP可达
+type reachableScanner struct {
+	online map[string]bool
+}
+
+func (s *reachableScanner) ScanIPRange(ctx context.Context, startIP, endIP string) []models.Device {
+	return nil
+}
+
+func (s *reachableScanner) TestDevice(ip string) (*models.Device, error) {
+	if !s.online[ip] {
+		return nil, fmt.Errorf("unreachable")
+	}
+	return &models.Device{IP: ip, BuildTime: "2024-01-01 00:00:00", Status: "online"}, nil
+}
+
+func TestDeferredDevicesArePolled(t *testing.T) {
+	retryUnit, deferredPollInterval = time.Millisecond, 10*time.Millisecond
+	defer func() { retryUnit, deferredPollInterval = time.Second, time.Minute }()
+
+	service := NewServiceWithRepository(t.TempDir(), NewMemoryRepository())
+	defer service.Close()
+	service.Scanner = &reachableScanner{online: map[string]bool{"127.0.0.1": true}}
+	for _, device := range []models.Device{
+		{ID: "d1", IP: "127.0.0.1", Status: "offline"},
+		{ID: "d2", IP: "127.0.0.2", Status: "offline"},
+	} {
+		if err := service.repo.Upsert(device); err != nil {
+			t.Fatal(err)
+		}
+	}
+	filePath := filepath.Join(t.TempDir(), "application-web")
+	if err := os.WriteFile(filePath, []byte("firmware"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	options := models.UpgradeOptions{DeferOffline: true, SkipVerify: true, LoginRetry: models.RetryPolicy{MaxAttempts: 1}}
+	job, err := service.StartUpdateJob([]string{"d1", "d2"}, "", "application-web", filePath, "", "", "admin", "admin", options)
+	if err != nil {
+		t.Fatalf("StartUpdateJob failed: %v", err)
+	}
+
+	// 没有刷新设备状态，轮询发现d1上线后继续执行
+	waitForJob := func(done func(job *models.UpdateJob) bool) *models.UpdateJob {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			service.jobMutex.Lock()
+			current, err := service.loadJob(job.ID)
+			service.jobMutex.Unlock()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if done(current) {
+				return current
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for the job, got %+v", current)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	current := waitForJob(func(job *models.UpdateJob) bool {
+		return len(job.Results) == 1 && job.Status == models.UpdateJobWaiting
+	})
+	if current.Results[0].IP != "127.0.0.1" || len(current.Deferred) != 1 || current.Deferred[0] != "d2" {
+		t.Fatalf("Expected d1 to run and d2 to keep waiting, got %+v", current)
+	}
+
+	// 轮询同样清理超过期限的设备
+	service.jobMutex.Lock()
+	service.openJobs[job.ID].DeferredUntil = time.Now().Add(-time.Minute).Format(lastSeenLayout)
+	service.jobMutex.Unlock()
+
+	current = waitForJob(func(job *models.UpdateJob) bool {
+		return job.Status == models.UpdateJobCompleted
+	})
+	if len(current.Deferred) != 0 || len(current.Results) != 2 || !current.Results[1].Skipped {
+		t.Errorf("Expected d2 to be skipped after the expiry, got %+v", current)
+	}
+}
@@ -0,0 +1,421 @@
+package device
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"application-updater/internal/models"
+	"application-updater/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// jobsDirName 工作区中保存更新任务的目录名
+const jobsDirName = "update_jobs"
+
+// jobsDir 更新任务的保存目录
+func (s *Service) jobsDir() string {
+	return filepath.Join(s.configDir, jobsDirName)
+}
+
+// jobPath 更新任务文件路径
+func (s *Service) jobPath(id string) string {
+	return filepath.Join(s.jobsDir(), id+".json")
+}
+
+// saveJob 保存更新任务，调用方需持有jobMutex。不再需要执行的任务不保留设备密码
+func (s *Service) saveJob(job *models.UpdateJob) {
+	if !s.jobNeedsCredentialsLocked(job) {
+		clearJobCredentials(job)
+	}
+	job.UpdatedAt = time.Now().Format(lastSeenLayout)
+	if err := utils.SaveConfig(s.jobPath(job.ID), job); err != nil {
+		fmt.Printf("保存更新任务 %s 失败: %v\n", job.ID, err)
+	}
+}
+
+// jobNeedsCredentialsLocked 任务是否还会自动执行：执行中，或仍在等待设备上线(包括刚上线、即将执行的设备)。
+// 中断的任务由用户继续，继续时重新输入密码。调用方需持有jobMutex
+func (s *Service) jobNeedsCredentialsLocked(job *models.UpdateJob) bool {
+	_, open := s.openJobs[job.ID]
+	return job.Status == models.UpdateJobRunning || open || len(job.Deferred) > 0
+}
+
+// clearJobCredentials 清除任务保存的设备密码
+func clearJobCredentials(job *models.UpdateJob) {
+	job.Password = ""
+	job.Options.SSHPassword = ""
+}
+
+// loadJob 读取更新任务，调用方需持有jobMutex
+func (s *Service) loadJob(id string) (*models.UpdateJob, error) {
+	var job models.UpdateJob
+	if err := utils.LoadConfig(s.jobPath(id), &job); err != nil {
+		return nil, fmt.Errorf("更新任务 %s 不存在", id)
+	}
+	return &job, nil
+}
+
+// jobLocked 返回执行中或等待中的任务，其他任务从文件读取，调用方需持有jobMutex
+func (s *Service) jobLocked(id string) (*models.UpdateJob, error) {
+	if job, ok := s.openJobs[id]; ok {
+		return job, nil
+	}
+	return s.loadJob(id)
+}
+
+// markInterruptedJobs 启动时将上次运行中的任务标记为中断，由用户决定是否继续；
+// 仍有离线设备等待的任务继续等待设备上线
+func (s *Service) markInterruptedJobs() {
+	s.jobMutex.Lock()
+	defer s.jobMutex.Unlock()
+
+	for _, job := range s.listJobsLocked() {
+		job := job
+		switch {
+		case job.Status == models.UpdateJobRunning:
+			job.Status = models.UpdateJobInterrupted
+			s.saveJob(&job)
+			fmt.Printf("更新任务 %s 被中断，剩余 %d 台设备\n", job.ID, len(job.Pending))
+		case !s.jobNeedsCredentialsLocked(&job) && (job.Password != "" || job.Options.SSHPassword != ""):
+			// 旧版本保存的已结束任务仍带有密码
+			clearJobCredentials(&job)
+			if err := utils.SaveConfig(s.jobPath(job.ID), &job); err != nil {
+				fmt.Printf("保存更新任务 %s 失败: %v\n", job.ID, err)
+			}
+		}
+		if len(job.Deferred) > 0 {
+			s.openJobs[job.ID] = &job
+		}
+	}
+	s.expireDeferredLocked(time.Now())
+}
+
+// listJobsLocked 读取所有更新任务，调用方需持有jobMutex
+func (s *Service) listJobsLocked() []models.UpdateJob {
+	jobs := []models.UpdateJob{}
+	entries, err := os.ReadDir(s.jobsDir())
+	if err != nil {
+		return jobs
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		if job, err := s.loadJob(strings.TrimSuffix(entry.Name(), ".json")); err == nil {
+			jobs = append(jobs, *job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt > jobs[j].CreatedAt
+	})
+	return jobs
+}
+
+// GetUpdateJobs 获取所有更新任务，最新的在前，不包含设备密码
+func (s *Service) GetUpdateJobs() []models.UpdateJob {
+	s.jobMutex.Lock()
+	defer s.jobMutex.Unlock()
+	s.expireDeferredLocked(time.Now())
+
+	jobs := s.listJobsLocked()
+	for i := range jobs {
+		clearJobCredentials(&jobs[i])
+	}
+	return jobs
+}
+
+// HasRunningJobs 是否有更新任务正在执行，包括设备上线后继续执行的任务
+func (s *Service) HasRunningJobs() bool {
+	s.jobMutex.Lock()
+	defer s.jobMutex.Unlock()
+	return len(s.jobRuns) > 0 || s.deferredRuns > 0
+}
+
+// DeleteUpdateJob 删除更新任务记录，等待中的离线设备不再更新
+func (s *Service) DeleteUpdateJob(id string) error {
+	s.jobMutex.Lock()
+	defer s.jobMutex.Unlock()
+
+	job, err := s.jobLocked(id)
+	if err != nil {
+		return err
+	}
+	if job.Status == models.UpdateJobRunning {
+		return fmt.Errorf("更新任务正在执行，无法删除")
+	}
+	delete(s.openJobs, id)
+	return os.Remove(s.jobPath(id))
+}
+
+// StartUpdateJob 创建并执行一个持久化的更新任务，应用中途退出后可以用ResumeUpdateJob继续剩余的设备。
+// 离线设备按options.DeferOffline加入等待队列或记为跳过。
+func (s *Service) StartUpdateJob(deviceIds []string, packageID, fileName, filePath, md5FileName, md5FilePath, username, password string, options models.UpgradeOptions) (models.UpdateJob, error) {
+	online, offline, err := s.updateTargets(deviceIds)
+	if err != nil {
+		return models.UpdateJob{}, err
+	}
+
+	_, sha, err := utils.FileChecksums(filePath)
+	if err != nil {
+		return models.UpdateJob{}, fmt.Errorf("无法读取更新文件: %w", err)
+	}
+
+	job := &models.UpdateJob{
+		ID:          uuid.New().String(),
+		CreatedAt:   time.Now().Format(lastSeenLayout),
+		Status:      models.UpdateJobRunning,
+		PackageID:   packageID,
+		FileName:    fileName,
+		FilePath:    filePath,
+		MD5FileName: md5FileName,
+		MD5FilePath: md5FilePath,
+		SHA256:      sha,
+		Username:    username,
+		Password:    password,
+		Options:     options,
+		Results:     []models.UpdateResult{},
+		Pending:     []string{},
+	}
+	for _, device := range online {
+		job.DeviceIDs = append(job.DeviceIDs, device.ID)
+	}
+	if options.Preflight && len(online) > 0 {
+		online = s.preflightJob(job, online)
+	}
+	for _, device := range online {
+		job.Pending = append(job.Pending, device.ID)
+	}
+
+	// 只有离线设备时也先校验更新文件，避免设备上线后才发现文件有问题
+	if len(online) == 0 && len(offline) > 0 {
+		checked, err := checkUpdateFile(fileName, filePath, md5FileName, md5FilePath, options)
+		if err != nil {
+			return models.UpdateJob{}, err
+		}
+		checked.cleanup()
+	}
+
+	s.jobMutex.Lock()
+	s.deferOfflineLocked(job, offline)
+	s.saveJob(job)
+	s.jobMutex.Unlock()
+
+	if len(online) == 0 {
+		return s.finishJob(job), nil
+	}
+
+	result, err := s.runJob(job, online)
+	if err != nil && len(result.Pending) == len(online) {
+		// 更新文件未通过校验等原因导致任务没有开始，不保留任务记录
+		s.jobMutex.Lock()
+		delete(s.openJobs, job.ID)
+		os.Remove(s.jobPath(job.ID))
+		s.jobMutex.Unlock()
+	}
+	return result, err
+}
+
+// preflightJob 在上传任何文件前检查所有在线设备，记录检查结果，未通过的设备记为跳过，返回可以升级的设备
+func (s *Service) preflightJob(job *models.UpdateJob, online []models.Device) []models.Device {
+	var size int64
+	if info, err := os.Stat(job.FilePath); err == nil {
+		size = info.Size()
+	}
+	job.Preflight = s.preflight(online, size, job.Username, job.Password, job.Options)
+
+	var ready []models.Device
+	for i, device := range online {
+		if job.Preflight[i].Go {
+			ready = append(ready, device)
+			continue
+		}
+		job.Results = append(job.Results, models.UpdateResult{
+			IP:      device.IP,
+			Skipped: true,
+			Message: "升级前检查未通过: " + preflightFailures(job.Preflight[i]),
+		})
+	}
+	return ready
+}
+
+// preflightFailures 汇总未通过的检查项
+func preflightFailures(result models.PreflightResult) string {
+	var failures []string
+	for _, check := range result.Checks {
+		if check.Status == models.CheckFailed {
+			failures = append(failures, check.Message)
+		}
+	}
+	return strings.Join(failures, "; ")
+}
+
+// ResumeUpdateJob 使用重新输入的设备凭据继续更新任务中尚未完成的设备，以及因暂时性故障失败的设备。
+// 用户名为空时沿用任务原来的用户名
+func (s *Service) ResumeUpdateJob(id, username, password, sshUsername, sshPassword string) (models.UpdateJob, error) {
+	s.jobMutex.Lock()
+	job, err := s.jobLocked(id)
+	if err == nil && job.Status == models.UpdateJobRunning {
+		err = fmt.Errorf("更新任务正在执行")
+	}
+	if err != nil {
+		s.jobMutex.Unlock()
+		return models.UpdateJob{}, err
+	}
+
+	// 重新执行的设备：未完成的和可重试的失败设备
+	retry := append([]string{}, job.Pending...)
+	for _, result := range job.Results {
+		if !result.Success && result.Retryable {
+			for _, device := range s.findDevices(models.DeviceFilter{IP: result.IP}) {
+				if containsString(job.DeviceIDs, device.ID) && !containsString(retry, device.ID) {
+					retry = append(retry, device.ID)
+				}
+			}
+		}
+	}
+	if len(retry) == 0 {
+		s.jobMutex.Unlock()
+		return *job, fmt.Errorf("更新任务没有需要继续的设备")
+	}
+
+	if username != "" {
+		job.Username = username
+	}
+	job.Password = password
+	if sshUsername != "" {
+		job.Options.SSHUsername = sshUsername
+	}
+	job.Options.SSHPassword = sshPassword
+	job.Status = models.UpdateJobRunning
+	job.Pending = retry
+	s.saveJob(job)
+	s.jobMutex.Unlock()
+
+	online, offline, err := s.updateTargets(retry)
+	if err != nil {
+		return s.finishJob(job), err
+	}
+
+	// 仍然离线的设备按任务设置加入等待队列，否则留在未完成列表中
+	if job.Options.DeferOffline {
+		s.jobMutex.Lock()
+		for _, device := range offline {
+			job.Pending = removeString(job.Pending, device.ID)
+		}
+		s.deferOfflineLocked(job, offline)
+		s.jobMutex.Unlock()
+	}
+	if len(online) == 0 {
+		if job.Options.DeferOffline {
+			return s.finishJob(job), nil
+		}
+		return s.finishJob(job), fmt.Errorf("需要继续的设备都不在线")
+	}
+	return s.runJob(job, online)
+}
+
+// runJob 执行更新任务中的一批设备，每台设备完成后立即保存结果
+func (s *Service) runJob(job *models.UpdateJob, devices []models.Device) (models.UpdateJob, error) {
+	s.jobMutex.Lock()
+	s.openJobs[job.ID] = job
+	s.jobRuns[job.ID]++
+	job.Status = models.UpdateJobRunning
+	s.saveJob(job)
+	s.jobMutex.Unlock()
+
+	// 文件被修改或删除时拒绝继续，避免设备上出现不同的版本
+	options := job.Options
+	options.ExpectedSHA256 = job.SHA256
+
+	_, err := s.updateDevices(devices, job.FileName, job.FilePath, job.MD5FileName, job.MD5FilePath, job.Username, job.Password, options, func(device models.Device, result models.UpdateResult) {
+		s.jobMutex.Lock()
+		defer s.jobMutex.Unlock()
+
+		job.Pending = removeString(job.Pending, device.ID)
+		mergeJobResult(job, result)
+		s.saveJob(job)
+	})
+
+	s.jobMutex.Lock()
+	s.jobRuns[job.ID]--
+	if s.jobRuns[job.ID] <= 0 {
+		delete(s.jobRuns, job.ID)
+	}
+	s.jobMutex.Unlock()
+	return s.finishJob(job), err
+}
+
+// finishJob 批次结束后保存任务状态并返回任务副本。还有批次在执行时保持执行中；
+// 仍有离线设备等待时为等待中；有未完成的设备(如应用退出)时标记为中断以便稍后继续
+func (s *Service) finishJob(job *models.UpdateJob) models.UpdateJob {
+	s.jobMutex.Lock()
+	defer s.jobMutex.Unlock()
+
+	if s.jobRuns[job.ID] == 0 {
+		switch {
+		case len(job.Pending) > 0:
+			job.Status = models.UpdateJobInterrupted
+		case len(job.Deferred) > 0:
+			job.Status = models.UpdateJobWaiting
+		default:
+			job.Status = models.UpdateJobCompleted
+		}
+		if len(job.Deferred) == 0 {
+			delete(s.openJobs, job.ID)
+		}
+	}
+	s.saveJob(job)
+	return copyJob(job)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(values []string, value string) []string {
+	result := values[:0]
+	for _, v := range values {
+		if v != value {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// PackageReferences 列出dir中未完成的更新任务(执行中、中断或等待设备上线)仍需要的更新文件
+func PackageReferences(dir string) ([]models.PackageReference, error) {
+	jobsDir := filepath.Join(dir, jobsDirName)
+	entries, err := os.ReadDir(jobsDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取更新任务目录失败: %w", err)
+	}
+
+	var refs []models.PackageReference
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		var job models.UpdateJob
+		if err := utils.LoadConfig(filepath.Join(jobsDir, entry.Name()), &job); err != nil {
+			return nil, fmt.Errorf("读取更新任务 %s 失败: %w", entry.Name(), err)
+		}
+		if job.Status == models.UpdateJobCompleted {
+			continue
+		}
+		refs = append(refs, models.PackageReference{PackageID: job.PackageID, FilePath: job.FilePath})
+	}
+	return refs, nil
+}
@@ -0,0 +1,60 @@
+package device
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"application-updater/internal/models"
+)
+
+func TestJobPasswordsAreDroppedWhenFinished(t *testing.T) {
+	service := NewServiceWithRepository(t.TempDir(), NewMemoryRepository())
+	if err := service.repo.Upsert(models.Device{ID: "d1", IP: "127.0.0.1", Status: "offline"}); err != nil {
+		t.Fatal(err)
+	}
+	filePath := filepath.Join(t.TempDir(), "application-web")
+	if err := os.WriteFile(filePath, []byte("firmware"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	options := models.UpgradeOptions{DeferOffline: true, SkipVerify: true, SSHUsername: "root", SSHPassword: "ssh-secret"}
+	job, err := service.StartUpdateJob([]string{"d1"}, "", "application-web", filePath, "", "", "admin", "secret", options)
+	if err != nil {
+		t.Fatalf("StartUpdateJob failed: %v", err)
+	}
+	if job.Password != "" || job.Options.SSHPassword != "" {
+		t.Errorf("Expected the returned job to leave out passwords, got %+v", job)
+	}
+	if listed := service.GetUpdateJobs()[0]; listed.Password != "" || listed.Options.SSHPassword != "" {
+		t.Errorf("Expected GetUpdateJobs to leave out passwords, got %+v", listed)
+	}
+
+	// 等待设备上线的任务需要保留密码
+	service.jobMutex.Lock()
+	stored, err := service.loadJob(job.ID)
+	service.jobMutex.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Password != "secret" || stored.Options.SSHPassword != "ssh-secret" {
+		t.Fatalf("Expected the waiting job to keep its passwords, got %+v", stored)
+	}
+
+	// 设备超过期限未上线，任务结束后不再保存密码
+	service.jobMutex.Lock()
+	service.openJobs[job.ID].DeferredUntil = time.Now().Add(-time.Minute).Format(lastSeenLayout)
+	service.expireDeferredLocked(time.Now())
+	stored, err = service.loadJob(job.ID)
+	service.jobMutex.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.UpdateJobCompleted {
+		t.Fatalf("Expected the job to complete, got %s", stored.Status)
+	}
+	if stored.Password != "" || stored.Options.SSHPassword != "" || stored.Username != "admin" || stored.Options.SSHUsername != "root" {
+		t.Errorf("Expected only the passwords to be removed, got %+v", stored)
+	}
+}
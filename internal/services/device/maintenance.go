@@ -0,0 +1,43 @@
+package device
+
+import (
+	"fmt"
+
+	"application-updater/internal/models"
+)
+
+// SetDevicesMaintenance 批量设置或清除设备的维护模式，维护中的设备不参与批量操作
+func (s *Service) SetDevicesMaintenance(ids []string, enabled bool, reason string) error {
+	if err := s.repo.SetMaintenance(ids, enabled, reason); err != nil {
+		return fmt.Errorf("更新维护模式失败: %w", err)
+	}
+	return nil
+}
+
+// MaintenanceReasons 返回指定IP中处于维护模式的设备及其原因
+func (s *Service) MaintenanceReasons(ips []string) map[string]string {
+	reasons := make(map[string]string)
+	if len(ips) == 0 {
+		return reasons
+	}
+
+	wanted := make(map[string]bool, len(ips))
+	for _, ip := range ips {
+		wanted[ip] = true
+	}
+
+	for _, device := range s.findDevices(models.DeviceFilter{}) {
+		if device.Maintenance && wanted[device.IP] {
+			reasons[device.IP] = device.MaintenanceReason
+		}
+	}
+	return reasons
+}
+
+// MaintenanceMessage 返回批量操作跳过维护中设备时的提示
+func MaintenanceMessage(reason string) string {
+	if reason == "" {
+		return "设备处于维护模式，已跳过"
+	}
+	return fmt.Sprintf("设备处于维护模式(%s)，已跳过", reason)
+}
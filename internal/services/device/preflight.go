@@ -0,0 +1,215 @@
+package device
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"application-updater/internal/models"
+	"application-updater/internal/utils"
+)
+
+const (
+	// preflightConcurrency 同时检查的设备数，检查只读取少量数据，比上传的并发数高
+	preflightConcurrency = 16
+	// preflightSpaceMargin 除更新文件外分区至少还要保留的空间
+	preflightSpaceMargin = 10 * 1024 * 1024
+	// maxClockSkew 设备时钟与本机允许的最大偏差
+	maxClockSkew = 5 * time.Minute
+)
+
+// PreflightDevices 在上传前检查设备能否升级，返回每台设备的go/no-go结果，不修改设备。
+// filePath为空时不检查可用空间是否足够容纳更新文件。
+func (s *Service) PreflightDevices(deviceIds []string, filePath, username, password string, options models.UpgradeOptions) ([]models.PreflightResult, error) {
+	online, offline, err := s.updateTargets(deviceIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var size int64
+	if filePath != "" {
+		info, err := os.Stat(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("无法读取更新文件: %w", err)
+		}
+		size = info.Size()
+	}
+
+	results := s.preflight(online, size, username, password, options)
+	for _, device := range offline {
+		results = append(results, models.PreflightResult{
+			DeviceID: device.ID,
+			IP:       device.IP,
+			Name:     device.Name,
+			Checks: []models.PreflightCheck{
+				{Name: models.CheckLogin, Status: models.CheckFailed, Message: "设备离线"},
+			},
+		})
+	}
+	return results, nil
+}
+
+// preflight 并发检查设备，结果顺序与devices一致
+func (s *Service) preflight(devices []models.Device, size int64, username, password string, options models.UpgradeOptions) []models.PreflightResult {
+	results := make([]models.PreflightResult, len(devices))
+	slots := make(chan struct{}, preflightConcurrency)
+	var wg sync.WaitGroup
+	for i, device := range devices {
+		wg.Add(1)
+		go func(i int, device models.Device) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			results[i] = s.preflightDevice(device, size, username, password, options)
+		}(i, device)
+	}
+	wg.Wait()
+	return results
+}
+
+// preflightDevice 检查一台设备：HTTP登录和buildTime，有SSH账号时再检查可用空间、服务状态和时钟
+func (s *Service) preflightDevice(device models.Device, size int64, username, password string, options models.UpgradeOptions) models.PreflightResult {
+	result := models.PreflightResult{DeviceID: device.ID, IP: device.IP, Name: device.Name}
+	add := func(name, status, message string) {
+		result.Checks = append(result.Checks, models.PreflightCheck{Name: name, Status: status, Message: message})
+	}
+
+	if _, err := s.Auth.LoginToDevice(device.IP, username, password); err != nil {
+		// 通过SSH升级时不需要web服务，登录失败不影响升级
+		status := models.CheckFailed
+		if options.Transport == models.TransportSSH {
+			status = models.CheckWarning
+		}
+		add(models.CheckLogin, status, fmt.Sprintf("登录失败: %v", err))
+	} else {
+		add(models.CheckLogin, models.CheckPassed, "登录成功")
+	}
+
+	result.BuildTime = s.readBuildTime(device.IP)
+	switch {
+	case result.BuildTime == "":
+		add(models.CheckBuild, models.CheckWarning, "无法读取buildTime")
+	case options.MinBuild != "" && compareBuilds(result.BuildTime, options.MinBuild) == models.BuildOlder:
+		add(models.CheckBuild, models.CheckFailed,
+			fmt.Sprintf("当前版本 %s 低于可直接升级的最低版本 %s", result.BuildTime, options.MinBuild))
+	default:
+		add(models.CheckBuild, models.CheckPassed, result.BuildTime)
+	}
+
+	s.preflightSSH(device, size, options, add)
+
+	result.Go = true
+	for _, check := range result.Checks {
+		if check.Status == models.CheckFailed {
+			result.Go = false
+		}
+	}
+	return result
+}
+
+// preflightSSH 通过一个SSH连接检查可用空间、服务状态和时钟，没有SSH账号时记为未检查
+func (s *Service) preflightSSH(device models.Device, size int64, options models.UpgradeOptions, add func(name, status, message string)) {
+	names := []string{models.CheckDisk, models.CheckService, models.CheckClock}
+	skipAll := func(message string) {
+		for _, name := range names {
+			add(name, models.CheckSkipped, message)
+		}
+	}
+
+	if options.SSHUsername == "" {
+		skipAll("未配置SSH账号")
+		return
+	}
+	client, err := utils.CreateSSHClient(device.IP, options.SSHUsername, options.SSHPassword, 22)
+	if err != nil {
+		status := models.CheckFailed
+		if options.Transport != models.TransportSSH {
+			status = models.CheckSkipped
+		}
+		for _, name := range names {
+			add(name, status, fmt.Sprintf("SSH连接失败: %v", err))
+		}
+		return
+	}
+	defer client.Close()
+
+	settings := s.GetSettings()
+
+	// df的输出与语言设置无关，使用-P避免长设备名换行
+	output, err := utils.ExecuteSSHCommand(client, "df -Pk "+utils.EscapeShellArg(path.Dir(settings.BinaryPath)))
+	if available, parseErr := parseDfAvailable(output); err != nil || parseErr != nil {
+		add(models.CheckDisk, models.CheckFailed, fmt.Sprintf("无法读取可用空间: %s", strings.TrimSpace(output)))
+	} else {
+		add(checkDiskSpace(available, requiredSpace(size, options)))
+	}
+
+	// is-active在服务未运行时返回非0，只看输出
+	output, _ = utils.ExecuteSSHCommand(client, "systemctl is-active "+utils.EscapeShellArg(settings.ServiceName))
+	state := strings.TrimSpace(output)
+	switch {
+	case state == "active":
+		add(models.CheckService, models.CheckPassed, settings.ServiceName+" 正在运行")
+	case options.Transport == models.TransportSSH || options.SSHFallback:
+		add(models.CheckService, models.CheckWarning, fmt.Sprintf("%s 状态为 %s，将通过SSH替换程序并重启", settings.ServiceName, state))
+	default:
+		add(models.CheckService, models.CheckFailed, fmt.Sprintf("%s 状态为 %s", settings.ServiceName, state))
+	}
+
+	output, err = utils.ExecuteSSHCommand(client, "date +%s")
+	if deviceTime, parseErr := strconv.ParseInt(strings.TrimSpace(output), 10, 64); err != nil || parseErr != nil {
+		add(models.CheckClock, models.CheckFailed, fmt.Sprintf("无法读取设备时间: %s", strings.TrimSpace(output)))
+	} else {
+		add(checkClock(time.Unix(deviceTime, 0), time.Now()))
+	}
+}
+
+// requiredSpace 升级需要的可用空间：上传的临时文件，开启快照时还有当前程序的副本
+func requiredSpace(size int64, options models.UpgradeOptions) int64 {
+	required := size + preflightSpaceMargin
+	if options.SnapshotBeforeUpload {
+		required += size
+	}
+	return required
+}
+
+// parseDfAvailable 从df -Pk的输出中读取可用空间(字节)
+func parseDfAvailable(output string) (int64, error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) < 2 {
+		return 0, fmt.Errorf("无法解析df输出")
+	}
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < 4 {
+		return 0, fmt.Errorf("无法解析df输出")
+	}
+	kb, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("无法解析df输出: %w", err)
+	}
+	return kb * 1024, nil
+}
+
+// checkDiskSpace 比较可用空间和需要的空间
+func checkDiskSpace(available, required int64) (string, string, string) {
+	message := fmt.Sprintf("可用 %.1f MB，需要 %.1f MB", float64(available)/1048576, float64(required)/1048576)
+	if available < required {
+		return models.CheckDisk, models.CheckFailed, message
+	}
+	return models.CheckDisk, models.CheckPassed, message
+}
+
+// checkClock 比较设备时间和本机时间，偏差过大时需要先同步时间
+func checkClock(deviceTime, now time.Time) (string, string, string) {
+	skew := deviceTime.Sub(now).Round(time.Second)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > maxClockSkew {
+		return models.CheckClock, models.CheckFailed, fmt.Sprintf("设备时间 %s 与本机相差 %v，请先同步时间", deviceTime.Format(lastSeenLayout), skew)
+	}
+	return models.CheckClock, models.CheckPassed, fmt.Sprintf("偏差 %v", skew)
+}
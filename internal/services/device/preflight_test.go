@@ -0,0 +1,77 @@
+package device
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"application-updater/internal/models"
+)
+
+func TestParseDfAvailable(t *testing.T) {
+	output := "Filesystem     1024-blocks    Used Available Capacity Mounted on\n/dev/mmcblk0p2     7312548 6101232    843744      88% /\n"
+	available, err := parseDfAvailable(output)
+	if err != nil || available != 843744*1024 {
+		t.Errorf("Expected 843744 KB, got %d err=%v", available, err)
+	}
+	if _, err := parseDfAvailable("df: /opt: No such file or directory"); err == nil {
+		t.Errorf("Expected an error for unexpected df output")
+	}
+
+	if _, status, _ := checkDiskSpace(available, requiredSpace(900*1024*1024, models.UpgradeOptions{})); status != models.CheckFailed {
+		t.Errorf("Expected a 900 MB update not to fit, got %s", status)
+	}
+	if _, status, _ := checkDiskSpace(available, requiredSpace(300*1024*1024, models.UpgradeOptions{SnapshotBeforeUpload: true})); status != models.CheckPassed {
+		t.Errorf("Expected a 300 MB update with snapshot to fit, got %s", status)
+	}
+}
+
+func TestCheckClock(t *testing.T) {
+	now := time.Now()
+	if _, status, _ := checkClock(now.Add(-30*time.Second), now); status != models.CheckPassed {
+		t.Errorf("Expected a small skew to pass, got %s", status)
+	}
+	if _, status, _ := checkClock(now.Add(-time.Hour), now); status != models.CheckFailed {
+		t.Errorf("Expected a one hour skew to fail, got %s", status)
+	}
+}
+
+func TestPreflightSkipsNoGoDevices(t *testing.T) {
+	service := NewServiceWithRepository(t.TempDir(), NewMemoryRepository())
+	for _, device := range []models.Device{
+		{ID: "d1", IP: "127.0.0.1", Status: "online"},
+		{ID: "d2", IP: "127.0.0.2", Status: "offline"},
+	} {
+		if err := service.repo.Upsert(device); err != nil {
+			t.Fatal(err)
+		}
+	}
+	filePath := filepath.Join(t.TempDir(), "application-web")
+	if err := os.WriteFile(filePath, []byte("firmware"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := service.PreflightDevices([]string{"d1", "d2"}, filePath, "admin", "admin", models.UpgradeOptions{})
+	if err != nil {
+		t.Fatalf("PreflightDevices failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Go || results[1].Go {
+		t.Fatalf("Expected unreachable and offline devices to be no-go, got %+v", results)
+	}
+	for _, check := range results[0].Checks {
+		if check.Name == models.CheckDisk && check.Status != models.CheckSkipped {
+			t.Errorf("Expected SSH checks to be skipped without an SSH account, got %+v", check)
+		}
+	}
+
+	// 未通过检查的设备不会上传，任务直接完成
+	options := models.UpgradeOptions{Preflight: true, SkipVerify: true}
+	job, err := service.StartUpdateJob([]string{"d1"}, "", "application-web", filePath, "", "", "admin", "admin", options)
+	if err != nil {
+		t.Fatalf("StartUpdateJob failed: %v", err)
+	}
+	if job.Status != models.UpdateJobCompleted || len(job.Preflight) != 1 || len(job.Results) != 1 || !job.Results[0].Skipped {
+		t.Errorf("Expected the no-go device to be skipped before upload, got %+v", job)
+	}
+}
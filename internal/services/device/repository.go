@@ -0,0 +1,266 @@
+package device
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"application-updater/internal/models"
+)
+
+// ErrDeviceNotFound 设备不存在
+var ErrDeviceNotFound = errors.New("设备不存在")
+
+// deletedAtLayout 软删除时间格式，与SQLite的CURRENT_TIMESTAMP一致，可按字符串比较
+const deletedAtLayout = "2006-01-02 15:04:05"
+
+// lastSeenLayout 最后在线时间格式(本地时间)，与buildTime格式一致，可按字符串比较
+const lastSeenLayout = "2006-01-02 15:04:05"
+
+// ChangeType 设备变更类型
+type ChangeType string
+
+const (
+	// ChangeAdded 新增设备
+	ChangeAdded ChangeType = "added"
+	// ChangeUpdated 设备信息已更新
+	ChangeUpdated ChangeType = "updated"
+	// ChangeRemoved 设备已删除(移入回收站)
+	ChangeRemoved ChangeType = "removed"
+	// ChangeRestored 设备已从回收站恢复
+	ChangeRestored ChangeType = "restored"
+)
+
+// DeviceChange 设备变更通知
+type DeviceChange struct {
+	Type   ChangeType
+	Device models.Device
+}
+
+// DeviceRepository 设备存储接口，屏蔽具体的存储实现
+type DeviceRepository interface {
+	// Get 根据ID获取设备，不存在时返回ErrDeviceNotFound
+	Get(id string) (models.Device, error)
+	// Find 按过滤条件查询设备
+	Find(filter models.DeviceFilter) ([]models.Device, error)
+	// Count 按过滤条件统计设备数量
+	Count(filter models.DeviceFilter) (int, error)
+	// Query 按过滤条件分页查询设备，返回当前页和总数
+	Query(query models.DeviceQuery) (models.DevicePage, error)
+	// Upsert 新增或更新单个设备，已删除的设备会被恢复
+	Upsert(device models.Device) error
+	// UpsertMany 在同一事务中新增或更新多个设备，已删除的设备会被恢复
+	UpsertMany(devices []models.Device) error
+	// UpdateStatus 更新设备状态
+	UpdateStatus(id, status string) error
+	// SetRegion 在同一事务中设置多个设备的区域
+	SetRegion(ids []string, region string) error
+	// SetMaintenance 在同一事务中设置或清除多个设备的维护模式
+	SetMaintenance(ids []string, enabled bool, reason string) error
+	// Delete 在同一事务中将设备移入回收站
+	Delete(ids []string) error
+	// DeleteAll 将所有设备移入回收站
+	DeleteAll() error
+	// Restore 在同一事务中从回收站恢复设备
+	Restore(ids []string) error
+	// Purge 永久删除回收站中删除时间早于before的设备，返回删除数量
+	Purge(before time.Time) (int, error)
+	// Snapshot 将当前存储内容完整写入指定文件
+	Snapshot(path string) error
+	// Regions 获取所有非空区域
+	Regions() ([]string, error)
+	// Tags 获取所有未删除设备使用的标签
+	Tags() ([]string, error)
+	// Subscribe 订阅设备变更，返回取消订阅函数
+	Subscribe(fn func(DeviceChange)) func()
+	// Close 释放存储资源
+	Close() error
+}
+
+// notifier 管理变更订阅者，供各存储实现复用
+type notifier struct {
+	mutex       sync.Mutex
+	nextID      int
+	subscribers map[int]func(DeviceChange)
+}
+
+func newNotifier() *notifier {
+	return &notifier{
+		subscribers: make(map[int]func(DeviceChange)),
+	}
+}
+
+// subscribe 注册订阅者
+func (n *notifier) subscribe(fn func(DeviceChange)) func() {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	id := n.nextID
+	n.nextID++
+	n.subscribers[id] = fn
+
+	return func() {
+		n.mutex.Lock()
+		defer n.mutex.Unlock()
+		delete(n.subscribers, id)
+	}
+}
+
+// publish 通知所有订阅者，调用方不能持有存储锁
+func (n *notifier) publish(changes ...DeviceChange) {
+	n.mutex.Lock()
+	subscribers := make([]func(DeviceChange), 0, len(n.subscribers))
+	for _, fn := range n.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	n.mutex.Unlock()
+
+	for _, change := range changes {
+		for _, fn := range subscribers {
+			fn(change)
+		}
+	}
+}
+
+// matchesFilter 判断设备是否满足过滤条件
+func matchesFilter(device models.Device, filter models.DeviceFilter) bool {
+	if len(filter.IDs) > 0 {
+		found := false
+		for _, id := range filter.IDs {
+			if id == device.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if filter.IP != "" && device.IP != filter.IP {
+		return false
+	}
+	if filter.Region != "" {
+		if device.Region != filter.Region && !(filter.IncludeNoRegion && device.Region == "") {
+			return false
+		}
+	}
+	if filter.Status != "" && device.Status != filter.Status {
+		return false
+	}
+	if filter.Search != "" {
+		search := strings.ToLower(filter.Search)
+		if !strings.Contains(strings.ToLower(device.IP), search) &&
+			!strings.Contains(strings.ToLower(device.Name), search) &&
+			!strings.Contains(strings.ToLower(device.Notes), search) {
+			return false
+		}
+	}
+	if filter.BuildTimeFrom != "" && device.BuildTime < filter.BuildTimeFrom {
+		return false
+	}
+	if filter.BuildTimeTo != "" && (device.BuildTime == "" || device.BuildTime > filter.BuildTimeTo) {
+		return false
+	}
+	if filter.LastSeenFrom != "" && device.LastSeen < filter.LastSeenFrom {
+		return false
+	}
+	if filter.LastSeenTo != "" && (device.LastSeen == "" || device.LastSeen > filter.LastSeenTo) {
+		return false
+	}
+	for _, tag := range filter.Tags {
+		if !containsTag(device.Tags, tag) {
+			return false
+		}
+	}
+	if filter.ExcludeMaintenance && device.Maintenance {
+		return false
+	}
+	if filter.OnlyDeleted {
+		return device.DeletedAt != ""
+	}
+	if !filter.IncludeDeleted && device.DeletedAt != "" {
+		return false
+	}
+	return true
+}
+
+// containsTag 判断标签列表中是否包含指定标签
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeTags 去除空白、空标签和重复标签
+func normalizeTags(tags []string) []string {
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !containsTag(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+// prepareDevice 在写入存储前整理标签，并为在线设备记录最后在线时间
+func prepareDevice(device models.Device) models.Device {
+	device.Tags = normalizeTags(device.Tags)
+	if !device.Maintenance {
+		device.MaintenanceReason = ""
+	}
+	if device.Status == "online" {
+		device.LastSeen = time.Now().Format(lastSeenLayout)
+	}
+	return device
+}
+
+// deviceSortValue 返回设备在指定排序字段上的值，未知字段返回空字符串
+func deviceSortValue(device models.Device, sortBy string) string {
+	switch sortBy {
+	case models.DeviceSortIP:
+		return device.IP
+	case models.DeviceSortName:
+		return device.Name
+	case models.DeviceSortRegion:
+		return device.Region
+	case models.DeviceSortStatus:
+		return device.Status
+	case models.DeviceSortBuildTime:
+		return device.BuildTime
+	case models.DeviceSortLastSeen:
+		return device.LastSeen
+	}
+	return ""
+}
+
+// sortDevices 按指定字段稳定排序，相同值保持原有顺序
+func sortDevices(devices []models.Device, sortBy string, desc bool) {
+	if sortBy == "" {
+		return
+	}
+	sort.SliceStable(devices, func(i, j int) bool {
+		a, b := deviceSortValue(devices[i], sortBy), deviceSortValue(devices[j], sortBy)
+		if desc {
+			return a > b
+		}
+		return a < b
+	})
+}
+
+// pageBounds 规范化页码并返回当前页的偏移量，pageSize小于等于0表示不分页
+func pageBounds(query models.DeviceQuery) (page, offset int) {
+	page = query.Page
+	if page < 1 {
+		page = 1
+	}
+	if query.PageSize <= 0 {
+		return 1, 0
+	}
+	return page, (page - 1) * query.PageSize
+}
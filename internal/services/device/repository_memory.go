@@ -0,0 +1,337 @@
+package device
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"application-updater/internal/models"
+)
+
+// MemoryRepository 基于内存的设备存储，主要用于测试
+type MemoryRepository struct {
+	mutex    sync.RWMutex
+	order    []string
+	devices  map[string]models.Device
+	notifier *notifier
+}
+
+// NewMemoryRepository 创建内存设备存储
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		devices:  make(map[string]models.Device),
+		notifier: newNotifier(),
+	}
+}
+
+// Get 根据ID获取设备
+func (r *MemoryRepository) Get(id string) (models.Device, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	device, ok := r.devices[id]
+	if !ok {
+		return models.Device{}, ErrDeviceNotFound
+	}
+	return device, nil
+}
+
+// Find 按过滤条件查询设备，按插入顺序返回
+func (r *MemoryRepository) Find(filter models.DeviceFilter) ([]models.Device, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	devices := []models.Device{}
+	for _, id := range r.order {
+		if device := r.devices[id]; matchesFilter(device, filter) {
+			devices = append(devices, device)
+		}
+	}
+	return devices, nil
+}
+
+// Count 按过滤条件统计设备数量
+func (r *MemoryRepository) Count(filter models.DeviceFilter) (int, error) {
+	devices, err := r.Find(filter)
+	return len(devices), err
+}
+
+// Query 按过滤条件分页查询设备
+func (r *MemoryRepository) Query(query models.DeviceQuery) (models.DevicePage, error) {
+	devices, err := r.Find(query.Filter)
+	if err != nil {
+		return models.DevicePage{}, err
+	}
+	sortDevices(devices, query.SortBy, query.SortDesc)
+
+	page, offset := pageBounds(query)
+	result := models.DevicePage{Total: len(devices), Page: page, PageSize: query.PageSize}
+	if query.PageSize <= 0 {
+		result.Devices = devices
+		return result, nil
+	}
+
+	end := offset + query.PageSize
+	if offset > len(devices) {
+		offset = len(devices)
+	}
+	if end > len(devices) {
+		end = len(devices)
+	}
+	result.Devices = devices[offset:end]
+	return result, nil
+}
+
+// Upsert 新增或更新单个设备
+func (r *MemoryRepository) Upsert(device models.Device) error {
+	return r.UpsertMany([]models.Device{device})
+}
+
+// UpsertMany 新增或更新多个设备，任一设备无效时不做任何修改
+func (r *MemoryRepository) UpsertMany(devices []models.Device) error {
+	for _, device := range devices {
+		if device.ID == "" {
+			return fmt.Errorf("设备 %s 缺少ID", device.IP)
+		}
+	}
+
+	r.mutex.Lock()
+	changes := make([]DeviceChange, 0, len(devices))
+	for _, device := range devices {
+		device = prepareDevice(device)
+		changeType := ChangeUpdated
+		if existing, exists := r.devices[device.ID]; !exists {
+			changeType = ChangeAdded
+			r.order = append(r.order, device.ID)
+		} else if existing.DeletedAt != "" {
+			changeType = ChangeRestored
+		}
+		device.DeletedAt = ""
+		r.devices[device.ID] = device
+		changes = append(changes, DeviceChange{Type: changeType, Device: device})
+	}
+	r.mutex.Unlock()
+
+	r.notifier.publish(changes...)
+	return nil
+}
+
+// UpdateStatus 更新设备状态
+func (r *MemoryRepository) UpdateStatus(id, status string) error {
+	r.mutex.Lock()
+	device, ok := r.devices[id]
+	if !ok {
+		r.mutex.Unlock()
+		return ErrDeviceNotFound
+	}
+	device.Status = status
+	if status == "online" {
+		device.LastSeen = time.Now().Format(lastSeenLayout)
+	}
+	r.devices[id] = device
+	r.mutex.Unlock()
+
+	r.notifier.publish(DeviceChange{Type: ChangeUpdated, Device: device})
+	return nil
+}
+
+// SetRegion 设置多个设备的区域，忽略不存在的设备
+func (r *MemoryRepository) SetRegion(ids []string, region string) error {
+	r.mutex.Lock()
+	changes := make([]DeviceChange, 0, len(ids))
+	for _, id := range ids {
+		device, ok := r.devices[id]
+		if !ok {
+			continue
+		}
+		device.Region = region
+		r.devices[id] = device
+		changes = append(changes, DeviceChange{Type: ChangeUpdated, Device: device})
+	}
+	r.mutex.Unlock()
+
+	r.notifier.publish(changes...)
+	return nil
+}
+
+// SetMaintenance 设置或清除多个设备的维护模式，忽略不存在的设备
+func (r *MemoryRepository) SetMaintenance(ids []string, enabled bool, reason string) error {
+	if !enabled {
+		reason = ""
+	}
+
+	r.mutex.Lock()
+	changes := make([]DeviceChange, 0, len(ids))
+	for _, id := range ids {
+		device, ok := r.devices[id]
+		if !ok {
+			continue
+		}
+		device.Maintenance = enabled
+		device.MaintenanceReason = reason
+		r.devices[id] = device
+		changes = append(changes, DeviceChange{Type: ChangeUpdated, Device: device})
+	}
+	r.mutex.Unlock()
+
+	r.notifier.publish(changes...)
+	return nil
+}
+
+// Delete 将设备移入回收站
+func (r *MemoryRepository) Delete(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	deletedAt := time.Now().UTC().Format(deletedAtLayout)
+	r.mutex.Lock()
+	changes := make([]DeviceChange, 0, len(ids))
+	for _, id := range ids {
+		device, ok := r.devices[id]
+		if !ok || device.DeletedAt != "" {
+			continue
+		}
+		device.DeletedAt = deletedAt
+		r.devices[id] = device
+		changes = append(changes, DeviceChange{Type: ChangeRemoved, Device: device})
+	}
+	r.mutex.Unlock()
+
+	if len(changes) == 0 {
+		return ErrDeviceNotFound
+	}
+	r.notifier.publish(changes...)
+	return nil
+}
+
+// DeleteAll 将所有设备移入回收站
+func (r *MemoryRepository) DeleteAll() error {
+	r.mutex.RLock()
+	ids := append([]string{}, r.order...)
+	r.mutex.RUnlock()
+
+	if err := r.Delete(ids); err != nil && err != ErrDeviceNotFound {
+		return err
+	}
+	return nil
+}
+
+// Restore 从回收站恢复设备
+func (r *MemoryRepository) Restore(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	r.mutex.Lock()
+	changes := make([]DeviceChange, 0, len(ids))
+	for _, id := range ids {
+		device, ok := r.devices[id]
+		if !ok {
+			continue
+		}
+		device.DeletedAt = ""
+		r.devices[id] = device
+		changes = append(changes, DeviceChange{Type: ChangeRestored, Device: device})
+	}
+	r.mutex.Unlock()
+
+	if len(changes) == 0 {
+		return ErrDeviceNotFound
+	}
+	r.notifier.publish(changes...)
+	return nil
+}
+
+// Purge 永久删除回收站中删除时间早于before的设备
+func (r *MemoryRepository) Purge(before time.Time) (int, error) {
+	cutoff := before.UTC().Format(deletedAtLayout)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	purged := 0
+	order := r.order[:0]
+	for _, id := range r.order {
+		if deletedAt := r.devices[id].DeletedAt; deletedAt != "" && deletedAt <= cutoff {
+			delete(r.devices, id)
+			purged++
+			continue
+		}
+		order = append(order, id)
+	}
+	r.order = order
+	return purged, nil
+}
+
+// Snapshot 将所有设备(包括回收站)以JSON格式写入指定文件
+func (r *MemoryRepository) Snapshot(path string) error {
+	devices, err := r.Find(models.DeviceFilter{IncludeDeleted: true})
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(devices, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化设备失败: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("写入快照失败: %w", err)
+	}
+	return nil
+}
+
+// Regions 获取所有非空区域
+func (r *MemoryRepository) Regions() ([]string, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	seen := make(map[string]bool)
+	regions := []string{}
+	for _, id := range r.order {
+		device := r.devices[id]
+		region := device.Region
+		if region != "" && device.DeletedAt == "" && !seen[region] {
+			seen[region] = true
+			regions = append(regions, region)
+		}
+	}
+	return regions, nil
+}
+
+// Tags 获取所有未删除设备使用的标签
+func (r *MemoryRepository) Tags() ([]string, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	tags := []string{}
+	for _, id := range r.order {
+		device := r.devices[id]
+		if device.DeletedAt != "" {
+			continue
+		}
+		for _, tag := range device.Tags {
+			if !containsTag(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// Subscribe 订阅设备变更
+func (r *MemoryRepository) Subscribe(fn func(DeviceChange)) func() {
+	return r.notifier.subscribe(fn)
+}
+
+// Close 内存存储无需释放资源
+func (r *MemoryRepository) Close() error {
+	return nil
+}
+
+// Ensure MemoryRepository implements DeviceRepository
+var _ DeviceRepository = (*MemoryRepository)(nil)
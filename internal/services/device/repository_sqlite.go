@@ -0,0 +1,694 @@
+package device
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"application-updater/internal/models"
+
+	_ "github.com/mattn/go-sqlite3" // SQLite驱动
+)
+
+// SQLiteRepository 基于SQLite的设备存储
+type SQLiteRepository struct {
+	db       *sql.DB
+	notifier *notifier
+}
+
+// NewSQLiteRepository 打开或创建指定路径的设备数据库
+func NewSQLiteRepository(dbPath string) (*SQLiteRepository, error) {
+	// 打开数据库连接
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败: %w", err)
+	}
+
+	// SQLite同一时间只允许一个写入者，使用单连接避免database is locked错误
+	db.SetMaxOpenConns(1)
+
+	// 创建设备表
+	createTableSQL := `
+	CREATE TABLE IF NOT EXISTS devices (
+		id TEXT PRIMARY KEY,
+		ip TEXT NOT NULL,
+		build_time TEXT,
+		status TEXT,
+		region TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_devices_ip ON devices(ip);
+	`
+
+	if _, err := db.Exec(createTableSQL); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("创建数据库表失败: %w", err)
+	}
+
+	// 升级旧版本数据库的表结构
+	if err := migrateDevicesTable(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteRepository{
+		db:       db,
+		notifier: newNotifier(),
+	}, nil
+}
+
+// migrateDevicesTable 为旧版本数据库补齐新增的列和索引
+func migrateDevicesTable(db *sql.DB) error {
+	columns := []struct{ name, definition string }{
+		{"deleted_at", "TIMESTAMP"},
+		{"name", "TEXT NOT NULL DEFAULT ''"},
+		{"notes", "TEXT NOT NULL DEFAULT ''"},
+		{"tags", "TEXT NOT NULL DEFAULT '[]'"}, // JSON数组，筛选使用device_tags表
+		{"last_seen", "TEXT"},
+		{"maintenance", "INTEGER NOT NULL DEFAULT 0"},
+		{"maintenance_reason", "TEXT NOT NULL DEFAULT ''"},
+	}
+	for _, column := range columns {
+		if err := ensureColumn(db, "devices", column.name, column.definition); err != nil {
+			return err
+		}
+	}
+
+	// 标签单独建表，便于按标签走索引筛选
+	statements := []string{
+		`CREATE TABLE IF NOT EXISTS device_tags (
+			device_id TEXT NOT NULL,
+			tag TEXT NOT NULL,
+			PRIMARY KEY (device_id, tag)
+		)`,
+		"CREATE INDEX IF NOT EXISTS idx_device_tags_tag ON device_tags(tag)",
+		"CREATE INDEX IF NOT EXISTS idx_devices_deleted_at ON devices(deleted_at)",
+		"CREATE INDEX IF NOT EXISTS idx_devices_region ON devices(region)",
+		"CREATE INDEX IF NOT EXISTS idx_devices_status ON devices(status)",
+		"CREATE INDEX IF NOT EXISTS idx_devices_build_time ON devices(build_time)",
+		"CREATE INDEX IF NOT EXISTS idx_devices_last_seen ON devices(last_seen)",
+		"CREATE INDEX IF NOT EXISTS idx_devices_name ON devices(name)",
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return fmt.Errorf("创建索引失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// ensureColumn 如果表中不存在指定列则添加该列
+func ensureColumn(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("读取表结构失败: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, columnType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			return fmt.Errorf("读取表结构失败: %w", err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	rows.Close()
+
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("添加列 %s 失败: %w", column, err)
+	}
+	fmt.Printf("数据库表 %s 已添加列 %s\n", table, column)
+	return nil
+}
+
+const deviceColumns = "id, ip, build_time, status, region, deleted_at, name, notes, tags, last_seen, maintenance, maintenance_reason"
+
+// sortColumns 排序字段到数据库列的映射
+var sortColumns = map[string]string{
+	models.DeviceSortIP:        "ip",
+	models.DeviceSortName:      "name",
+	models.DeviceSortRegion:    "region",
+	models.DeviceSortStatus:    "status",
+	models.DeviceSortBuildTime: "build_time",
+	models.DeviceSortLastSeen:  "last_seen",
+}
+
+// scanDevice 扫描一行设备记录
+func scanDevice(scanner interface{ Scan(...interface{}) error }) (models.Device, error) {
+	var device models.Device
+	var buildTime, status, region, deletedAt, name, notes, tags, lastSeen, maintenanceReason sql.NullString
+	err := scanner.Scan(&device.ID, &device.IP, &buildTime, &status, &region, &deletedAt, &name, &notes, &tags, &lastSeen,
+		&device.Maintenance, &maintenanceReason)
+	device.BuildTime = buildTime.String
+	device.Status = status.String
+	device.Region = region.String
+	device.DeletedAt = deletedAt.String
+	device.Name = name.String
+	device.Notes = notes.String
+	device.LastSeen = lastSeen.String
+	device.MaintenanceReason = maintenanceReason.String
+	if tags.String != "" {
+		json.Unmarshal([]byte(tags.String), &device.Tags)
+	}
+	return device, err
+}
+
+// escapeLike 转义LIKE模式中的通配符，配合ESCAPE '\'使用
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+// buildWhere 根据过滤条件构造WHERE子句
+func buildWhere(filter models.DeviceFilter) (string, []interface{}) {
+	conditions := []string{}
+	args := []interface{}{}
+
+	if len(filter.IDs) > 0 {
+		placeholders := make([]string, len(filter.IDs))
+		for i, id := range filter.IDs {
+			placeholders[i] = "?"
+			args = append(args, id)
+		}
+		conditions = append(conditions, fmt.Sprintf("id IN (%s)", strings.Join(placeholders, ",")))
+	}
+	if filter.IP != "" {
+		conditions = append(conditions, "ip = ?")
+		args = append(args, filter.IP)
+	}
+	if filter.Region != "" {
+		if filter.IncludeNoRegion {
+			conditions = append(conditions, "(region = ? OR region = '' OR region IS NULL)")
+		} else {
+			conditions = append(conditions, "region = ?")
+		}
+		args = append(args, filter.Region)
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Search != "" {
+		pattern := "%" + escapeLike(filter.Search) + "%"
+		conditions = append(conditions, `(ip LIKE ? ESCAPE '\' OR name LIKE ? ESCAPE '\' OR notes LIKE ? ESCAPE '\')`)
+		args = append(args, pattern, pattern, pattern)
+	}
+	if filter.BuildTimeFrom != "" {
+		conditions = append(conditions, "build_time >= ?")
+		args = append(args, filter.BuildTimeFrom)
+	}
+	if filter.BuildTimeTo != "" {
+		conditions = append(conditions, "build_time <= ? AND build_time != ''")
+		args = append(args, filter.BuildTimeTo)
+	}
+	if filter.LastSeenFrom != "" {
+		conditions = append(conditions, "last_seen >= ?")
+		args = append(args, filter.LastSeenFrom)
+	}
+	if filter.LastSeenTo != "" {
+		conditions = append(conditions, "last_seen <= ?")
+		args = append(args, filter.LastSeenTo)
+	}
+	for _, tag := range filter.Tags {
+		conditions = append(conditions, "id IN (SELECT device_id FROM device_tags WHERE tag = ?)")
+		args = append(args, tag)
+	}
+	if filter.ExcludeMaintenance {
+		conditions = append(conditions, "maintenance = 0")
+	}
+	if filter.OnlyDeleted {
+		conditions = append(conditions, "deleted_at IS NOT NULL")
+	} else if !filter.IncludeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// Get 根据ID获取设备
+func (r *SQLiteRepository) Get(id string) (models.Device, error) {
+	row := r.db.QueryRow("SELECT "+deviceColumns+" FROM devices WHERE id = ?", id)
+	device, err := scanDevice(row)
+	if err == sql.ErrNoRows {
+		return models.Device{}, ErrDeviceNotFound
+	}
+	if err != nil {
+		return models.Device{}, fmt.Errorf("查询设备失败: %w", err)
+	}
+	return device, nil
+}
+
+// Find 按过滤条件查询设备
+func (r *SQLiteRepository) Find(filter models.DeviceFilter) ([]models.Device, error) {
+	where, args := buildWhere(filter)
+	rows, err := r.db.Query("SELECT "+deviceColumns+" FROM devices"+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询设备失败: %w", err)
+	}
+	defer rows.Close()
+
+	devices := []models.Device{}
+	for rows.Next() {
+		device, err := scanDevice(rows)
+		if err != nil {
+			fmt.Printf("扫描设备记录失败: %v\n", err)
+			continue
+		}
+		devices = append(devices, device)
+	}
+	return devices, rows.Err()
+}
+
+// Count 按过滤条件统计设备数量
+func (r *SQLiteRepository) Count(filter models.DeviceFilter) (int, error) {
+	where, args := buildWhere(filter)
+	var count int
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM devices"+where, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("查询设备数量失败: %w", err)
+	}
+	return count, nil
+}
+
+// Query 按过滤条件分页查询设备
+func (r *SQLiteRepository) Query(query models.DeviceQuery) (models.DevicePage, error) {
+	total, err := r.Count(query.Filter)
+	if err != nil {
+		return models.DevicePage{}, err
+	}
+
+	// 未指定或未知的排序字段按添加顺序返回
+	orderBy := " ORDER BY rowid"
+	if column, ok := sortColumns[query.SortBy]; ok {
+		direction := "ASC"
+		if query.SortDesc {
+			direction = "DESC"
+		}
+		orderBy = fmt.Sprintf(" ORDER BY %s %s, rowid", column, direction)
+	}
+
+	where, args := buildWhere(query.Filter)
+	page, offset := pageBounds(query)
+	limit := ""
+	if query.PageSize > 0 {
+		limit = " LIMIT ? OFFSET ?"
+		args = append(args, query.PageSize, offset)
+	}
+
+	rows, err := r.db.Query("SELECT "+deviceColumns+" FROM devices"+where+orderBy+limit, args...)
+	if err != nil {
+		return models.DevicePage{}, fmt.Errorf("查询设备失败: %w", err)
+	}
+	defer rows.Close()
+
+	result := models.DevicePage{Devices: []models.Device{}, Total: total, Page: page, PageSize: query.PageSize}
+	for rows.Next() {
+		device, err := scanDevice(rows)
+		if err != nil {
+			fmt.Printf("扫描设备记录失败: %v\n", err)
+			continue
+		}
+		result.Devices = append(result.Devices, device)
+	}
+	return result, rows.Err()
+}
+
+// upsertTx 在事务中新增或更新设备，返回变更类型
+func upsertTx(tx *sql.Tx, device models.Device) (ChangeType, error) {
+	tags, err := json.Marshal(device.Tags)
+	if err != nil {
+		return "", fmt.Errorf("序列化标签失败: %w", err)
+	}
+
+	var deletedAt sql.NullString
+	err = tx.QueryRow("SELECT deleted_at FROM devices WHERE id = ?", device.ID).Scan(&deletedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("查询设备失败: %w", err)
+	}
+
+	changeType := ChangeAdded
+	if err == nil {
+		_, err = tx.Exec(
+			`UPDATE devices SET ip = ?, build_time = ?, status = ?, region = ?, name = ?, notes = ?, tags = ?, last_seen = NULLIF(?, ''),
+			maintenance = ?, maintenance_reason = ?, deleted_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+			device.IP, device.BuildTime, device.Status, device.Region, device.Name, device.Notes, string(tags), device.LastSeen,
+			device.Maintenance, device.MaintenanceReason, device.ID)
+		if err != nil {
+			return "", fmt.Errorf("更新设备失败: %w", err)
+		}
+		changeType = ChangeUpdated
+		if deletedAt.Valid {
+			changeType = ChangeRestored
+		}
+	} else {
+		_, err = tx.Exec(
+			`INSERT INTO devices (id, ip, build_time, status, region, name, notes, tags, last_seen, maintenance, maintenance_reason)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?)`,
+			device.ID, device.IP, device.BuildTime, device.Status, device.Region, device.Name, device.Notes, string(tags), device.LastSeen,
+			device.Maintenance, device.MaintenanceReason)
+		if err != nil {
+			return "", fmt.Errorf("添加设备失败: %w", err)
+		}
+	}
+
+	// 重建标签索引
+	if _, err := tx.Exec("DELETE FROM device_tags WHERE device_id = ?", device.ID); err != nil {
+		return "", fmt.Errorf("更新设备标签失败: %w", err)
+	}
+	for _, tag := range device.Tags {
+		if _, err := tx.Exec("INSERT INTO device_tags (device_id, tag) VALUES (?, ?)", device.ID, tag); err != nil {
+			return "", fmt.Errorf("更新设备标签失败: %w", err)
+		}
+	}
+	return changeType, nil
+}
+
+// Upsert 新增或更新单个设备
+func (r *SQLiteRepository) Upsert(device models.Device) error {
+	return r.UpsertMany([]models.Device{device})
+}
+
+// UpsertMany 在同一事务中新增或更新多个设备
+func (r *SQLiteRepository) UpsertMany(devices []models.Device) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+
+	changes := make([]DeviceChange, 0, len(devices))
+	for _, device := range devices {
+		if device.ID == "" {
+			tx.Rollback()
+			return fmt.Errorf("设备 %s 缺少ID", device.IP)
+		}
+		device = prepareDevice(device)
+		changeType, err := upsertTx(tx, device)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		device.DeletedAt = ""
+		changes = append(changes, DeviceChange{Type: changeType, Device: device})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+
+	r.notifier.publish(changes...)
+	return nil
+}
+
+// UpdateStatus 更新设备状态
+func (r *SQLiteRepository) UpdateStatus(id, status string) error {
+	var lastSeen interface{}
+	if status == "online" {
+		lastSeen = time.Now().Format(lastSeenLayout)
+	}
+
+	result, err := r.db.Exec(
+		"UPDATE devices SET status = ?, last_seen = COALESCE(?, last_seen), updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+		status, lastSeen, id)
+	if err != nil {
+		return fmt.Errorf("更新设备状态失败: %w", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrDeviceNotFound
+	}
+
+	if device, err := r.Get(id); err == nil {
+		r.notifier.publish(DeviceChange{Type: ChangeUpdated, Device: device})
+	}
+	return nil
+}
+
+// SetRegion 在同一事务中设置多个设备的区域
+func (r *SQLiteRepository) SetRegion(ids []string, region string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+
+	stmt, err := tx.Prepare("UPDATE devices SET region = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?")
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("准备更新语句失败: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, id := range ids {
+		if _, err := stmt.Exec(region, id); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("更新区域失败: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+
+	if devices, err := r.Find(models.DeviceFilter{IDs: ids}); err == nil {
+		changes := make([]DeviceChange, 0, len(devices))
+		for _, device := range devices {
+			changes = append(changes, DeviceChange{Type: ChangeUpdated, Device: device})
+		}
+		r.notifier.publish(changes...)
+	}
+	return nil
+}
+
+// SetMaintenance 在同一事务中设置或清除多个设备的维护模式
+func (r *SQLiteRepository) SetMaintenance(ids []string, enabled bool, reason string) error {
+	if !enabled {
+		reason = ""
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+
+	stmt, err := tx.Prepare("UPDATE devices SET maintenance = ?, maintenance_reason = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?")
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("准备更新语句失败: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, id := range ids {
+		if _, err := stmt.Exec(enabled, reason, id); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("更新维护模式失败: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+
+	if devices, err := r.Find(models.DeviceFilter{IDs: ids}); err == nil {
+		changes := make([]DeviceChange, 0, len(devices))
+		for _, device := range devices {
+			changes = append(changes, DeviceChange{Type: ChangeUpdated, Device: device})
+		}
+		r.notifier.publish(changes...)
+	}
+	return nil
+}
+
+// setDeletedAt 在同一事务中设置或清除设备的删除时间，condition非空时只更新满足条件的设备。
+// 返回实际更新的设备
+func (r *SQLiteRepository) setDeletedAt(ids []string, deletedAt interface{}, condition string) ([]models.Device, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("开始事务失败: %w", err)
+	}
+
+	query := "UPDATE devices SET deleted_at = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?"
+	if condition != "" {
+		query += " AND " + condition
+	}
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("准备更新语句失败: %w", err)
+	}
+	defer stmt.Close()
+
+	updated := make([]string, 0, len(ids))
+	for _, id := range ids {
+		result, err := stmt.Exec(deletedAt, id)
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("更新设备 %s 失败: %w", id, err)
+		}
+		if rows, err := result.RowsAffected(); err == nil && rows > 0 {
+			updated = append(updated, id)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %w", err)
+	}
+	if len(updated) == 0 {
+		return nil, nil
+	}
+	return r.Find(models.DeviceFilter{IDs: updated, IncludeDeleted: true})
+}
+
+// Delete 在同一事务中将设备移入回收站，已在回收站中的设备保持原来的删除时间
+func (r *SQLiteRepository) Delete(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	devices, err := r.setDeletedAt(ids, time.Now().UTC().Format(deletedAtLayout), "deleted_at IS NULL")
+	if err != nil {
+		return fmt.Errorf("从数据库删除设备失败: %w", err)
+	}
+	if len(devices) == 0 {
+		return ErrDeviceNotFound
+	}
+
+	changes := make([]DeviceChange, 0, len(devices))
+	for _, device := range devices {
+		changes = append(changes, DeviceChange{Type: ChangeRemoved, Device: device})
+	}
+	r.notifier.publish(changes...)
+	return nil
+}
+
+// DeleteAll 将所有设备移入回收站
+func (r *SQLiteRepository) DeleteAll() error {
+	devices, err := r.Find(models.DeviceFilter{})
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, len(devices))
+	for i, device := range devices {
+		ids[i] = device.ID
+	}
+
+	if err := r.Delete(ids); err != nil && err != ErrDeviceNotFound {
+		return fmt.Errorf("清空设备列表失败: %w", err)
+	}
+	return nil
+}
+
+// Restore 在同一事务中从回收站恢复设备
+func (r *SQLiteRepository) Restore(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	devices, err := r.setDeletedAt(ids, nil, "")
+	if err != nil {
+		return fmt.Errorf("恢复设备失败: %w", err)
+	}
+	if len(devices) == 0 {
+		return ErrDeviceNotFound
+	}
+
+	changes := make([]DeviceChange, 0, len(devices))
+	for _, device := range devices {
+		changes = append(changes, DeviceChange{Type: ChangeRestored, Device: device})
+	}
+	r.notifier.publish(changes...)
+	return nil
+}
+
+// Purge 永久删除回收站中删除时间早于before的设备
+func (r *SQLiteRepository) Purge(before time.Time) (int, error) {
+	result, err := r.db.Exec(
+		"DELETE FROM devices WHERE deleted_at IS NOT NULL AND deleted_at <= ?",
+		before.UTC().Format(deletedAtLayout))
+	if err != nil {
+		return 0, fmt.Errorf("清除回收站失败: %w", err)
+	}
+
+	affected, _ := result.RowsAffected()
+	if affected > 0 {
+		if _, err := r.db.Exec("DELETE FROM device_tags WHERE device_id NOT IN (SELECT id FROM devices)"); err != nil {
+			fmt.Printf("清除已删除设备的标签失败: %v\n", err)
+		}
+	}
+	return int(affected), nil
+}
+
+// Snapshot 使用VACUUM INTO将数据库完整复制到指定文件
+func (r *SQLiteRepository) Snapshot(path string) error {
+	// VACUUM INTO要求目标文件不存在
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除旧快照失败: %w", err)
+	}
+
+	if _, err := r.db.Exec("VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("创建数据库快照失败: %w", err)
+	}
+	return nil
+}
+
+// Regions 获取所有非空区域
+func (r *SQLiteRepository) Regions() ([]string, error) {
+	rows, err := r.db.Query("SELECT DISTINCT region FROM devices WHERE region != '' AND deleted_at IS NULL")
+	if err != nil {
+		return nil, fmt.Errorf("查询区域失败: %w", err)
+	}
+	defer rows.Close()
+
+	regions := []string{}
+	for rows.Next() {
+		var region sql.NullString
+		if err := rows.Scan(&region); err != nil {
+			continue
+		}
+		if region.String != "" {
+			regions = append(regions, region.String)
+		}
+	}
+	return regions, rows.Err()
+}
+
+// Tags 获取所有未删除设备使用的标签
+func (r *SQLiteRepository) Tags() ([]string, error) {
+	rows, err := r.db.Query(`SELECT DISTINCT t.tag FROM device_tags t
+		JOIN devices d ON d.id = t.device_id
+		WHERE d.deleted_at IS NULL ORDER BY t.tag`)
+	if err != nil {
+		return nil, fmt.Errorf("查询标签失败: %w", err)
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// Subscribe 订阅设备变更
+func (r *SQLiteRepository) Subscribe(fn func(DeviceChange)) func() {
+	return r.notifier.subscribe(fn)
+}
+
+// Close 关闭数据库连接
+func (r *SQLiteRepository) Close() error {
+	return r.db.Close()
+}
+
+// Ensure SQLiteRepository implements DeviceRepository
+var _ DeviceRepository = (*SQLiteRepository)(nil)
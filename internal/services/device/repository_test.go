@@ -0,0 +1,320 @@
+package device
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"application-updater/internal/models"
+)
+
+// repositories returns every DeviceRepository implementation under test
+func repositories(t *testing.T) map[string]DeviceRepository {
+	sqliteRepo, err := NewSQLiteRepository(filepath.Join(t.TempDir(), "devices.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteRepository failed: %v", err)
+	}
+	t.Cleanup(func() { sqliteRepo.Close() })
+
+	return map[string]DeviceRepository{
+		"sqlite": sqliteRepo,
+		"memory": NewMemoryRepository(),
+	}
+}
+
+func TestRepositoryFindByRegion(t *testing.T) {
+	for name, repo := range repositories(t) {
+		t.Run(name, func(t *testing.T) {
+			err := repo.UpsertMany([]models.Device{
+				{ID: "a", IP: "10.0.0.1", Status: "online", Region: "north"},
+				{ID: "b", IP: "10.0.0.2", Status: "offline", Region: "south"},
+				{ID: "c", IP: "10.0.0.3", Status: "online"},
+			})
+			if err != nil {
+				t.Fatalf("UpsertMany failed: %v", err)
+			}
+
+			devices, err := repo.Find(models.DeviceFilter{Region: "north", IncludeNoRegion: true})
+			if err != nil {
+				t.Fatalf("Find failed: %v", err)
+			}
+			if len(devices) != 2 {
+				t.Errorf("Expected 2 devices in north including unassigned, got %d", len(devices))
+			}
+
+			devices, _ = repo.Find(models.DeviceFilter{Region: "north"})
+			if len(devices) != 1 || devices[0].ID != "a" {
+				t.Errorf("Expected only device a in north, got %v", devices)
+			}
+
+			count, _ := repo.Count(models.DeviceFilter{Status: "online"})
+			if count != 2 {
+				t.Errorf("Expected 2 online devices, got %d", count)
+			}
+		})
+	}
+}
+
+func TestRepositoryUpsertManyIsAtomic(t *testing.T) {
+	for name, repo := range repositories(t) {
+		t.Run(name, func(t *testing.T) {
+			err := repo.UpsertMany([]models.Device{
+				{ID: "a", IP: "10.0.0.1"},
+				{IP: "10.0.0.2"}, // 缺少ID
+			})
+			if err == nil {
+				t.Fatalf("Expected UpsertMany to reject a device without ID")
+			}
+
+			if count, _ := repo.Count(models.DeviceFilter{}); count != 0 {
+				t.Errorf("Expected no devices after failed UpsertMany, got %d", count)
+			}
+		})
+	}
+}
+
+func TestRepositoryNotifiesChanges(t *testing.T) {
+	for name, repo := range repositories(t) {
+		t.Run(name, func(t *testing.T) {
+			var changes []DeviceChange
+			unsubscribe := repo.Subscribe(func(change DeviceChange) {
+				changes = append(changes, change)
+			})
+
+			repo.Upsert(models.Device{ID: "a", IP: "10.0.0.1"})
+			repo.UpdateStatus("a", "online")
+			repo.SetRegion([]string{"a"}, "north")
+			repo.Delete([]string{"a"})
+			unsubscribe()
+			repo.Upsert(models.Device{ID: "b", IP: "10.0.0.2"})
+
+			expected := []ChangeType{ChangeAdded, ChangeUpdated, ChangeUpdated, ChangeRemoved}
+			if len(changes) != len(expected) {
+				t.Fatalf("Expected %d changes, got %d", len(expected), len(changes))
+			}
+			for i, changeType := range expected {
+				if changes[i].Type != changeType {
+					t.Errorf("Change %d: expected %s, got %s", i, changeType, changes[i].Type)
+				}
+			}
+			if changes[2].Device.Region != "north" {
+				t.Errorf("Expected region change to carry the new region, got %q", changes[2].Device.Region)
+			}
+		})
+	}
+}
+
+func TestServiceRegionFilter(t *testing.T) {
+	service := NewServiceWithRepository(t.TempDir(), NewMemoryRepository())
+
+	service.AddDevice(models.Device{ID: "a", IP: "10.0.0.1", Region: "north"})
+	service.AddDevice(models.Device{ID: "b", IP: "10.0.0.2", Region: "south"})
+	service.SetRegionFilter("north")
+
+	if devices := service.GetDevices(); len(devices) != 1 {
+		t.Fatalf("Expected 1 device in north, got %d", len(devices))
+	}
+
+	// 修改区域后无需手动维护缓存，查询结果立即生效
+	if err := service.SetDevicesRegion([]string{"10.0.0.2"}, "north"); err != nil {
+		t.Fatalf("SetDevicesRegion failed: %v", err)
+	}
+	if devices := service.GetDevices(); len(devices) != 2 {
+		t.Errorf("Expected 2 devices in north after region change, got %d", len(devices))
+	}
+
+	service.RemoveDevice("a")
+	if devices := service.GetDevices(); len(devices) != 1 {
+		t.Errorf("Expected 1 device in north after removal, got %d", len(devices))
+	}
+}
+
+func TestRepositorySoftDelete(t *testing.T) {
+	for name, repo := range repositories(t) {
+		t.Run(name, func(t *testing.T) {
+			repo.UpsertMany([]models.Device{
+				{ID: "a", IP: "10.0.0.1", Region: "north"},
+				{ID: "b", IP: "10.0.0.2", Region: "north"},
+			})
+
+			if err := repo.Delete([]string{"a"}); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if devices, _ := repo.Find(models.DeviceFilter{}); len(devices) != 1 {
+				t.Errorf("Expected 1 live device after delete, got %d", len(devices))
+			}
+			trash, _ := repo.Find(models.DeviceFilter{OnlyDeleted: true})
+			if len(trash) != 1 || trash[0].ID != "a" || trash[0].DeletedAt == "" {
+				t.Fatalf("Expected device a in trash with deletedAt, got %v", trash)
+			}
+
+			if err := repo.Restore([]string{"a"}); err != nil {
+				t.Fatalf("Restore failed: %v", err)
+			}
+			if devices, _ := repo.Find(models.DeviceFilter{}); len(devices) != 2 {
+				t.Errorf("Expected 2 live devices after restore, got %d", len(devices))
+			}
+
+			repo.DeleteAll()
+			if purged, _ := repo.Purge(time.Now().Add(-time.Hour)); purged != 0 {
+				t.Errorf("Expected retention to keep recently deleted devices, purged %d", purged)
+			}
+			if purged, _ := repo.Purge(time.Now().Add(time.Second)); purged != 2 {
+				t.Errorf("Expected 2 purged devices, got %d", purged)
+			}
+			if count, _ := repo.Count(models.DeviceFilter{IncludeDeleted: true}); count != 0 {
+				t.Errorf("Expected empty repository after purge, got %d", count)
+			}
+		})
+	}
+}
+
+func TestRepositoryDeleteSkipsTrashedDevices(t *testing.T) {
+	for name, repo := range repositories(t) {
+		t.Run(name, func(t *testing.T) {
+			repo.UpsertMany([]models.Device{
+				{ID: "a", IP: "10.0.0.1"},
+				{ID: "b", IP: "10.0.0.2"},
+			})
+			if err := repo.Delete([]string{"a"}); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			trashed, err := repo.Get("a")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var removed []string
+			unsubscribe := repo.Subscribe(func(change DeviceChange) {
+				if change.Type == ChangeRemoved {
+					removed = append(removed, change.Device.ID)
+				}
+			})
+			defer unsubscribe()
+
+			// 删除时间精确到秒，等待后再次删除才能发现保留期是否被重置
+			time.Sleep(1100 * time.Millisecond)
+			if err := repo.Delete([]string{"a", "b"}); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if len(removed) != 1 || removed[0] != "b" {
+				t.Errorf("Expected only b to be reported as removed, got %v", removed)
+			}
+			if device, _ := repo.Get("a"); device.DeletedAt != trashed.DeletedAt {
+				t.Errorf("Expected a to keep its deletion time %s, got %s", trashed.DeletedAt, device.DeletedAt)
+			}
+
+			if err := repo.Delete([]string{"a"}); err != ErrDeviceNotFound {
+				t.Errorf("Expected deleting a trashed device to report ErrDeviceNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestServiceClearDevicesTakesSnapshot(t *testing.T) {
+	configDir := t.TempDir()
+	repo, err := NewSQLiteRepository(filepath.Join(configDir, "devices.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteRepository failed: %v", err)
+	}
+	service := NewServiceWithRepository(configDir, repo)
+	defer service.Close()
+
+	service.AddDevice(models.Device{ID: "a", IP: "10.0.0.1"})
+	if err := service.ClearDevices(); err != nil {
+		t.Fatalf("ClearDevices failed: %v", err)
+	}
+
+	snapshots, _ := filepath.Glob(filepath.Join(configDir, "snapshots", "devices_*_clear.db"))
+	if len(snapshots) != 1 {
+		t.Fatalf("Expected 1 snapshot before clear, got %d", len(snapshots))
+	}
+
+	snapshot, err := NewSQLiteRepository(snapshots[0])
+	if err != nil {
+		t.Fatalf("Failed to open snapshot: %v", err)
+	}
+	defer snapshot.Close()
+	if count, _ := snapshot.Count(models.DeviceFilter{}); count != 1 {
+		t.Errorf("Expected snapshot to contain the device, got %d", count)
+	}
+
+	if deleted := service.GetDeletedDevices(); len(deleted) != 1 {
+		t.Errorf("Expected cleared device in trash, got %d", len(deleted))
+	}
+}
+
+func TestRepositoryQuery(t *testing.T) {
+	for name, repo := range repositories(t) {
+		t.Run(name, func(t *testing.T) {
+			repo.UpsertMany([]models.Device{
+				{ID: "a", IP: "10.0.0.3", Name: "Gate", BuildTime: "2024-01-01 00:00:00", Tags: []string{"gate", "north"}},
+				{ID: "b", IP: "10.0.0.1", Notes: "near gate 50%", BuildTime: "2024-06-01 00:00:00", Tags: []string{"gate"}},
+				{ID: "c", IP: "10.0.0.2", Name: "Hall", BuildTime: "2025-01-01 00:00:00", Status: "online"},
+			})
+
+			page, err := repo.Query(models.DeviceQuery{
+				Filter: models.DeviceFilter{Search: "GATE"},
+				SortBy: models.DeviceSortIP,
+			})
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if page.Total != 2 || page.Devices[0].ID != "b" || page.Devices[1].ID != "a" {
+				t.Errorf("Expected b, a matching search sorted by IP, got %+v", page.Devices)
+			}
+
+			if page, _ := repo.Query(models.DeviceQuery{Filter: models.DeviceFilter{Search: "50%"}}); page.Total != 1 {
+				t.Errorf("Expected literal %% in search to match one device, got %d", page.Total)
+			}
+
+			page, _ = repo.Query(models.DeviceQuery{
+				Filter: models.DeviceFilter{Tags: []string{"gate", "north"}, BuildTimeTo: "2024-12-31 23:59:59"},
+			})
+			if page.Total != 1 || page.Devices[0].ID != "a" {
+				t.Errorf("Expected only device a with both tags, got %+v", page.Devices)
+			}
+
+			page, _ = repo.Query(models.DeviceQuery{SortBy: models.DeviceSortBuildTime, SortDesc: true, Page: 2, PageSize: 2})
+			if page.Total != 3 || len(page.Devices) != 1 || page.Devices[0].ID != "a" {
+				t.Errorf("Expected second page to hold the oldest build, got total %d %+v", page.Total, page.Devices)
+			}
+
+			online, _ := repo.Get("c")
+			if online.LastSeen == "" {
+				t.Errorf("Expected online device to record lastSeen")
+			}
+			if page, _ := repo.Query(models.DeviceQuery{Filter: models.DeviceFilter{LastSeenFrom: online.LastSeen}}); page.Total != 1 {
+				t.Errorf("Expected 1 device seen since %s, got %d", online.LastSeen, page.Total)
+			}
+
+			if tags, _ := repo.Tags(); len(tags) != 2 {
+				t.Errorf("Expected 2 distinct tags, got %v", tags)
+			}
+		})
+	}
+}
+
+func TestRepositoryMaintenance(t *testing.T) {
+	for name, repo := range repositories(t) {
+		t.Run(name, func(t *testing.T) {
+			repo.UpsertMany([]models.Device{{ID: "a", IP: "10.0.0.1"}, {ID: "b", IP: "10.0.0.2"}})
+
+			if err := repo.SetMaintenance([]string{"a"}, true, "replacing disk"); err != nil {
+				t.Fatalf("SetMaintenance failed: %v", err)
+			}
+			devices, _ := repo.Find(models.DeviceFilter{ExcludeMaintenance: true})
+			if len(devices) != 1 || devices[0].ID != "b" {
+				t.Errorf("Expected only device b outside maintenance, got %v", devices)
+			}
+			if device, _ := repo.Get("a"); !device.Maintenance || device.MaintenanceReason != "replacing disk" {
+				t.Errorf("Expected maintenance flag and reason on device a, got %+v", device)
+			}
+
+			repo.SetMaintenance([]string{"a"}, false, "ignored")
+			if device, _ := repo.Get("a"); device.Maintenance || device.MaintenanceReason != "" {
+				t.Errorf("Expected maintenance to be cleared, got %+v", device)
+			}
+		})
+	}
+}
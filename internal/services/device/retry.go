@@ -0,0 +1,102 @@
+package device
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+
+	"application-updater/internal/models"
+)
+
+// retryUnit 重试等待时间的单位，测试中会缩短
+var retryUnit = time.Second
+
+// 各步骤的默认重试策略
+var (
+	defaultLoginRetry  = models.RetryPolicy{MaxAttempts: 3, InitialDelaySeconds: 2, MaxDelaySeconds: 10}
+	defaultUploadRetry = models.RetryPolicy{MaxAttempts: 3, InitialDelaySeconds: 5, MaxDelaySeconds: 60}
+	defaultVerifyRetry = models.RetryPolicy{MaxAttempts: 2, InitialDelaySeconds: 10, MaxDelaySeconds: 60}
+)
+
+// statusError 设备返回了非200的HTTP状态码
+type statusError struct {
+	code int
+	body string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("更新失败，状态码: %d, 响应: %s", e.code, e.body)
+}
+
+// isRetryable 判断错误是否为暂时性故障：网络错误、连接中断和服务端5xx/429可以重试，
+// 认证失败、设备拒绝更新和文件错误等重试也不会成功的错误视为致命错误
+func isRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var status *statusError
+	if errors.As(err, &status) {
+		return status.code >= 500 || status.code == 429
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// withDefaults 用默认策略补全未设置的字段
+func withDefaults(policy, defaults models.RetryPolicy) models.RetryPolicy {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = defaults.MaxAttempts
+	}
+	if policy.InitialDelaySeconds <= 0 {
+		policy.InitialDelaySeconds = defaults.InitialDelaySeconds
+	}
+	if policy.MaxDelaySeconds <= 0 {
+		policy.MaxDelaySeconds = defaults.MaxDelaySeconds
+	}
+	return policy
+}
+
+// backoff 返回第attempt次重试前的等待时间：指数增长并封顶，再在后一半区间内随机抖动，
+// 避免大量设备同时失败后在同一时刻重试
+func backoff(policy models.RetryPolicy, attempt int) time.Duration {
+	delay := time.Duration(policy.InitialDelaySeconds) * retryUnit
+	limit := time.Duration(policy.MaxDelaySeconds) * retryUnit
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// retryStep 执行step直到成功、遇到致命错误或达到最大尝试次数，返回尝试次数和最后一次的错误
+func retryStep(name, ip string, policy models.RetryPolicy, step func() error) (int, error) {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = step()
+		if err == nil || !isRetryable(err) || attempt >= policy.MaxAttempts {
+			return attempt, err
+		}
+
+		delay := backoff(policy, attempt)
+		fmt.Printf("设备 %s %s失败(第%d次): %v，%v后重试\n", ip, name, attempt, err, delay)
+		time.Sleep(delay)
+	}
+}
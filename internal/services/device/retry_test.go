@@ -0,0 +1,69 @@
+package device
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"application-updater/internal/models"
+	"application-updater/internal/utils"
+)
+
+func TestIsRetryable(t *testing.T) {
+	cases := []struct {
+		err       error
+		retryable bool
+	}{
+		{&net.OpError{Op: "dial", Err: fmt.Errorf("connection refused")}, true},
+		{fmt.Errorf("发送请求失败: %w", io.ErrUnexpectedEOF), true},
+		{&statusError{code: 503}, true},
+		{&statusError{code: 400}, false},
+		{fmt.Errorf("登录失败: 密码错误"), false},
+	}
+	for _, c := range cases {
+		if got := isRetryable(c.err); got != c.retryable {
+			t.Errorf("isRetryable(%v) = %v, want %v", c.err, got, c.retryable)
+		}
+	}
+}
+
+func TestRetryStepStopsOnFatalError(t *testing.T) {
+	retryUnit = time.Millisecond
+	policy := models.RetryPolicy{MaxAttempts: 3, InitialDelaySeconds: 1, MaxDelaySeconds: 2}
+
+	calls := 0
+	attempts, err := retryStep("上传", "10.0.0.1", policy, func() error {
+		calls++
+		if calls < 3 {
+			return &statusError{code: 502}
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("Expected success on the third attempt, got %d attempts, err %v", attempts, err)
+	}
+
+	attempts, _ = retryStep("登录", "10.0.0.1", policy, func() error {
+		return fmt.Errorf("登录失败: 密码错误")
+	})
+	if attempts != 1 {
+		t.Errorf("Expected fatal errors not to be retried, got %d attempts", attempts)
+	}
+}
+
+func TestRunningJobsAreInterruptedOnStart(t *testing.T) {
+	dir := t.TempDir()
+	service := NewServiceWithRepository(dir, NewMemoryRepository())
+	job := models.UpdateJob{ID: "job1", Status: models.UpdateJobRunning, Pending: []string{"d1"}}
+	if err := utils.SaveConfig(service.jobPath(job.ID), job); err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟应用重启
+	jobs := NewServiceWithRepository(dir, NewMemoryRepository()).GetUpdateJobs()
+	if len(jobs) != 1 || jobs[0].Status != models.UpdateJobInterrupted {
+		t.Fatalf("Expected the running job to be marked interrupted, got %+v", jobs)
+	}
+}
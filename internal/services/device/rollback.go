@@ -0,0 +1,153 @@
+package device
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"application-updater/internal/models"
+	"application-updater/internal/services/lock"
+	"application-updater/internal/utils"
+)
+
+// 设备上application-web的默认位置
+const (
+	defaultBinaryPath   = "/usr/local/bin/application-web"
+	defaultConfigPath   = "/etc/application-web"
+	defaultServiceName  = "application-web"
+	defaultRollbackPath = "/var/lib/application-web/rollback"
+)
+
+// applyRollbackDefaults 为未设置的程序、配置、服务和快照位置填入默认值
+func applyRollbackDefaults(settings *models.DeviceSettings) {
+	if settings.BinaryPath == "" {
+		settings.BinaryPath = defaultBinaryPath
+	}
+	if settings.ConfigPath == "" {
+		settings.ConfigPath = defaultConfigPath
+	}
+	if settings.ServiceName == "" {
+		settings.ServiceName = defaultServiceName
+	}
+	if settings.RollbackPath == "" {
+		settings.RollbackPath = defaultRollbackPath
+	}
+}
+
+// runSSH 在设备上执行一条命令并返回输出
+func runSSH(ip, username, password, command string) (string, error) {
+	client, err := utils.CreateSSHClient(ip, username, password, 22)
+	if err != nil {
+		return "", fmt.Errorf("SSH连接失败: %w", err)
+	}
+	defer client.Close()
+
+	output, err := utils.ExecuteSSHCommand(client, command)
+	if err != nil {
+		return output, fmt.Errorf("执行命令失败: %w, 输出: %s", err, strings.TrimSpace(output))
+	}
+	return output, nil
+}
+
+// snapshotCommand 生成保存当前程序、配置和buildTime的命令，快照保存在设备本地，上一次的快照会被替换
+func snapshotCommand(settings models.DeviceSettings, buildTime string) string {
+	dir := utils.EscapeShellArg(settings.RollbackPath)
+	config := utils.EscapeShellArg(settings.ConfigPath)
+	return fmt.Sprintf("set -e; rm -rf %[1]s.tmp; mkdir -p %[1]s.tmp; "+
+		"cp -a %[2]s %[1]s.tmp/binary; "+
+		"if [ -e %[3]s ]; then cp -a %[3]s %[1]s.tmp/config; fi; "+
+		"printf '%%s' %[4]s > %[1]s.tmp/buildTime; "+
+		"rm -rf %[1]s; mv %[1]s.tmp %[1]s",
+		dir, utils.EscapeShellArg(settings.BinaryPath), config, utils.EscapeShellArg(buildTime))
+}
+
+// restoreCommand 生成从快照恢复程序和配置并重启服务的命令，程序文件先复制到同目录再原子替换
+func restoreCommand(settings models.DeviceSettings) string {
+	dir := utils.EscapeShellArg(settings.RollbackPath)
+	binary := utils.EscapeShellArg(settings.BinaryPath)
+	config := utils.EscapeShellArg(settings.ConfigPath)
+	return fmt.Sprintf("set -e; test -f %[1]s/binary; "+
+		"cp -a %[1]s/binary %[2]s.rollback; mv -f %[2]s.rollback %[2]s; "+
+		"if [ -e %[1]s/config ]; then rm -rf %[3]s; cp -a %[1]s/config %[3]s; fi; "+
+		"systemctl restart %[4]s",
+		dir, binary, config, utils.EscapeShellArg(settings.ServiceName))
+}
+
+// snapshotDevice 升级前在设备上保存当前程序和配置
+func (s *Service) snapshotDevice(ip, buildTime string, options models.UpgradeOptions) error {
+	if options.SSHUsername == "" {
+		return fmt.Errorf("升级前快照需要SSH账号")
+	}
+	if _, err := runSSH(ip, options.SSHUsername, options.SSHPassword, snapshotCommand(s.GetSettings(), buildTime)); err != nil {
+		return fmt.Errorf("升级前快照失败，已取消更新: %w", err)
+	}
+	fmt.Printf("已在设备 %s 上保存升级前快照(buildTime: %s)\n", ip, displayBuild(buildTime))
+	return nil
+}
+
+// RollbackDevices 通过SSH将设备恢复到升级前快照中的程序和配置，重启服务并验证buildTime已恢复
+func (s *Service) RollbackDevices(deviceIds []string, sshUsername, sshPassword string) ([]models.UpdateResult, error) {
+	if sshUsername == "" {
+		return nil, fmt.Errorf("回滚需要SSH账号")
+	}
+	devices, err := s.repo.Find(models.DeviceFilter{IDs: deviceIds})
+	if err != nil {
+		return nil, fmt.Errorf("查询设备失败: %w", err)
+	}
+	if len(devices) == 0 {
+		return nil, fmt.Errorf("没有需要回滚的设备")
+	}
+
+	settings := s.GetSettings()
+	results := make([]models.UpdateResult, len(devices))
+	semaphore := make(chan struct{}, s.transfers.Settings().MaxConcurrent)
+	var wg sync.WaitGroup
+	for i, device := range devices {
+		wg.Add(1)
+		go func(i int, ip string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			results[i] = s.rollbackDevice(ip, settings, sshUsername, sshPassword)
+		}(i, device.IP)
+	}
+	wg.Wait()
+
+	return results, nil
+}
+
+// rollbackDevice 回滚单台设备
+func (s *Service) rollbackDevice(ip string, settings models.DeviceSettings, sshUsername, sshPassword string) models.UpdateResult {
+	result := models.UpdateResult{IP: ip}
+
+	release, err := lock.Default().TryAcquire(ip, lock.OperationRollback)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	defer release()
+
+	result.BuildBefore = s.readBuildTime(ip)
+
+	// 快照中记录的buildTime即回滚后应运行的版本
+	snapshotBuild, err := runSSH(ip, sshUsername, sshPassword, "cat "+utils.EscapeShellArg(settings.RollbackPath+"/buildTime"))
+	if err != nil {
+		result.Message = "设备上没有可用的升级前快照: " + err.Error()
+		return result
+	}
+
+	if _, err := runSSH(ip, sshUsername, sshPassword, restoreCommand(settings)); err != nil {
+		result.Message = "回滚失败: " + err.Error()
+		return result
+	}
+
+	expected := strings.TrimSpace(snapshotBuild)
+	s.verifyUpgrade(&result, models.UpgradeOptions{ExpectedBuild: expected})
+	if result.Outcome == models.OutcomeUpgraded {
+		result.Message = fmt.Sprintf("回滚成功，buildTime: %s -> %s", displayBuild(result.BuildBefore), result.BuildAfter)
+	} else {
+		result.Message = "回滚后验证失败: " + result.Message
+	}
+	return result
+}
@@ -176,14 +176,10 @@ func (s *DeviceScanner) RefreshDevices(devices []models.Device) []models.Device
 					result = originalDevice
 					result.Status = "offline"
 				} else {
-					// 设备在线 - 使用原始设备的ID和Region，更新BuildTime和Status
-					result = *updatedDevice
-
-					// 始终保留原始设备ID
-					result.ID = originalDevice.ID
-
-					// 保留区域信息
-					result.Region = originalDevice.Region
+					// 设备在线 - 保留原始设备的ID、区域、名称等信息，只更新BuildTime和Status
+					result = originalDevice
+					result.BuildTime = updatedDevice.BuildTime
+					result.Status = updatedDevice.Status
 				}
 				resultChan <- result
 			}
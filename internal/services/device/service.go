@@ -1,24 +1,20 @@
 package device
 
 import (
-	"bytes"
 	"context"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
-	"strings"
 	"sync"
 	"time"
 
 	"application-updater/internal/models"
-
-	_ "github.com/mattn/go-sqlite3" // SQLite驱动
+	"application-updater/internal/services/lock"
+	"application-updater/internal/services/transfer"
 )
 
 // Scanner 接口定义设备扫描功能
@@ -29,36 +25,65 @@ type Scanner interface {
 
 // Service 设备服务
 type Service struct {
-	Scanner         Scanner
-	Auth            *Auth
-	mutex           sync.RWMutex
-	currentRegion   string
-	filteredDevices []models.Device
+	Scanner       Scanner
+	Auth          *Auth
+	mutex         sync.RWMutex
+	currentRegion string
 
 	// 原Manager字段
 	configDir string
-	db        *sql.DB
+	repo      DeviceRepository
+
+	uploadProgress func(progress models.UploadProgress)
+	transfers      *transfer.Manager
+
+	// jobMutex 保护更新任务文件的读写
+	jobMutex sync.Mutex
+	// openJobs 执行中或等待离线设备上线的任务，与任务文件保持一致
+	openJobs map[string]*models.UpdateJob
+	// jobRuns 每个任务正在执行的批次数
+	jobRuns map[string]int
+	// deferredRuns 正在为上线设备继续执行任务的后台协程数
+	deferredRuns int
+
+	// 定期检查等待上线设备的后台协程
+	stopWatch chan struct{}
+	watchDone chan struct{}
+	closeOnce sync.Once
 }
 
-// NewService 创建设备服务实例
+// NewService 创建设备服务实例，设备存储在配置目录下的devices.db中
 func NewService(configDir string) *Service {
-	client := &http.Client{}
-
-	service := &Service{
-		Scanner:         NewScanner(client),
-		Auth:            NewAuth(client),
-		filteredDevices: []models.Device{},
-		configDir:       configDir,
-	}
-
 	// 确保配置目录存在
 	if err := os.MkdirAll(configDir, 0755); err != nil {
 		fmt.Printf("创建配置目录失败: %v\n", err)
 	}
 
 	// 初始化数据库
-	if err := service.initDatabase(); err != nil {
-		fmt.Printf("初始化数据库失败: %v\n", err)
+	var repo DeviceRepository
+	repo, err := NewSQLiteRepository(filepath.Join(configDir, "devices.db"))
+	if err != nil {
+		fmt.Printf("初始化数据库失败: %v, 使用内存存储\n", err)
+		repo = NewMemoryRepository()
+	}
+
+	return NewServiceWithRepository(configDir, repo)
+}
+
+// NewServiceWithRepository 使用指定的设备存储创建设备服务实例
+func NewServiceWithRepository(configDir string, repo DeviceRepository) *Service {
+	client := &http.Client{}
+
+	service := &Service{
+		Scanner:   NewScanner(client),
+		Auth:      NewAuth(client),
+		configDir: configDir,
+		repo:      repo,
+		transfers: transfer.NewManager(configDir),
+		openJobs:  make(map[string]*models.UpdateJob),
+		jobRuns:   make(map[string]int),
+		stopWatch: make(chan struct{}),
+		watchDone: make(chan struct{}),
 	}
 
 	// 加载设备列表
@@ -66,130 +91,115 @@ func NewService(configDir string) *Service {
 		fmt.Printf("加载设备列表失败: %v\n", err)
 	}
 
-	return service
-}
-
-// initDatabase 初始化SQLite数据库
-func (s *Service) initDatabase() error {
-	dbPath := filepath.Join(s.configDir, "devices.db")
-	var err error
+	// 上次退出时仍在执行的更新任务可以继续，等待离线设备的任务在设备上线时继续
+	service.markInterruptedJobs()
+	service.repo.Subscribe(service.onDeviceChange)
+	go service.watchDeferred(deferredPollInterval, trashPurgeInterval)
 
-	// 打开数据库连接
-	s.db, err = sql.Open("sqlite3", dbPath)
-	if err != nil {
-		return fmt.Errorf("打开数据库失败: %w", err)
-	}
-
-	// 创建设备表
-	createTableSQL := `
-	CREATE TABLE IF NOT EXISTS devices (
-		id TEXT PRIMARY KEY,
-		ip TEXT NOT NULL,
-		build_time TEXT,
-		status TEXT,
-		region TEXT,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-	);
-	CREATE INDEX IF NOT EXISTS idx_devices_ip ON devices(ip);
-	`
-
-	_, err = s.db.Exec(createTableSQL)
-	if err != nil {
-		return fmt.Errorf("创建数据库表失败: %w", err)
+	// 清除超过保留期的回收站设备
+	if _, err := service.PurgeExpiredDevices(); err != nil {
+		fmt.Printf("清除过期的回收站设备失败: %v\n", err)
 	}
 
-	return nil
+	return service
+}
+
+// Subscribe 订阅设备变更，返回取消订阅函数
+func (s *Service) Subscribe(fn func(DeviceChange)) func() {
+	return s.repo.Subscribe(fn)
 }
+
 func (s *Service) GetAllRegions() []string {
-	rows, err := s.db.Query("SELECT DISTINCT region FROM devices")
+	regions, err := s.repo.Regions()
 	if err != nil {
 		fmt.Printf("查询区域失败: %v\n", err)
 		return []string{}
 	}
-	defer rows.Close()
-	fmt.Printf("查询区域成功: %v\n", rows)
-	regions := []string{}
-	for rows.Next() {
-		var region string
-		if err := rows.Scan(&region); err != nil {
-			continue
-		}
-		if region != "" {
-			regions = append(regions, region)
-		}
-	}
 	return regions
 }
 
-// GetDevices 获取所有设备
-func (s *Service) GetDevices() []models.Device {
+// regionFilter 返回当前区域过滤条件，包含指定区域和空区域的设备
+func (s *Service) regionFilter() models.DeviceFilter {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	// 如果有过滤区域，返回过滤后的设备列表
-	if s.currentRegion != "" {
-		return append([]models.Device{}, s.filteredDevices...)
+	return models.DeviceFilter{
+		Region:          s.currentRegion,
+		IncludeNoRegion: true,
 	}
-
-	// 否则返回所有设备
-	return s.getAllDevicesFromDB()
 }
 
-// getAllDevicesFromDB 直接从数据库获取所有设备
-func (s *Service) getAllDevicesFromDB() []models.Device {
-	// 直接从数据库查询所有设备
-	rows, err := s.db.Query("SELECT id, ip, build_time, status, region FROM devices")
+// findDevices 按过滤条件查询设备，出错时返回空列表
+func (s *Service) findDevices(filter models.DeviceFilter) []models.Device {
+	devices, err := s.repo.Find(filter)
 	if err != nil {
 		fmt.Printf("查询设备失败: %v\n", err)
 		return []models.Device{}
 	}
-	defer rows.Close()
-
-	devices := []models.Device{}
-	for rows.Next() {
-		var device models.Device
-		err := rows.Scan(&device.ID, &device.IP, &device.BuildTime, &device.Status, &device.Region)
-		if err != nil {
-			fmt.Printf("扫描设备记录失败: %v\n", err)
-			continue
-		}
-		devices = append(devices, device)
-	}
-
 	return devices
 }
 
+// GetDevices 获取当前区域过滤条件下的设备
+func (s *Service) GetDevices() []models.Device {
+	return s.findDevices(s.regionFilter())
+}
+
 // GetAllDevices 获取所有设备，不考虑过滤
 func (s *Service) GetAllDevices() []models.Device {
-	return s.getAllDevicesFromDB()
+	return s.findDevices(models.DeviceFilter{})
 }
 
-// SetRegionFilter 设置区域过滤
-func (s *Service) SetRegionFilter(region string) {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+// FindDevices 按过滤条件查询设备
+func (s *Service) FindDevices(filter models.DeviceFilter) ([]models.Device, error) {
+	return s.repo.Find(filter)
+}
 
-	s.currentRegion = region
+// QueryDevices 按过滤条件、排序和分页查询设备
+func (s *Service) QueryDevices(query models.DeviceQuery) (models.DevicePage, error) {
+	return s.repo.Query(query)
+}
 
-	// 如果区域为空，清空过滤设备列表
-	if region == "" {
-		s.filteredDevices = []models.Device{}
-		return
+// GetTags 获取所有设备使用的标签
+func (s *Service) GetTags() []string {
+	tags, err := s.repo.Tags()
+	if err != nil {
+		fmt.Printf("查询标签失败: %v\n", err)
+		return []string{}
 	}
+	return tags
+}
 
-	// 获取所有设备
-	allDevices := s.getAllDevicesFromDB()
+// UpdateDeviceDetails 更新设备的名称、备注和标签
+func (s *Service) UpdateDeviceDetails(id, name, notes string, tags []string) (models.Device, error) {
+	device, err := s.repo.Get(id)
+	if err != nil {
+		return models.Device{}, fmt.Errorf("未找到ID为 %s 的设备: %w", id, err)
+	}
 
-	// 过滤设备，包含指定区域和空区域的设备
-	s.filteredDevices = make([]models.Device, 0, len(allDevices))
-	for _, device := range allDevices {
-		if device.Region == region || device.Region == "" {
-			s.filteredDevices = append(s.filteredDevices, device)
-		}
+	device.Name = name
+	device.Notes = notes
+	device.Tags = tags
+	if err := s.repo.Upsert(device); err != nil {
+		return models.Device{}, fmt.Errorf("更新设备信息失败: %w", err)
 	}
+	return s.repo.Get(id)
+}
 
-	fmt.Printf("已过滤区域 %s 的设备(包含无区域设备)，共找到 %d 个设备\n", region, len(s.filteredDevices))
+// GetDevice 根据ID获取设备
+func (s *Service) GetDevice(id string) (models.Device, error) {
+	return s.repo.Get(id)
+}
+
+// SetRegionFilter 设置区域过滤
+func (s *Service) SetRegionFilter(region string) {
+	s.mutex.Lock()
+	s.currentRegion = region
+	s.mutex.Unlock()
+
+	if region != "" {
+		count, _ := s.repo.Count(s.regionFilter())
+		fmt.Printf("已过滤区域 %s 的设备(包含无区域设备)，共找到 %d 个设备\n", region, count)
+	}
 }
 
 // GetCurrentRegion 获取当前过滤区域
@@ -199,38 +209,18 @@ func (s *Service) GetCurrentRegion() string {
 	return s.currentRegion
 }
 
-// RefreshDevices 刷新设备状态
+// RefreshDevices 刷新当前区域过滤条件下的设备状态
 func (s *Service) RefreshDevices() []models.Device {
-	// 先解锁获取所有设备
 	allDevices := s.GetDevices()
 
-	var refreshedDevices []models.Device
 	// 使用Scanner刷新所有设备状态
 	if scanner, ok := s.Scanner.(*DeviceScanner); ok {
-		refreshedDevices = scanner.RefreshDevices(allDevices)
-
-		// 获取锁后更新数据库
-		s.mutex.Lock()
-		for _, device := range refreshedDevices {
-			_, err := s.db.Exec("UPDATE devices SET status = ? WHERE id = ?", device.Status, device.ID)
-			if err != nil {
-				fmt.Printf("更新设备 %s 状态失败: %v\n", device.ID, err)
-			}
-		}
+		refreshedDevices := scanner.RefreshDevices(allDevices)
 
-		// 如果有设置区域过滤，重新应用过滤
-		if s.currentRegion != "" {
-			s.filteredDevices = make([]models.Device, 0, len(refreshedDevices))
-			for _, device := range refreshedDevices {
-				if device.Region == s.currentRegion || device.Region == "" {
-					s.filteredDevices = append(s.filteredDevices, device)
-				}
-			}
-			filtered := append([]models.Device{}, s.filteredDevices...)
-			s.mutex.Unlock()
-			return filtered
+		// 在同一事务中写回状态和buildTime
+		if err := s.repo.UpsertMany(refreshedDevices); err != nil {
+			fmt.Printf("更新设备状态失败: %v\n", err)
 		}
-		s.mutex.Unlock()
 		return refreshedDevices
 	}
 
@@ -251,8 +241,7 @@ func (s *Service) LoadDevices() error {
 	fmt.Println("开始加载设备列表...")
 
 	// 检查数据库中是否有设备
-	var count int
-	err := s.db.QueryRow("SELECT COUNT(*) FROM devices").Scan(&count)
+	count, err := s.repo.Count(models.DeviceFilter{})
 	if err != nil {
 		fmt.Printf("查询设备数量失败: %v\n", err)
 		count = 0
@@ -304,34 +293,9 @@ func (s *Service) LoadDevices() error {
 
 		fmt.Printf("从JSON解析了 %d 个设备\n", len(jsonDevices))
 
-		// 准备插入语句
-		stmt, err := s.db.Prepare("INSERT INTO devices (id, ip, build_time, status, region) VALUES (?, ?, ?, ?, ?)")
-		if err != nil {
-			fmt.Printf("准备插入语句失败: %v\n", err)
-			return err
-		}
-		defer stmt.Close()
-
-		// 开始事务
-		tx, err := s.db.Begin()
-		if err != nil {
-			fmt.Printf("开始事务失败: %v\n", err)
-			return err
-		}
-
-		// 将设备导入数据库
-		for _, device := range jsonDevices {
-			_, err = tx.Stmt(stmt).Exec(device.ID, device.IP, device.BuildTime, device.Status, device.Region)
-			if err != nil {
-				tx.Rollback()
-				fmt.Printf("插入设备记录失败: %v\n", err)
-				return err
-			}
-		}
-
-		// 提交事务
-		if err := tx.Commit(); err != nil {
-			fmt.Printf("提交事务失败: %v\n", err)
+		// 在同一事务中将设备导入数据库
+		if err := s.repo.UpsertMany(jsonDevices); err != nil {
+			fmt.Printf("导入设备记录失败: %v\n", err)
 			return err
 		}
 
@@ -359,55 +323,15 @@ func (s *Service) LoadDevices() error {
 	return nil
 }
 
-// AddDevice 添加设备
+// AddDevice 添加设备，已存在的设备将被更新
 func (s *Service) AddDevice(device models.Device) (models.Device, error) {
 	// 确保设备ID已设置
 	if device.ID == "" {
 		device.ID = models.GenerateDeviceID(device.Region, device.IP)
 	}
 
-	// 添加设备到数据库
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
-	// 检查设备是否已存在
-	var existingID string
-	err := s.db.QueryRow("SELECT id FROM devices WHERE id = ?", device.ID).Scan(&existingID)
-
-	if err == nil {
-		// 设备已存在，更新记录
-		_, err = s.db.Exec(
-			"UPDATE devices SET ip = ?, build_time = ?, status = ?, region = ? WHERE id = ?",
-			device.IP, device.BuildTime, device.Status, device.Region, device.ID)
-		if err != nil {
-			return models.Device{}, fmt.Errorf("更新设备失败: %w", err)
-		}
-	} else {
-		// 设备不存在，插入新记录
-		_, err = s.db.Exec(
-			"INSERT INTO devices (id, ip, build_time, status, region) VALUES (?, ?, ?, ?, ?)",
-			device.ID, device.IP, device.BuildTime, device.Status, device.Region)
-		if err != nil {
-			return models.Device{}, fmt.Errorf("添加设备失败: %w", err)
-		}
-	}
-
-	// 如果有区域过滤且设备属于该区域或无区域，更新过滤后的设备列表
-	if s.currentRegion != "" && (device.Region == s.currentRegion || device.Region == "") {
-		// 检查设备是否已存在于过滤列表中
-		found := false
-		for i, d := range s.filteredDevices {
-			if d.ID == device.ID {
-				s.filteredDevices[i] = device
-				found = true
-				break
-			}
-		}
-
-		// 如果不存在，添加到过滤列表
-		if !found {
-			s.filteredDevices = append(s.filteredDevices, device)
-		}
+	if err := s.repo.Upsert(device); err != nil {
+		return models.Device{}, err
 	}
 
 	return device, nil
@@ -433,179 +357,64 @@ func (s *Service) TestAndAddDevice(ip string, region string) (models.Device, err
 	return s.AddDevice(*device)
 }
 
-// RemoveDevice 移除设备
+// RemoveDevice 将设备移入回收站
 func (s *Service) RemoveDevice(id string) error {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
-	// 从数据库删除设备
-	_, err := s.db.Exec("DELETE FROM devices WHERE id = ?", id)
-	if err != nil {
-		return fmt.Errorf("从数据库删除设备失败: %w", err)
-	}
+	return s.repo.Delete([]string{id})
+}
 
-	// 如果有区域过滤，从过滤后的设备列表中移除
-	if s.currentRegion != "" {
-		newFiltered := make([]models.Device, 0, len(s.filteredDevices))
-		for _, device := range s.filteredDevices {
-			if device.ID != id {
-				newFiltered = append(newFiltered, device)
-			}
+// RemoveDevices 批量将设备移入回收站，操作前自动创建数据库快照
+func (s *Service) RemoveDevices(ids []string) error {
+	if len(ids) > 1 {
+		if _, err := s.snapshotBeforeBulkOperation("remove"); err != nil {
+			return err
 		}
-		s.filteredDevices = newFiltered
 	}
-
-	return nil
+	return s.repo.Delete(ids)
 }
-func (s *Service) GetDeviceByRegionAndIP(region string, ip string) (models.Device, bool) {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
 
-	var device models.Device
-	err := s.db.QueryRow(
-		"SELECT id, ip, build_time, status, region FROM devices WHERE region = ? AND ip = ?",
-		region, ip).Scan(&device.ID, &device.IP, &device.BuildTime, &device.Status, &device.Region)
-
-	if err != nil {
+// GetDeviceByRegionAndIP 根据区域和IP查找设备
+func (s *Service) GetDeviceByRegionAndIP(region string, ip string) (models.Device, bool) {
+	devices, err := s.repo.Find(models.DeviceFilter{Region: region, IP: ip})
+	if err != nil || len(devices) == 0 {
 		return models.Device{}, false
 	}
 
-	return device, true
+	return devices[0], true
 }
 
 // SetDeviceRegion 设置设备区域
 func (s *Service) SetDeviceRegion(deviceID, region string) error {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
-	// 查找指定ID的设备
-	var device models.Device
-	err := s.db.QueryRow(
-		"SELECT id, ip, build_time, status, region FROM devices WHERE id = ?",
-		deviceID).Scan(&device.ID, &device.IP, &device.BuildTime, &device.Status, &device.Region)
-
-	if err != nil {
+	if _, err := s.repo.Get(deviceID); err != nil {
 		return fmt.Errorf("未找到ID为 %s 的设备: %w", deviceID, err)
 	}
 
-	// 保存原来的区域以便比较
-	oldRegion := device.Region
-
-	// 更新设备区域
-	_, err = s.db.Exec("UPDATE devices SET region = ? WHERE id = ?", region, deviceID)
-	if err != nil {
+	if err := s.repo.SetRegion([]string{deviceID}, region); err != nil {
 		return fmt.Errorf("更新设备区域失败: %w", err)
 	}
 
-	// 如果有区域过滤，更新过滤后的设备列表
-	if s.currentRegion != "" {
-		// 从过滤列表移除旧设备，如果设备区域既不是当前过滤区域也不是空区域
-		if oldRegion == s.currentRegion && region != s.currentRegion && region != "" {
-			newFiltered := make([]models.Device, 0, len(s.filteredDevices))
-			for _, d := range s.filteredDevices {
-				if d.ID != deviceID {
-					newFiltered = append(newFiltered, d)
-				}
-			}
-			s.filteredDevices = newFiltered
-		}
-
-		// 添加新设备到过滤列表（如果符合当前区域或无区域）
-		if region == s.currentRegion || region == "" {
-			// 获取更新后的设备
-			var updatedDevice models.Device
-			err := s.db.QueryRow(
-				"SELECT id, ip, build_time, status, region FROM devices WHERE id = ?",
-				deviceID).Scan(&updatedDevice.ID, &updatedDevice.IP, &updatedDevice.BuildTime, &updatedDevice.Status, &updatedDevice.Region)
-
-			if err == nil {
-				// 检查是否已存在
-				exists := false
-				for i, filteredDevice := range s.filteredDevices {
-					if filteredDevice.ID == deviceID {
-						// 更新现有设备
-						s.filteredDevices[i] = updatedDevice
-						exists = true
-						break
-					}
-				}
-				// 如果不存在，添加到过滤列表
-				if !exists {
-					s.filteredDevices = append(s.filteredDevices, updatedDevice)
-				}
-			}
-		}
-	}
-
 	return nil
 }
 
 // SetDevicesRegion 批量设置设备区域
 func (s *Service) SetDevicesRegion(deviceIDs []string, region string) error {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
-	// 开始事务
-	tx, err := s.db.Begin()
-	if err != nil {
-		return fmt.Errorf("开始事务失败: %w", err)
-	}
-
-	// 更新设备区域
-	stmt, err := tx.Prepare("UPDATE devices SET region = ? WHERE id = ?")
-	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("准备更新语句失败: %w", err)
-	}
-	defer stmt.Close()
-
-	// 用于旧版本的IP地址兼容
-	ipStmt, err := tx.Prepare("UPDATE devices SET region = ? WHERE ip = ?")
-	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("准备IP更新语句失败: %w", err)
-	}
-	defer ipStmt.Close()
-
+	ids := make([]string, 0, len(deviceIDs))
 	for _, id := range deviceIDs {
-		if isIPAddress(id) {
-			// 如果是IP地址，使用IP进行更新
-			_, err := ipStmt.Exec(region, id)
-			if err != nil {
-				tx.Rollback()
-				return fmt.Errorf("使用IP更新区域失败: %w", err)
-			}
-		} else {
-			// 否则使用ID更新
-			_, err := stmt.Exec(region, id)
-			if err != nil {
-				tx.Rollback()
-				return fmt.Errorf("更新区域失败: %w", err)
-			}
+		if !isIPAddress(id) {
+			ids = append(ids, id)
+			continue
 		}
-	}
-
-	// 提交事务
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("提交事务失败: %w", err)
-	}
-
-	// 更新过滤后的设备列表
-	if s.currentRegion != "" {
-		// 重新获取所有设备
-		allDevices := s.getAllDevicesFromDB()
 
-		// 过滤设备
-		newFiltered := make([]models.Device, 0, len(allDevices))
-		for _, device := range allDevices {
-			if device.Region == s.currentRegion || device.Region == "" {
-				newFiltered = append(newFiltered, device)
-			}
+		// 用于旧版本的IP地址兼容，将IP解析为设备ID
+		devices, err := s.repo.Find(models.DeviceFilter{IP: id})
+		if err != nil {
+			return fmt.Errorf("使用IP查询设备失败: %w", err)
+		}
+		for _, device := range devices {
+			ids = append(ids, device.ID)
 		}
-		s.filteredDevices = newFiltered
 	}
 
-	return nil
+	return s.repo.SetRegion(ids, region)
 }
 
 // isIPAddress 简单检查字符串是否看起来像IP地址
@@ -615,25 +424,16 @@ func isIPAddress(s string) bool {
 	return ipPattern.MatchString(s)
 }
 
-// ClearDevices 清空设备列表
+// ClearDevices 清空设备列表，设备移入回收站，操作前自动创建数据库快照
 func (s *Service) ClearDevices() error {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
-	// 从数据库删除所有设备
-	_, err := s.db.Exec("DELETE FROM devices")
-	if err != nil {
-		return fmt.Errorf("清空设备列表失败: %w", err)
+	if _, err := s.snapshotBeforeBulkOperation("clear"); err != nil {
+		return err
 	}
-
-	// 清空过滤后的设备列表
-	s.filteredDevices = []models.Device{}
-
-	return nil
+	return s.repo.DeleteAll()
 }
 
 // ScanIPRange delegates to the Scanner implementation to scan an IP range for devices.
-// It enhances the result by setting device IDs and updating the device cache.
+// It enhances the result by setting device IDs and saving the devices in one transaction.
 func (s *Service) ScanIPRange(ctx context.Context, startIP, endIP string) []models.Device {
 	devices := s.Scanner.ScanIPRange(ctx, startIP, endIP)
 
@@ -647,88 +447,36 @@ func (s *Service) ScanIPRange(ctx context.Context, startIP, endIP string) []mode
 		validDevices = append(validDevices, device)
 	}
 
-	// 确保每个设备都有ID
-	for i := range validDevices {
-		if validDevices[i].ID == "" {
-			validDevices[i].ID = models.GenerateDeviceID(validDevices[i].Region, validDevices[i].IP)
-		}
-	}
-
-	// 添加或更新设备到数据库
 	for i := range validDevices {
 		deviceCopy := validDevices[i] // 创建副本以避免引用问题
 
-		// 检查设备是否已存在 - 只有当region不为空时才根据region和IP查询
-		var existingDevice models.Device
-		var exists bool
-
-		if deviceCopy.Region != "" {
-			existingDevice, exists = s.GetDeviceByRegionAndIP(deviceCopy.Region, deviceCopy.IP)
-		} else {
-			// 如果region为空，只根据IP查询
-			var rows *sql.Rows
-			rows, err := s.db.Query("SELECT id, ip, build_time, status, region FROM devices WHERE ip = ?", deviceCopy.IP)
-			if err == nil && rows.Next() {
-				err = rows.Scan(&existingDevice.ID, &existingDevice.IP, &existingDevice.BuildTime, &existingDevice.Status, &existingDevice.Region)
-				if err == nil {
-					exists = true
-				}
-				rows.Close()
-			}
-		}
-
-		if exists {
-			// 更新状态但保留其他信息
-			s.UpdateDeviceStatus(existingDevice.ID, deviceCopy.Status)
-			// 确保返回列表中包含最新状态
-			validDevices[i] = existingDevice
+		// 检查设备是否已存在 - 只有当region不为空时才根据region和IP查询，否则只根据IP查询
+		// 回收站中的设备被重新扫描到时沿用原ID并恢复
+		existing, err := s.repo.Find(models.DeviceFilter{Region: deviceCopy.Region, IP: deviceCopy.IP, IncludeDeleted: true})
+		if err == nil && len(existing) > 0 {
+			// 更新状态和buildTime但保留其他信息
+			validDevices[i] = existing[0]
 			validDevices[i].Status = deviceCopy.Status
-		} else {
-			// 添加新设备
-			added, err := s.AddDevice(deviceCopy)
-			if err != nil {
-				fmt.Printf("添加设备失败 %s: %v\n", deviceCopy.IP, err)
-			} else {
-				// 用添加后的设备替换原来的设备（可能包含数据库生成的信息）
-				validDevices[i] = added
-			}
+			validDevices[i].BuildTime = deviceCopy.BuildTime
+		} else if validDevices[i].ID == "" {
+			// 新设备，生成ID
+			validDevices[i].ID = models.GenerateDeviceID(deviceCopy.Region, deviceCopy.IP)
 		}
 	}
 
-	// 如果有区域过滤，更新过滤后的设备列表
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
-
-	if s.currentRegion != "" {
-		// 添加新扫描的设备到过滤列表
-		for _, device := range validDevices {
-			if device.Region == s.currentRegion || device.Region == "" {
-				// 检查是否已存在
-				exists := false
-				for i, filteredDevice := range s.filteredDevices {
-					if filteredDevice.IP == device.IP {
-						// 更新现有设备
-						s.filteredDevices[i] = device
-						exists = true
-						break
-					}
-				}
-
-				// 如果不存在，添加到过滤列表
-				if !exists {
-					s.filteredDevices = append(s.filteredDevices, device)
-				}
-			}
-		}
+	// 在同一事务中添加或更新设备
+	if err := s.repo.UpsertMany(validDevices); err != nil {
+		fmt.Printf("保存扫描结果失败: %v\n", err)
 	}
 
 	return validDevices
 }
-func (s *Service) UpdateDeviceStatus(id string, status string) {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
 
-	s.db.Exec("UPDATE devices SET status = ? WHERE id = ?", status, id)
+// UpdateDeviceStatus 更新设备状态
+func (s *Service) UpdateDeviceStatus(id string, status string) {
+	if err := s.repo.UpdateStatus(id, status); err != nil {
+		fmt.Printf("更新设备 %s 状态失败: %v\n", id, err)
+	}
 }
 
 // TestDevice delegates to the Scanner implementation to test if a device is reachable.
@@ -743,127 +491,97 @@ func (s *Service) LoginToDevice(ip, username, password string) (string, error) {
 
 // Close 关闭服务并释放资源
 func (s *Service) Close() error {
-	if s.db != nil {
-		return s.db.Close()
+	s.closeOnce.Do(func() {
+		close(s.stopWatch)
+		<-s.watchDone
+	})
+	if s.repo != nil {
+		return s.repo.Close()
 	}
 	return nil
 }
 
 // GetRegions 获取所有区域
 func (s *Service) GetRegions() ([]string, error) {
-	s.mutex.RLock()
-	defer s.mutex.RUnlock()
+	return s.repo.Regions()
+}
 
-	rows, err := s.db.Query("SELECT DISTINCT region FROM devices WHERE region != ''")
+// UpdateDevicesFromFile 将本地更新文件上传到设备并验证新版本，未指定设备ID时更新当前过滤条件下的所有设备。
+// 离线设备不参与更新，在结果中记为跳过。
+func (s *Service) UpdateDevicesFromFile(deviceIds []string, fileName, filePath, md5FileName, md5FilePath, username, password string, options models.UpgradeOptions) ([]models.UpdateResult, error) {
+	online, offline, err := s.updateTargets(deviceIds)
 	if err != nil {
-		return nil, fmt.Errorf("查询区域失败: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
-
-	regions := []string{}
-	for rows.Next() {
-		var region string
-		if err := rows.Scan(&region); err != nil {
-			continue
-		}
-		if region != "" {
-			regions = append(regions, region)
-		}
+	results, err := s.updateDevices(online, fileName, filePath, md5FileName, md5FilePath, username, password, options, nil)
+	if err != nil {
+		return nil, err
 	}
-
-	return regions, nil
+	for _, device := range offline {
+		results = append(results, offlineResult(device))
+	}
+	return results, nil
 }
 
-// UpdateDevicesFile 上传更新文件到设备
-func (s *Service) UpdateDevicesFile(deviceIds []string, fileName string, fileBinary []byte, md5FileName string, md5FileBinary []byte, username, password string) ([]models.UpdateResult, error) {
-	// 创建临时文件存储二进制数据
-	tempFile, err := os.CreateTemp("", "update-*-"+fileName)
-	if err != nil {
-		return nil, fmt.Errorf("创建临时文件失败: %w", err)
+// updateTargets 获取需要更新的设备并按是否在线分开：指定了设备ID则只更新指定的设备，否则更新当前过滤条件下的所有设备
+func (s *Service) updateTargets(deviceIds []string) (online, offline []models.Device, err error) {
+	filter := s.regionFilter()
+	if len(deviceIds) > 0 {
+		filter = models.DeviceFilter{IDs: deviceIds}
 	}
-	defer os.Remove(tempFile.Name()) // 确保函数结束时删除临时文件
 
-	// 写入文件数据
-	if _, err := tempFile.Write(fileBinary); err != nil {
-		tempFile.Close()
-		return nil, fmt.Errorf("写入临时文件失败: %w", err)
+	devices, err := s.repo.Find(filter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("查询设备失败: %w", err)
 	}
-	tempFile.Close()
 
-	// 临时MD5文件路径
-	var tempMD5FilePath string
-
-	// 如果提供了MD5文件，创建临时MD5文件
-	if md5FileName != "" && len(md5FileBinary) > 0 {
-		tempMD5File, err := os.CreateTemp("", "md5-*-"+md5FileName)
-		if err != nil {
-			return nil, fmt.Errorf("创建临时MD5文件失败: %w", err)
-		}
-		defer os.Remove(tempMD5File.Name()) // 确保函数结束时删除临时文件
-
-		// 写入MD5文件数据
-		if _, err := tempMD5File.Write(md5FileBinary); err != nil {
-			tempMD5File.Close()
-			return nil, fmt.Errorf("写入临时MD5文件失败: %w", err)
+	if len(devices) == 0 {
+		return nil, nil, fmt.Errorf("没有需要更新的设备")
+	}
+	for _, device := range devices {
+		if device.Status == "online" {
+			online = append(online, device)
+		} else {
+			offline = append(offline, device)
 		}
-		tempMD5File.Close()
-
-		tempMD5FilePath = tempMD5File.Name()
 	}
+	return online, offline, nil
+}
 
-	// 获取需要更新的设备
-	s.mutex.RLock()
-	devices := make([]models.Device, 0)
-
-	// 如果指定了设备ID，则只更新指定的设备
-	if len(deviceIds) > 0 {
-		// 从数据库获取指定ID的设备
-		placeholders := make([]string, len(deviceIds))
-		args := make([]interface{}, len(deviceIds))
-		for i, id := range deviceIds {
-			placeholders[i] = "?"
-			args[i] = id
-		}
+// updateDevices 并发更新设备，每台设备完成后在调用方的goroutine中依次调用onResult(可为nil)
+func (s *Service) updateDevices(devices []models.Device, fileName, filePath, md5FileName, md5FilePath, username, password string, options models.UpgradeOptions, onResult func(device models.Device, result models.UpdateResult)) ([]models.UpdateResult, error) {
+	// 上传前校验文件完整性，缺少MD5文件时自动生成
+	checked, err := checkUpdateFile(fileName, filePath, md5FileName, md5FilePath, options)
+	if err != nil {
+		return nil, err
+	}
+	defer checked.cleanup()
 
-		// 构建查询
-		query := fmt.Sprintf("SELECT id, ip, build_time, status, region FROM devices WHERE id IN (%s) AND status = 'online'",
-			strings.Join(placeholders, ","))
+	type deviceResult struct {
+		device models.Device
+		result models.UpdateResult
+	}
 
-		rows, err := s.db.Query(query, args...)
-		if err != nil {
-			s.mutex.RUnlock()
-			return nil, fmt.Errorf("查询设备失败: %w", err)
-		}
-		defer rows.Close()
+	results := make([]models.UpdateResult, 0, len(devices))
+	resultChan := make(chan deviceResult, len(devices))
 
-		// 读取符合条件的设备
-		for rows.Next() {
-			var device models.Device
-			if err := rows.Scan(&device.ID, &device.IP, &device.BuildTime, &device.Status, &device.Region); err != nil {
-				continue
-			}
-			devices = append(devices, device)
-		}
-	} else {
-		// 如果未指定设备ID，则更新当前过滤条件下的所有在线设备
-		for _, device := range s.filteredDevices {
-			if device.Status == "online" {
-				devices = append(devices, device)
+	// 维护模式中的设备不参与批量更新
+	pending := make([]models.Device, 0, len(devices))
+	for _, device := range devices {
+		if device.Maintenance {
+			result := models.UpdateResult{IP: device.IP, Success: false, Skipped: true, Message: MaintenanceMessage(device.MaintenanceReason)}
+			results = append(results, result)
+			if onResult != nil {
+				onResult(device, result)
 			}
+			continue
 		}
+		pending = append(pending, device)
 	}
-	s.mutex.RUnlock()
+	devices = pending
 
-	if len(devices) == 0 {
-		return nil, fmt.Errorf("没有需要更新的设备")
-	}
-
-	results := make([]models.UpdateResult, 0, len(devices))
-	resultChan := make(chan models.UpdateResult, len(devices))
-
-	// 限制并发数量为8
-	maxConcurrent := 8
-	semaphore := make(chan struct{}, maxConcurrent)
+	// 并发数由传输设置决定，自适应模式下会根据上传情况调整
+	slots := s.transfers.NewConcurrency()
 	var wg sync.WaitGroup
 
 	// 为每个设备启动一个goroutine执行更新
@@ -871,24 +589,7 @@ func (s *Service) UpdateDevicesFile(deviceIds []string, fileName string, fileBin
 		wg.Add(1)
 		go func(device models.Device) {
 			defer wg.Done()
-
-			// 占用信号量
-			semaphore <- struct{}{}
-			defer func() {
-				// 释放信号量
-				<-semaphore
-			}()
-
-			result, err := s.uploadUpdateFile(device.IP, fileName, md5FileName, tempFile.Name(), tempMD5FilePath, username, password)
-			if err != nil {
-				resultChan <- models.UpdateResult{
-					IP:      device.IP,
-					Success: false,
-					Message: err.Error(),
-				}
-				return
-			}
-			resultChan <- result
+			resultChan <- deviceResult{device, s.updateDevice(device, fileName, filePath, checked, username, password, options, slots)}
 		}(device)
 	}
 
@@ -899,82 +600,161 @@ func (s *Service) UpdateDevicesFile(deviceIds []string, fileName string, fileBin
 	}()
 
 	// 收集结果
-	for result := range resultChan {
-		results = append(results, result)
+	for item := range resultChan {
+		results = append(results, item.result)
+		if onResult != nil {
+			onResult(item.device, item.result)
+		}
 	}
 
 	return results, nil
 }
 
-// uploadUpdateFile 上传更新文件到单个设备
-func (s *Service) uploadUpdateFile(ip string, fileName, md5FileName, filePath string, md5FilePath string, username, password string) (models.UpdateResult, error) {
-	result := models.UpdateResult{
-		IP:      ip,
-		Success: false,
-		Message: "",
+// updateDevice 通过HTTP或SSH将更新文件传到单台设备并验证，每个步骤按各自的重试策略重试暂时性故障。
+// 上传完成前占用一个并发名额，等待设备重启和验证时不占用。
+func (s *Service) updateDevice(device models.Device, fileName, filePath string, checked checkedUpdate, username, password string, options models.UpgradeOptions, slots *transfer.Concurrency) models.UpdateResult {
+	ip := device.IP
+	slots.Acquire()
+	uploadErr, uploadStart, latency, released := error(nil), time.Time{}, time.Duration(0), false
+	releaseSlot := func() {
+		if !released {
+			released = true
+			elapsed := time.Duration(0)
+			if !uploadStart.IsZero() {
+				elapsed = time.Since(uploadStart)
+			}
+			slots.Release(uploadErr, checked.size, elapsed, latency)
+		}
 	}
+	defer releaseSlot()
 
-	// 首先登录获取token
-	token, err := s.Auth.LoginToDevice(ip, username, password)
+	// 同一设备上的其他操作(如备份)进行中时立即失败
+	release, err := lock.Default().TryAcquire(ip, lock.OperationUpgrade)
 	if err != nil {
-		return result, fmt.Errorf("登录设备失败: %w", err)
+		return models.UpdateResult{IP: ip, Success: false, Message: err.Error()}
 	}
+	defer release()
 
-	fmt.Println("token", token)
-	// 打开文件
-	file, err := os.Open(filePath)
-	if err != nil {
-		return result, fmt.Errorf("无法打开文件: %w", err)
+	// 记录更新前的版本，用于验证设备是否真正完成更新。请求的往返时间反映其他上传占用链路的程度
+	probeStart := time.Now()
+	result := models.UpdateResult{IP: ip, BuildBefore: s.readBuildTime(ip)}
+	if result.BuildBefore != "" {
+		latency = time.Since(probeStart)
+	}
+	if options.SkipCurrent && sameBuild(result.BuildBefore, options.ExpectedBuild) {
+		result.Skipped = true
+		result.Message = "设备已运行目标版本 " + result.BuildBefore
+		return result
+	}
+	if options.MinBuild != "" && compareBuilds(result.BuildBefore, options.MinBuild) == models.BuildOlder {
+		result.Skipped = true
+		result.Message = fmt.Sprintf("设备版本 %s 低于更新包要求的最低版本 %s", result.BuildBefore, options.MinBuild)
+		return result
+	}
+	fail := func(err error) models.UpdateResult {
+		result.Success = false
+		result.Message = err.Error()
+		result.Retryable = isRetryable(err)
+		return result
 	}
-	defer file.Close()
 
-	// 创建multipart表单
-	var requestBody bytes.Buffer
-	writer := multipart.NewWriter(&requestBody)
+	if options.SnapshotBeforeUpload {
+		if err := s.snapshotDevice(ip, result.BuildBefore, options); err != nil {
+			return fail(err)
+		}
+	}
 
-	// 添加file字段
-	fileWriter, err := writer.CreateFormFile("binary", fileName)
-	if err != nil {
-		return result, fmt.Errorf("创建表单文件字段失败: %w", err)
+	// 按任务选择的方式上传，HTTP升级失败时可以自动改用SSH
+	uploadStart = time.Now()
+	fallbackNote := ""
+	if options.Transport == models.TransportSSH {
+		uploadErr = s.upgradeOverSSH(&result, device, filePath, checked, options)
+	} else {
+		uploadErr = s.upgradeOverHTTP(&result, device, fileName, filePath, checked, username, password, options)
+		if uploadErr != nil && options.SSHFallback && options.SSHUsername != "" {
+			fmt.Printf("设备 %s HTTP升级失败，改用SSH升级: %v\n", ip, uploadErr)
+			fallbackNote = "HTTP升级失败(" + uploadErr.Error() + ")，已改用SSH升级"
+			uploadErr = s.upgradeOverSSH(&result, device, filePath, checked, options)
+		}
 	}
-	_, err = io.Copy(fileWriter, file)
-	if err != nil {
-		return result, fmt.Errorf("复制文件到表单失败: %w", err)
+	releaseSlot()
+	if uploadErr != nil {
+		if fallbackNote != "" {
+			return fail(fmt.Errorf("%s，SSH升级也失败: %w", fallbackNote, uploadErr))
+		}
+		return fail(uploadErr)
 	}
+	result.Success = true
 
-	// 如果提供了MD5文件，添加到表单
-	if md5FilePath != "" {
-		// 打开MD5文件
-		md5File, err := os.Open(md5FilePath)
-		if err != nil {
-			return result, fmt.Errorf("无法打开MD5文件: %w", err)
+	// SSH升级已在替换前校验过MD5
+	checksumNote := ""
+	if result.Transport == models.TransportHTTP {
+		if checksumNote, err = verifyRemoteChecksum(&result, checked.md5, options); err != nil {
+			return fail(err)
 		}
-		defer md5File.Close()
+	}
 
-		// 添加md5字段
-		md5Writer, err := writer.CreateFormFile("md5file", md5FileName)
-		if err != nil {
-			return result, fmt.Errorf("创建MD5表单字段失败: %w", err)
+	if options.SkipVerify {
+		result.Message = "更新文件已上传(未验证)"
+	} else {
+		s.verifyWithRetry(&result, withDefaults(options.VerifyRetry, defaultVerifyRetry), options)
+	}
+	for _, note := range []string{fallbackNote, checksumNote} {
+		if note != "" {
+			result.Message += "；" + note
 		}
-		_, err = io.Copy(md5Writer, md5File)
-		if err != nil {
-			return result, fmt.Errorf("复制MD5文件到表单失败: %w", err)
+	}
+	return result
+}
+
+// verifyWithRetry 验证更新结果，设备在超时前未恢复在线时按策略等待后重新轮询
+func (s *Service) verifyWithRetry(result *models.UpdateResult, policy models.RetryPolicy, options models.UpgradeOptions) {
+	s.verifyUpgrade(result, options)
+	result.Attempts++
+	for attempt := 1; attempt < policy.MaxAttempts && result.Outcome == models.OutcomeUnreachable; attempt++ {
+		delay := backoff(policy, attempt)
+		fmt.Printf("设备 %s 未恢复在线，%v后重新验证\n", result.IP, delay)
+		time.Sleep(delay)
+		s.pollUpgrade(result, options)
+		result.Attempts++
+	}
+	result.Retryable = result.Outcome == models.OutcomeUnreachable
+}
+
+// uploadUpdateFile 使用已登录的token上传更新文件到单个设备，设备是否运行新版本由验证阶段确认
+func (s *Service) uploadUpdateFile(device models.Device, token string, fileName, md5FileName, filePath string, md5FilePath string) error {
+	parts := []uploadPart{{field: "binary", fileName: fileName, path: filePath}}
+	if md5FilePath != "" {
+		parts = append(parts, uploadPart{field: "md5file", fileName: md5FileName, path: md5FilePath})
+	}
+	return s.uploadParts(device, token, parts)
+}
+
+// uploadParts 将文件作为表单字段上传到设备的/api/system/upgrade接口
+func (s *Service) uploadParts(device models.Device, token string, parts []uploadPart) error {
+	ip := device.IP
+	// 从磁盘流式上传，避免每台设备都在内存中构造完整的请求体
+	var onProgress func(sent, total int64)
+	if handler := s.progressHandler(); handler != nil {
+		onProgress = func(sent, total int64) {
+			handler(models.UploadProgress{IP: ip, BytesSent: sent, TotalBytes: total})
 		}
 	}
 
-	// 关闭writer
-	err = writer.Close()
+	body, contentType, contentLength, err := newMultipartBody(parts, onProgress)
 	if err != nil {
-		return result, fmt.Errorf("关闭表单writer失败: %w", err)
+		return err
 	}
+	defer body.Close()
 
-	// 创建请求
+	// 创建请求，上传速度受全局和区域带宽上限限制
 	url := fmt.Sprintf("http://%s:8089/api/system/upgrade", ip)
-	req, err := http.NewRequest("POST", url, &requestBody)
+	req, err := http.NewRequest("POST", url, s.transfers.LimitReader(device.Region, body))
 	if err != nil {
-		return result, fmt.Errorf("创建请求失败: %w", err)
+		return fmt.Errorf("创建请求失败: %w", err)
 	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.ContentLength = contentLength
+	req.Header.Set("Content-Type", contentType)
 
 	// 添加token到请求头
 	req.Header.Set("Token", token)
@@ -985,19 +765,19 @@ func (s *Service) uploadUpdateFile(ip string, fileName, md5FileName, filePath st
 	}
 	resp, err := client.Do(req)
 	if err != nil {
-		return result, fmt.Errorf("发送请求失败: %w", err)
+		return fmt.Errorf("发送请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// 读取响应
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return result, fmt.Errorf("读取响应失败: %w", err)
+		return fmt.Errorf("读取响应失败: %w", err)
 	}
 
 	// 检查响应状态
 	if resp.StatusCode != http.StatusOK {
-		return result, fmt.Errorf("更新失败，状态码: %d, 响应: %s", resp.StatusCode, string(respBody))
+		return &statusError{code: resp.StatusCode, body: string(respBody)}
 	}
 
 	// 尝试解析JSON响应
@@ -1007,12 +787,9 @@ func (s *Service) uploadUpdateFile(ip string, fileName, md5FileName, filePath st
 	}
 	if err := json.Unmarshal(respBody, &jsonResp); err == nil {
 		if jsonResp.Status != 0 {
-			return result, fmt.Errorf("更新失败: %s", jsonResp.Message)
+			return fmt.Errorf("更新失败: %s", jsonResp.Message)
 		}
 	}
 
-	// 更新成功
-	result.Success = true
-	result.Message = "更新成功"
-	return result, nil
+	return nil
 }
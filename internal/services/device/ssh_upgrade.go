@@ -0,0 +1,86 @@
+package device
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"application-updater/internal/models"
+	"application-updater/internal/utils"
+)
+
+// swapCommand 生成替换程序文件并重启服务的命令。临时文件与程序在同一目录，mv是原子操作，
+// 服务不会读到写了一半的程序
+func swapCommand(settings models.DeviceSettings, staging string) string {
+	return fmt.Sprintf("set -e; chmod 0755 %[1]s; mv -f %[1]s %[2]s; systemctl restart %[3]s",
+		utils.EscapeShellArg(staging), utils.EscapeShellArg(settings.BinaryPath), utils.EscapeShellArg(settings.ServiceName))
+}
+
+// upgradeOverHTTP 登录设备并通过/api/system/upgrade上传更新文件，每个步骤按各自的重试策略重试
+func (s *Service) upgradeOverHTTP(result *models.UpdateResult, device models.Device, fileName, filePath string, checked checkedUpdate, username, password string, options models.UpgradeOptions) error {
+	result.Transport = models.TransportHTTP
+
+	var token string
+	attempts, err := retryStep("登录", device.IP, withDefaults(options.LoginRetry, defaultLoginRetry), func() error {
+		var loginErr error
+		token, loginErr = s.Auth.LoginToDevice(device.IP, username, password)
+		return loginErr
+	})
+	result.Attempts += attempts
+	if err != nil {
+		return fmt.Errorf("登录设备失败: %w", err)
+	}
+
+	attempts, err = retryStep("上传", device.IP, withDefaults(options.UploadRetry, defaultUploadRetry), func() error {
+		return s.uploadUpdateFile(device, token, fileName, checked.md5FileName, filePath, checked.md5FilePath)
+	})
+	result.Attempts += attempts
+	return err
+}
+
+// upgradeOverSSH 通过SSH将程序文件复制到设备上程序所在目录，校验MD5后原子替换并重启服务，
+// 用于web服务异常、无法通过接口升级的设备
+func (s *Service) upgradeOverSSH(result *models.UpdateResult, device models.Device, filePath string, checked checkedUpdate, options models.UpgradeOptions) error {
+	result.Transport = models.TransportSSH
+	if options.SSHUsername == "" {
+		return fmt.Errorf("SSH升级需要SSH账号")
+	}
+
+	settings := s.GetSettings()
+	staging := settings.BinaryPath + ".upload"
+	attempts, err := retryStep("SSH复制", device.IP, withDefaults(options.UploadRetry, defaultUploadRetry), func() error {
+		client, err := utils.CreateSSHClient(device.IP, options.SSHUsername, options.SSHPassword, 22)
+		if err != nil {
+			return fmt.Errorf("SSH连接失败: %w", err)
+		}
+		defer client.Close()
+
+		return utils.SCPFileToRemote(client, filePath, staging, func(r io.Reader) io.Reader {
+			return s.transfers.LimitReader(device.Region, r)
+		})
+	})
+	result.Attempts += attempts
+	if err != nil {
+		return fmt.Errorf("通过SSH复制程序文件失败: %w", err)
+	}
+
+	output, err := runSSH(device.IP, options.SSHUsername, options.SSHPassword, "md5sum "+utils.EscapeShellArg(staging))
+	if err != nil {
+		return err
+	}
+	remote, err := parseMD5File([]byte(output))
+	if err != nil {
+		return fmt.Errorf("md5sum输出无法解析: %s", strings.TrimSpace(output))
+	}
+	if remote != checked.md5 {
+		runSSH(device.IP, options.SSHUsername, options.SSHPassword, "rm -f "+utils.EscapeShellArg(staging))
+		return fmt.Errorf("设备上的文件MD5不一致(预期 %s，实际 %s)，已取消替换", checked.md5, remote)
+	}
+	result.ChecksumVerified = true
+
+	if _, err := runSSH(device.IP, options.SSHUsername, options.SSHPassword, swapCommand(settings, staging)); err != nil {
+		return fmt.Errorf("替换程序并重启服务失败: %w", err)
+	}
+	fmt.Printf("已通过SSH替换设备 %s 上的程序并重启 %s\n", device.IP, settings.ServiceName)
+	return nil
+}
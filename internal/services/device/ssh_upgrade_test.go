@@ -0,0 +1,51 @@
+package device
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"application-updater/internal/models"
+)
+
+func TestSwapCommandIsAtomic(t *testing.T) {
+	settings := models.DeviceSettings{BinaryPath: "/opt/app web/application-web", ServiceName: "application-web"}
+	command := swapCommand(settings, settings.BinaryPath+".upload")
+	want := "chmod 0755 '/opt/app web/application-web.upload'; mv -f '/opt/app web/application-web.upload' '/opt/app web/application-web'; systemctl restart 'application-web'"
+	if !strings.Contains(command, want) {
+		t.Errorf("Expected the staged binary to be moved into place before the restart, got %q", command)
+	}
+}
+
+func TestTransportSelection(t *testing.T) {
+	retryUnit = time.Millisecond
+	defer func() { retryUnit = time.Second }()
+
+	service := NewServiceWithRepository(t.TempDir(), NewMemoryRepository())
+	filePath := filepath.Join(t.TempDir(), "application-web")
+	os.WriteFile(filePath, []byte("binary"), 0644)
+	devices := []models.Device{{ID: "d1", IP: "127.0.0.1", Status: "online"}}
+	once := models.RetryPolicy{MaxAttempts: 1}
+
+	// 选择SSH时不再尝试HTTP接口
+	results, err := service.updateDevices(devices, "application-web", filePath, "", "", "admin", "admin",
+		models.UpgradeOptions{Transport: models.TransportSSH, UploadRetry: once}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Success || results[0].Transport != models.TransportSSH || !strings.Contains(results[0].Message, "SSH账号") {
+		t.Errorf("Expected an SSH upgrade without account to fail, got %+v", results[0])
+	}
+
+	// 没有SSH账号时HTTP失败不会回退
+	results, err = service.updateDevices(devices, "application-web", filePath, "", "", "admin", "admin",
+		models.UpgradeOptions{SSHFallback: true, LoginRetry: once}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Success || results[0].Transport != models.TransportHTTP {
+		t.Errorf("Expected the HTTP failure to be reported without fallback, got %+v", results[0])
+	}
+}
@@ -0,0 +1,126 @@
+package device
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"application-updater/internal/models"
+)
+
+// buildTimeLayouts 设备可能返回的buildTime格式
+var buildTimeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+	"2006/01/02 15:04:05",
+	"20060102150405",
+	"20060102_150405",
+	"20060102-150405",
+	"200601021504",
+	"Jan _2 2006 15:04:05",
+	"Mon Jan _2 15:04:05 MST 2006",
+	"Mon Jan _2 15:04:05 2006",
+	"2006-01-02",
+	"20060102",
+}
+
+// parseBuildTime 解析buildTime，无法识别的格式返回false
+func parseBuildTime(build string) (time.Time, bool) {
+	build = strings.TrimSpace(build)
+	for _, layout := range buildTimeLayouts {
+		if t, err := time.ParseInLocation(layout, build, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// compareBuilds 比较两个buildTime：可以解析时按时间比较，否则只能判断是否相同
+func compareBuilds(build, reference string) string {
+	if strings.TrimSpace(build) == strings.TrimSpace(reference) {
+		return models.BuildEqual
+	}
+
+	buildAt, ok1 := parseBuildTime(build)
+	referenceAt, ok2 := parseBuildTime(reference)
+	switch {
+	case !ok1 || !ok2:
+		return models.BuildUnknown
+	case buildAt.Before(referenceAt):
+		return models.BuildOlder
+	case buildAt.After(referenceAt):
+		return models.BuildNewer
+	default:
+		return models.BuildEqual
+	}
+}
+
+// sameBuild 判断两个buildTime是否表示同一版本
+func sameBuild(build, reference string) bool {
+	return build != "" && reference != "" && compareBuilds(build, reference) == models.BuildEqual
+}
+
+// PreviewBuildTarget 按buildTime比较结果列出会被更新的设备，已运行目标版本的设备会被跳过
+func (s *Service) PreviewBuildTarget(target models.BuildTarget) (models.BuildTargetPreview, error) {
+	switch target.Mode {
+	case models.BuildOlder, models.BuildEqual, models.BuildDifferent:
+	default:
+		return models.BuildTargetPreview{}, fmt.Errorf("不支持的比较方式: %s", target.Mode)
+	}
+	if strings.TrimSpace(target.Build) == "" {
+		return models.BuildTargetPreview{}, fmt.Errorf("请指定用于比较的buildTime")
+	}
+	if target.Mode == models.BuildOlder {
+		if _, ok := parseBuildTime(target.Build); !ok {
+			return models.BuildTargetPreview{}, fmt.Errorf("无法解析buildTime: %s", target.Build)
+		}
+	}
+
+	targetBuild := target.TargetBuild
+	if targetBuild == "" && target.Mode != models.BuildEqual {
+		targetBuild = target.Build
+	}
+
+	devices, err := s.repo.Find(models.DeviceFilter{Region: target.Region, Tags: target.Tags})
+	if err != nil {
+		return models.BuildTargetPreview{}, fmt.Errorf("查询设备失败: %w", err)
+	}
+
+	preview := models.BuildTargetPreview{Devices: make([]models.BuildTargetDevice, 0, len(devices))}
+	for _, device := range devices {
+		entry := models.BuildTargetDevice{Device: device, Comparison: compareBuilds(device.BuildTime, target.Build)}
+		if entry.Comparison != models.BuildEqual && target.Mode == models.BuildDifferent {
+			entry.Comparison = models.BuildDifferent
+		}
+
+		switch {
+		case entry.Comparison == models.BuildUnknown:
+			entry.Reason = "无法解析buildTime"
+		case entry.Comparison != target.Mode:
+			entry.Reason = "版本不符合条件"
+		case sameBuild(device.BuildTime, targetBuild):
+			entry.Reason = "已运行目标版本"
+		case device.Status != "online":
+			entry.Reason = "设备离线"
+		case device.Maintenance:
+			entry.Reason = MaintenanceMessage(device.MaintenanceReason)
+		default:
+			entry.Selected = true
+			preview.Selected++
+		}
+		preview.Devices = append(preview.Devices, entry)
+	}
+	return preview, nil
+}
+
+// SelectedDeviceIDs 返回预览中会被更新的设备ID
+func SelectedDeviceIDs(preview models.BuildTargetPreview) []string {
+	ids := []string{}
+	for _, entry := range preview.Devices {
+		if entry.Selected {
+			ids = append(ids, entry.Device.ID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,40 @@
+package device
+
+import (
+	"testing"
+
+	"application-updater/internal/models"
+)
+
+func TestPreviewBuildTarget(t *testing.T) {
+	repo := NewMemoryRepository()
+	for _, device := range []models.Device{
+		{ID: "old", IP: "10.0.0.1", BuildTime: "2024-01-01 10:00:00", Status: "online", Region: "A"},
+		{ID: "current", IP: "10.0.0.2", BuildTime: "2024-03-01 10:00:00", Status: "online", Region: "A"},
+		{ID: "offline", IP: "10.0.0.3", BuildTime: "2023-12-01 10:00:00", Status: "offline", Region: "A"},
+		{ID: "garbled", IP: "10.0.0.4", BuildTime: "v1.2", Status: "online", Region: "A"},
+		{ID: "other-region", IP: "10.0.0.5", BuildTime: "2024-01-01 10:00:00", Status: "online", Region: "B"},
+	} {
+		if err := repo.Upsert(device); err != nil {
+			t.Fatal(err)
+		}
+	}
+	service := NewServiceWithRepository(t.TempDir(), repo)
+
+	preview, err := service.PreviewBuildTarget(models.BuildTarget{Mode: models.BuildOlder, Build: "20240301100000", Region: "A"})
+	if err != nil {
+		t.Fatalf("PreviewBuildTarget failed: %v", err)
+	}
+	if ids := SelectedDeviceIDs(preview); len(ids) != 1 || ids[0] != "old" {
+		t.Errorf("Expected only the older online device to be selected, got %v", ids)
+	}
+	if len(preview.Devices) != 4 {
+		t.Errorf("Expected the preview to be scoped to region A, got %d devices", len(preview.Devices))
+	}
+
+	// different模式下无法解析的版本也会被选中，已运行目标版本的设备被跳过
+	preview, _ = service.PreviewBuildTarget(models.BuildTarget{Mode: models.BuildDifferent, Build: "2024-03-01 10:00:00", Region: "A"})
+	if ids := SelectedDeviceIDs(preview); len(ids) != 2 {
+		t.Errorf("Expected old and garbled devices to be selected, got %v", ids)
+	}
+}
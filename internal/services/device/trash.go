@@ -0,0 +1,134 @@
+package device
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"application-updater/internal/models"
+	"application-updater/internal/utils"
+)
+
+const (
+	// defaultTrashRetentionDays 回收站默认保留天数
+	defaultTrashRetentionDays = 30
+	// defaultSnapshotKeep 默认保留的快照数量
+	defaultSnapshotKeep = 20
+)
+
+// settingsPath 设备管理设置文件路径
+func (s *Service) settingsPath() string {
+	return filepath.Join(s.configDir, "device_settings.json")
+}
+
+// snapshotDir 数据库快照目录
+func (s *Service) snapshotDir() string {
+	return filepath.Join(s.configDir, "snapshots")
+}
+
+// GetSettings 获取设备管理设置，文件不存在时返回默认值
+func (s *Service) GetSettings() models.DeviceSettings {
+	settings := models.DeviceSettings{}
+	if utils.FileExists(s.settingsPath()) {
+		if err := utils.LoadConfig(s.settingsPath(), &settings); err != nil {
+			fmt.Printf("读取设备管理设置失败: %v, 使用默认值\n", err)
+		}
+	}
+
+	if settings.TrashRetentionDays <= 0 {
+		settings.TrashRetentionDays = defaultTrashRetentionDays
+	}
+	if settings.SnapshotKeep <= 0 {
+		settings.SnapshotKeep = defaultSnapshotKeep
+	}
+	applyRollbackDefaults(&settings)
+	return settings
+}
+
+// SaveSettings 保存设备管理设置
+func (s *Service) SaveSettings(settings models.DeviceSettings) error {
+	if settings.TrashRetentionDays <= 0 {
+		return fmt.Errorf("回收站保留天数必须大于0")
+	}
+	if settings.SnapshotKeep <= 0 {
+		return fmt.Errorf("快照保留数量必须大于0")
+	}
+	return utils.SaveConfig(s.settingsPath(), settings)
+}
+
+// GetDeletedDevices 获取回收站中的设备，超过保留期的设备先被清除
+func (s *Service) GetDeletedDevices() []models.Device {
+	if _, err := s.PurgeExpiredDevices(); err != nil {
+		fmt.Printf("清除过期的回收站设备失败: %v\n", err)
+	}
+	return s.findDevices(models.DeviceFilter{OnlyDeleted: true})
+}
+
+// RestoreDevices 从回收站恢复设备，超过保留期的设备已被清除，不能恢复
+func (s *Service) RestoreDevices(ids []string) error {
+	if _, err := s.PurgeExpiredDevices(); err != nil {
+		return err
+	}
+	return s.repo.Restore(ids)
+}
+
+// PurgeDeletedDevices 立即清空回收站，操作前自动创建数据库快照
+func (s *Service) PurgeDeletedDevices() (int, error) {
+	if _, err := s.snapshotBeforeBulkOperation("purge"); err != nil {
+		return 0, err
+	}
+	return s.repo.Purge(time.Now())
+}
+
+// PurgeExpiredDevices 永久删除超过保留期的回收站设备
+func (s *Service) PurgeExpiredDevices() (int, error) {
+	retention := time.Duration(s.GetSettings().TrashRetentionDays) * 24 * time.Hour
+	purged, err := s.repo.Purge(time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	if purged > 0 {
+		fmt.Printf("已永久删除 %d 个超过保留期的回收站设备\n", purged)
+	}
+	return purged, nil
+}
+
+// Snapshot 将当前设备数据库(包括回收站)的一致副本写入指定文件
+func (s *Service) Snapshot(path string) error {
+	return s.repo.Snapshot(path)
+}
+
+// snapshotBeforeBulkOperation 在批量破坏性操作前创建数据库快照，返回快照路径
+func (s *Service) snapshotBeforeBulkOperation(operation string) (string, error) {
+	if err := utils.EnsureDirExists(s.snapshotDir()); err != nil {
+		return "", fmt.Errorf("创建快照目录失败: %w", err)
+	}
+
+	name := fmt.Sprintf("devices_%s_%s.db", time.Now().Format("20060102_150405"), operation)
+	path := filepath.Join(s.snapshotDir(), name)
+	if err := s.repo.Snapshot(path); err != nil {
+		return "", fmt.Errorf("操作前创建快照失败，已取消操作: %w", err)
+	}
+	fmt.Printf("已在%s操作前创建数据库快照: %s\n", operation, path)
+
+	s.pruneSnapshots(s.GetSettings().SnapshotKeep)
+	return path, nil
+}
+
+// pruneSnapshots 只保留最近的keep个快照
+func (s *Service) pruneSnapshots(keep int) {
+	snapshots, err := utils.ListFilesInDirectory(s.snapshotDir(), "devices_*.db")
+	if err != nil || len(snapshots) <= keep {
+		return
+	}
+
+	// 文件名以时间戳开头，按名称排序即按时间排序
+	sort.Strings(snapshots)
+	for _, path := range snapshots[:len(snapshots)-keep] {
+		if err := os.Remove(path); err != nil {
+			fmt.Printf("删除旧快照 %s 失败: %v\n", path, err)
+		}
+	}
+}
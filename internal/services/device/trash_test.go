@@ -0,0 +1,41 @@
+package device
+
+import (
+	"testing"
+	"time"
+
+	"application-updater/internal/models"
+)
+
+func TestExpiredDevicesLeaveTheTrash(t *testing.T) {
+	repo := NewMemoryRepository()
+	service := NewServiceWithRepository(t.TempDir(), repo)
+	defer service.Close()
+
+	for _, id := range []string{"old", "recent"} {
+		if err := repo.Upsert(models.Device{ID: id, IP: "10.0.0." + id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := service.RemoveDevices([]string{"old", "recent"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// old是超过默认保留期之前放入回收站的
+	repo.mutex.Lock()
+	device := repo.devices["old"]
+	device.DeletedAt = time.Now().UTC().AddDate(0, 0, -defaultTrashRetentionDays-1).Format(deletedAtLayout)
+	repo.devices["old"] = device
+	repo.mutex.Unlock()
+
+	deleted := service.GetDeletedDevices()
+	if len(deleted) != 1 || deleted[0].ID != "recent" {
+		t.Fatalf("Expected only the recent device in the trash, got %+v", deleted)
+	}
+	if err := service.RestoreDevices([]string{"old"}); err == nil {
+		t.Errorf("Expected the purged device not to be restorable")
+	}
+	if err := service.RestoreDevices([]string{"recent"}); err != nil {
+		t.Errorf("Expected the recent device to be restored, got %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package device
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+	"time"
+
+	"application-updater/internal/models"
+)
+
+// progressInterval 上传进度回调的最小间隔
+const progressInterval = 200 * time.Millisecond
+
+// uploadPart multipart表单中的一个文件字段
+type uploadPart struct {
+	field    string
+	fileName string
+	path     string
+	size     int64
+}
+
+// SetUploadProgressHandler 设置上传进度回调，每台设备上传时按固定间隔报告已发送的字节数
+func (s *Service) SetUploadProgressHandler(handler func(progress models.UploadProgress)) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.uploadProgress = handler
+}
+
+// progressHandler 返回当前的上传进度回调，未设置时返回nil
+func (s *Service) progressHandler() func(progress models.UploadProgress) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.uploadProgress
+}
+
+// newMultipartBody 创建从磁盘流式读取文件的multipart请求体，返回请求体、Content-Type和总长度。
+// 文件内容不会整体读入内存，调用方必须关闭返回的请求体。
+func newMultipartBody(parts []uploadPart, onProgress func(sent, total int64)) (io.ReadCloser, string, int64, error) {
+	for i := range parts {
+		info, err := os.Stat(parts[i].path)
+		if err != nil {
+			return nil, "", 0, fmt.Errorf("无法读取文件 %s: %w", parts[i].fileName, err)
+		}
+		parts[i].size = info.Size()
+	}
+
+	// 用相同的boundary先写出不含文件内容的表单，计算Content-Length
+	var skeleton bytes.Buffer
+	sizer := multipart.NewWriter(&skeleton)
+	total := int64(0)
+	for _, part := range parts {
+		if _, err := sizer.CreateFormFile(part.field, part.fileName); err != nil {
+			return nil, "", 0, fmt.Errorf("创建表单字段失败: %w", err)
+		}
+		total += part.size
+	}
+	if err := sizer.Close(); err != nil {
+		return nil, "", 0, fmt.Errorf("关闭表单writer失败: %w", err)
+	}
+	total += int64(skeleton.Len())
+
+	reader, pipe := io.Pipe()
+	counter := &progressWriter{w: pipe, total: total, onProgress: onProgress}
+	writer := multipart.NewWriter(counter)
+	if err := writer.SetBoundary(sizer.Boundary()); err != nil {
+		return nil, "", 0, err
+	}
+
+	go func() {
+		err := writeMultipart(writer, parts)
+		counter.report(true)
+		pipe.CloseWithError(err)
+	}()
+
+	return reader, writer.FormDataContentType(), total, nil
+}
+
+// writeMultipart 依次将文件写入multipart表单
+func writeMultipart(writer *multipart.Writer, parts []uploadPart) error {
+	for _, part := range parts {
+		fieldWriter, err := writer.CreateFormFile(part.field, part.fileName)
+		if err != nil {
+			return fmt.Errorf("创建表单字段失败: %w", err)
+		}
+
+		file, err := os.Open(part.path)
+		if err != nil {
+			return fmt.Errorf("无法打开文件 %s: %w", part.fileName, err)
+		}
+		written, err := io.Copy(fieldWriter, file)
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("复制文件 %s 到表单失败: %w", part.fileName, err)
+		}
+		if written != part.size {
+			return fmt.Errorf("文件 %s 在上传过程中被修改", part.fileName)
+		}
+	}
+	return writer.Close()
+}
+
+// progressWriter 统计写入的字节数并按间隔报告进度
+type progressWriter struct {
+	w          io.Writer
+	sent       int64
+	total      int64
+	lastReport time.Time
+	onProgress func(sent, total int64)
+}
+
+func (p *progressWriter) Write(data []byte) (int, error) {
+	n, err := p.w.Write(data)
+	p.sent += int64(n)
+	p.report(false)
+	return n, err
+}
+
+// report 调用进度回调，force为true时忽略间隔限制
+func (p *progressWriter) report(force bool) {
+	if p.onProgress == nil || (!force && time.Since(p.lastReport) < progressInterval) {
+		return
+	}
+	p.lastReport = time.Now()
+	p.onProgress(p.sent, p.total)
+}
+
+// GetTransferSettings 获取带宽和并发设置
+func (s *Service) GetTransferSettings() models.TransferSettings {
+	return s.transfers.Settings()
+}
+
+// SaveTransferSettings 保存带宽和并发设置，进行中的传输立即按新的带宽上限执行
+func (s *Service) SaveTransferSettings(settings models.TransferSettings) error {
+	return s.transfers.SaveSettings(settings)
+}
+
+// LimitReader 按设备所在区域和全局带宽上限限制读取速度，用于向设备传输文件
+func (s *Service) LimitReader(ip string, r io.Reader) io.Reader {
+	region := ""
+	if devices := s.findDevices(models.DeviceFilter{IP: ip}); len(devices) > 0 {
+		region = devices[0].Region
+	}
+	return s.transfers.LimitReader(region, r)
+}
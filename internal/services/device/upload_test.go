@@ -0,0 +1,48 @@
+package device
+
+import (
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMultipartBodyStreamsWithContentLength(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "app.bin")
+	md5File := filepath.Join(dir, "app.md5")
+	os.WriteFile(binary, []byte(strings.Repeat("x", 100000)), 0644)
+	os.WriteFile(md5File, []byte("md5"), 0644)
+
+	var lastSent, lastTotal int64
+	body, contentType, length, err := newMultipartBody([]uploadPart{
+		{field: "binary", fileName: "app.bin", path: binary},
+		{field: "md5file", fileName: "app.md5", path: md5File},
+	}, func(sent, total int64) { lastSent, lastTotal = sent, total })
+	if err != nil {
+		t.Fatalf("newMultipartBody failed: %v", err)
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("Reading body failed: %v", err)
+	}
+	if int64(len(data)) != length {
+		t.Errorf("Expected Content-Length %d to match body size %d", length, len(data))
+	}
+	if lastSent != length || lastTotal != length {
+		t.Errorf("Expected final progress %d/%d, got %d/%d", length, length, lastSent, lastTotal)
+	}
+
+	boundary := strings.TrimPrefix(contentType, "multipart/form-data; boundary=")
+	form, err := multipart.NewReader(strings.NewReader(string(data)), boundary).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatalf("Parsing body failed: %v", err)
+	}
+	if len(form.File["binary"]) != 1 || form.File["binary"][0].Size != 100000 || len(form.File["md5file"]) != 1 {
+		t.Errorf("Unexpected form fields: %+v", form.File)
+	}
+}
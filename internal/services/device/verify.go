@@ -0,0 +1,115 @@
+package device
+
+import (
+	"fmt"
+	"time"
+
+	"application-updater/internal/models"
+)
+
+const defaultVerifyTimeout = 5 * time.Minute
+
+// 默认的重启等待时间和轮询buildTime的间隔，测试中会缩短
+var (
+	defaultRestartWait = 10 * time.Second
+	verifyPollInterval = 5 * time.Second
+)
+
+// readBuildTime 读取设备当前的buildTime，设备不可达时返回空字符串
+func (s *Service) readBuildTime(ip string) string {
+	device, err := s.Scanner.TestDevice(ip)
+	if err != nil {
+		return ""
+	}
+	return device.BuildTime
+}
+
+// verifyUpgrade 等待设备重启后轮询/api/buildTime，将结果记录为upgraded、unchanged、unexpected_build或unreachable
+func (s *Service) verifyUpgrade(result *models.UpdateResult, options models.UpgradeOptions) {
+	restartWait := defaultRestartWait
+	if options.RestartWaitSeconds > 0 {
+		restartWait = time.Duration(options.RestartWaitSeconds) * time.Second
+	}
+
+	fmt.Printf("等待设备 %s 重启，%v后开始验证\n", result.IP, restartWait)
+	time.Sleep(restartWait)
+
+	s.pollUpgrade(result, options)
+}
+
+// pollUpgrade 轮询设备的buildTime直到达到目标版本或超时，并同步设备状态
+func (s *Service) pollUpgrade(result *models.UpdateResult, options models.UpgradeOptions) {
+	timeout := defaultVerifyTimeout
+	if options.VerifyTimeoutSeconds > 0 {
+		timeout = time.Duration(options.VerifyTimeoutSeconds) * time.Second
+	}
+
+	// 设备重启前可能仍返回旧版本，因此一直轮询到达到目标版本或超时
+	reachable := false
+	deadline := time.Now().Add(timeout)
+	for {
+		if build := s.readBuildTime(result.IP); build != "" {
+			reachable = true
+			result.BuildAfter = build
+			if isTargetBuild(build, result.BuildBefore, options.ExpectedBuild) {
+				break
+			}
+		}
+		if time.Now().Add(verifyPollInterval).After(deadline) {
+			break
+		}
+		time.Sleep(verifyPollInterval)
+	}
+
+	switch {
+	case !reachable:
+		result.Outcome = models.OutcomeUnreachable
+		result.Success = false
+		result.Message = fmt.Sprintf("上传成功，但设备在 %v 内未恢复在线", timeout)
+	case isTargetBuild(result.BuildAfter, result.BuildBefore, options.ExpectedBuild):
+		result.Outcome = models.OutcomeUpgraded
+		result.Success = true
+		result.Message = fmt.Sprintf("更新成功，buildTime: %s -> %s", displayBuild(result.BuildBefore), result.BuildAfter)
+	case options.ExpectedBuild != "" && result.BuildAfter != result.BuildBefore:
+		result.Outcome = models.OutcomeUnexpectedBuild
+		result.Success = false
+		result.Message = fmt.Sprintf("设备运行的buildTime %s 与预期 %s 不符", result.BuildAfter, options.ExpectedBuild)
+	default:
+		result.Outcome = models.OutcomeUnchanged
+		result.Success = false
+		result.Message = fmt.Sprintf("上传成功，但设备仍运行旧版本 %s", result.BuildAfter)
+	}
+
+	// 验证过程中已确认设备是否在线，同步到设备列表
+	status := "offline"
+	if reachable {
+		status = "online"
+	}
+	for _, device := range s.findDevices(models.DeviceFilter{IP: result.IP}) {
+		device.Status = status
+		if reachable {
+			device.BuildTime = result.BuildAfter
+		}
+		if err := s.repo.Upsert(device); err != nil {
+			fmt.Printf("更新设备 %s 信息失败: %v\n", result.IP, err)
+		}
+	}
+}
+
+// isTargetBuild 判断设备是否已运行目标版本：指定了预期版本时必须一致，否则只要求与更新前不同
+func isTargetBuild(build, before, expected string) bool {
+	if build == "" {
+		return false
+	}
+	if expected != "" {
+		return build == expected
+	}
+	return build != before
+}
+
+func displayBuild(build string) string {
+	if build == "" {
+		return "未知"
+	}
+	return build
+}
@@ -0,0 +1,66 @@
+package device
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"application-updater/internal/models"
+)
+
+// scriptedScanner 按顺序返回预设的buildTime，空字符串表示设备不可达
+type scriptedScanner struct {
+	builds []string
+}
+
+func (s *scriptedScanner) ScanIPRange(ctx context.Context, startIP, endIP string) []models.Device {
+	return nil
+}
+
+func (s *scriptedScanner) TestDevice(ip string) (*models.Device, error) {
+	build := ""
+	if len(s.builds) > 0 {
+		build, s.builds = s.builds[0], s.builds[1:]
+	}
+	if build == "" {
+		return nil, fmt.Errorf("unreachable")
+	}
+	return &models.Device{IP: ip, BuildTime: build, Status: "online"}, nil
+}
+
+func TestVerifyUpgrade(t *testing.T) {
+	defaultRestartWait, verifyPollInterval = 0, time.Millisecond
+	options := models.UpgradeOptions{VerifyTimeoutSeconds: 1}
+
+	cases := []struct {
+		name     string
+		builds   []string
+		expected string
+		outcome  string
+	}{
+		{"restarts with new build", []string{"old", "", "", "new"}, "", models.OutcomeUpgraded},
+		{"reaches expected build", []string{"old", "new"}, "new", models.OutcomeUpgraded},
+		{"wrong build", []string{"other"}, "new", models.OutcomeUnexpectedBuild},
+		{"still old build", []string{"old"}, "new", models.OutcomeUnchanged},
+		{"never comes back", nil, "", models.OutcomeUnreachable},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			service := NewServiceWithRepository(t.TempDir(), NewMemoryRepository())
+			service.Scanner = &scriptedScanner{builds: c.builds}
+
+			result := models.UpdateResult{IP: "10.0.0.1", Success: true, BuildBefore: "old"}
+			options.ExpectedBuild = c.expected
+			service.verifyUpgrade(&result, options)
+
+			if result.Outcome != c.outcome {
+				t.Errorf("Expected outcome %s, got %s (%s)", c.outcome, result.Outcome, result.Message)
+			}
+			if result.Success != (c.outcome == models.OutcomeUpgraded) {
+				t.Errorf("Expected success only for upgraded devices, got %v", result.Success)
+			}
+		})
+	}
+}
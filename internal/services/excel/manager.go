@@ -13,7 +13,7 @@ type Manager interface {
 	SaveExcelData(fileData string) (string, error)
 
 	// ProcessExcelData processes Excel data rows for camera configuration
-	ProcessExcelData(rows []models.ExcelRow, username, password, urlTemplate string, algorithmType int, region string) []models.CameraConfigResult
+	ProcessExcelData(rows []models.ExcelRow, username, password, urlTemplate string, algorithms models.CameraAlgorithms, region string) []models.CameraConfigResult
 
 	// CleanupTempFiles cleans up temporary Excel files
 	CleanupTempFiles() error
@@ -19,7 +19,7 @@ type Service struct {
 
 // CameraService defines the interface for camera configuration operations
 type CameraService interface {
-	ConfigureCamerasFromData(deviceConfigs []models.ExcelRow, username, password, urlTemplate string, algorithmType int, region string) []models.CameraConfigResult
+	ConfigureCamerasFromData(deviceConfigs []models.ExcelRow, username, password, urlTemplate string, algorithms models.CameraAlgorithms, region string) []models.CameraConfigResult
 }
 
 // NewService creates a new Excel service
@@ -87,8 +87,8 @@ func (s *Service) SaveExcelData(fileData string) (string, error) {
 }
 
 // ProcessExcelData processes Excel data rows for camera configuration
-func (s *Service) ProcessExcelData(rows []models.ExcelRow, username, password, urlTemplate string, algorithmType int, region string) []models.CameraConfigResult {
-	return s.cameraService.ConfigureCamerasFromData(rows, username, password, urlTemplate, algorithmType, region)
+func (s *Service) ProcessExcelData(rows []models.ExcelRow, username, password, urlTemplate string, algorithms models.CameraAlgorithms, region string) []models.CameraConfigResult {
+	return s.cameraService.ConfigureCamerasFromData(rows, username, password, urlTemplate, algorithms, region)
 }
 
 // CleanupTempFiles cleans up temporary Excel files
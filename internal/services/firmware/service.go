@@ -0,0 +1,380 @@
+package firmware
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"application-updater/internal/models"
+	"application-updater/internal/services/signing"
+	"application-updater/internal/utils"
+)
+
+const (
+	metadataFile = "package.json"
+	timeLayout   = "2006-01-02 15:04:05"
+
+	// DefaultUnusedDays is how long an unpinned package may stay unused before garbage collection removes it
+	DefaultUnusedDays = 90
+)
+
+// Service stores update packages under the config directory, one directory per SHA-256
+type Service struct {
+	mutex sync.Mutex
+	dir   string
+}
+
+// NewService opens the package repository in dir
+func NewService(dir string) *Service {
+	if err := utils.EnsureDirExists(dir); err != nil {
+		fmt.Printf("Failed to create package repository: %v\n", err)
+	}
+	return &Service{dir: dir}
+}
+
+// packageDir returns the directory holding a package
+func (s *Service) packageDir(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+// load reads the metadata of a package
+func (s *Service) load(id string) (models.FirmwarePackage, error) {
+	var pkg models.FirmwarePackage
+	if err := utils.LoadConfig(filepath.Join(s.packageDir(id), metadataFile), &pkg); err != nil {
+		return models.FirmwarePackage{}, fmt.Errorf("package %s not found", id)
+	}
+	return pkg, nil
+}
+
+// save writes the metadata of a package
+func (s *Service) save(pkg models.FirmwarePackage) error {
+	return utils.SaveConfig(filepath.Join(s.packageDir(pkg.ID), metadataFile), pkg)
+}
+
+// List returns all packages, newest first
+func (s *Service) List() []models.FirmwarePackage {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		fmt.Printf("Failed to read package repository: %v\n", err)
+		return []models.FirmwarePackage{}
+	}
+
+	packages := []models.FirmwarePackage{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if pkg, err := s.load(entry.Name()); err == nil {
+			packages = append(packages, pkg)
+		}
+	}
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].UploadedAt > packages[j].UploadedAt
+	})
+	return packages
+}
+
+// Get returns a package by ID
+func (s *Service) Get(id string) (models.FirmwarePackage, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.load(id)
+}
+
+// Paths returns the local paths of a package's binary and MD5 file; the MD5 path is empty if none was imported
+func (s *Service) Paths(pkg models.FirmwarePackage) (filePath, md5FilePath string) {
+	dir := s.packageDir(pkg.ID)
+	filePath = filepath.Join(dir, pkg.FileName)
+	if pkg.MD5FileName != "" {
+		md5FilePath = filepath.Join(dir, pkg.MD5FileName)
+	}
+	return filePath, md5FilePath
+}
+
+// Import copies a binary and optional MD5 file into the repository.
+// If a package with the same content already exists it is returned unchanged and created is false.
+func (s *Service) Import(filePath, md5FilePath, notes, buildTime string) (pkg models.FirmwarePackage, created bool, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// Copy into a staging directory first so the package ID can be derived from the content
+	staging, err := os.MkdirTemp(s.dir, ".import-*")
+	if err != nil {
+		return models.FirmwarePackage{}, false, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	fileName := filepath.Base(filePath)
+	size, sha, md5sum, err := copyWithChecksums(filePath, filepath.Join(staging, fileName))
+	if err != nil {
+		return models.FirmwarePackage{}, false, err
+	}
+	if size == 0 {
+		return models.FirmwarePackage{}, false, fmt.Errorf("update file %s is empty", fileName)
+	}
+
+	if existing, err := s.load(sha); err == nil {
+		fmt.Printf("Package %s already exists as %s\n", fileName, existing.ID)
+		return existing, false, nil
+	}
+
+	pkg = models.FirmwarePackage{
+		ID:         sha,
+		FileName:   fileName,
+		Size:       size,
+		SHA256:     sha,
+		MD5:        md5sum,
+		BuildTime:  buildTime,
+		Notes:      notes,
+		UploadedAt: time.Now().Format(timeLayout),
+	}
+
+	if md5FilePath != "" {
+		pkg.MD5FileName = filepath.Base(md5FilePath)
+		if pkg.MD5FileName == pkg.FileName || pkg.MD5FileName == metadataFile {
+			return models.FirmwarePackage{}, false, fmt.Errorf("invalid MD5 file name: %s", pkg.MD5FileName)
+		}
+		if err := utils.CopyFile(md5FilePath, filepath.Join(staging, pkg.MD5FileName)); err != nil {
+			return models.FirmwarePackage{}, false, fmt.Errorf("failed to copy MD5 file: %w", err)
+		}
+	}
+
+	// Package files are read-only, only the metadata may change later
+	for _, name := range []string{pkg.FileName, pkg.MD5FileName} {
+		if name != "" {
+			os.Chmod(filepath.Join(staging, name), 0444)
+		}
+	}
+
+	if err := os.Rename(staging, s.packageDir(pkg.ID)); err != nil {
+		return models.FirmwarePackage{}, false, fmt.Errorf("failed to store package: %w", err)
+	}
+	if err := s.save(pkg); err != nil {
+		return models.FirmwarePackage{}, false, err
+	}
+
+	fmt.Printf("Imported package %s (%s, %d bytes)\n", pkg.FileName, pkg.ID, pkg.Size)
+	return pkg, true, nil
+}
+
+// ImportBundle imports the binary of a signed package together with its manifest and signature.
+// A package whose binary does not match its manifest is refused; whether the signer is trusted is
+// checked when the package is deployed, so keys can be added later.
+func (s *Service) ImportBundle(bundlePath string) (models.FirmwarePackage, bool, error) {
+	staging, err := os.MkdirTemp("", "package-bundle-*")
+	if err != nil {
+		return models.FirmwarePackage{}, false, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	bundle, err := signing.OpenBundle(bundlePath, staging)
+	if err != nil {
+		return models.FirmwarePackage{}, false, err
+	}
+
+	pkg, created, err := s.Import(bundle.FilePath, bundle.MD5FilePath, bundle.Manifest.ReleaseNotes, bundle.Manifest.TargetBuild)
+	if err != nil {
+		return models.FirmwarePackage{}, false, err
+	}
+
+	// A binary imported earlier without signature becomes signed
+	manifest := bundle.Manifest
+	pkg, err = s.update(pkg.ID, func(pkg *models.FirmwarePackage) {
+		pkg.Manifest = &manifest
+		pkg.Signature = bundle.Signature
+		if pkg.BuildTime == "" {
+			pkg.BuildTime = manifest.TargetBuild
+		}
+	})
+	return pkg, created, err
+}
+
+// CheckDeployable verifies a package's signature against the trusted keys. A package without a valid
+// signature may only be deployed when an override was recorded; overridden reports that it was needed.
+func CheckDeployable(pkg models.FirmwarePackage, keys []models.TrustedKey) (overridden bool, err error) {
+	_, err = signing.VerifyManifest(pkg.Manifest, pkg.Signature, keys)
+	if err == nil && pkg.Manifest.SHA256 != pkg.SHA256 {
+		err = signing.ErrTampered
+	}
+	if err == nil {
+		return false, nil
+	}
+	if pkg.Override != nil {
+		return true, nil
+	}
+	return false, &RefusedError{Target: pkg.FileName, Err: err}
+}
+
+// RefusedError reports why a package or bundle may not be deployed without an override.
+// The message is shown to the operator; Err keeps the signing error for errors.Is.
+type RefusedError struct {
+	Target string
+	Err    error
+}
+
+func (e *RefusedError) Error() string {
+	var reason string
+	switch {
+	case errors.Is(e.Err, signing.ErrUnsigned):
+		reason = "未签名"
+	case errors.Is(e.Err, signing.ErrUntrusted):
+		reason = "签名公钥不在受信任列表中"
+	case errors.Is(e.Err, signing.ErrTampered):
+		reason = "签名与内容不符，可能已被篡改"
+	default:
+		reason = "签名校验失败: " + e.Err.Error()
+	}
+	return fmt.Sprintf("拒绝部署 %s：%s", e.Target, reason)
+}
+
+func (e *RefusedError) Unwrap() error {
+	return e.Err
+}
+
+// copyWithChecksums copies src to dst and returns its size, SHA-256 and MD5
+func copyWithChecksums(src, dst string) (int64, string, string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, "", "", fmt.Errorf("failed to open update file: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return 0, "", "", fmt.Errorf("failed to create package file: %w", err)
+	}
+	defer out.Close()
+
+	shaHash, md5Hash := sha256.New(), md5.New()
+	size, err := io.Copy(io.MultiWriter(out, shaHash, md5Hash), in)
+	if err != nil {
+		return 0, "", "", fmt.Errorf("failed to copy update file: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return 0, "", "", fmt.Errorf("failed to write package file: %w", err)
+	}
+
+	return size, hex.EncodeToString(shaHash.Sum(nil)), hex.EncodeToString(md5Hash.Sum(nil)), nil
+}
+
+// update applies a metadata change to a package
+func (s *Service) update(id string, apply func(pkg *models.FirmwarePackage)) (models.FirmwarePackage, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	pkg, err := s.load(id)
+	if err != nil {
+		return models.FirmwarePackage{}, err
+	}
+	apply(&pkg)
+	return pkg, s.save(pkg)
+}
+
+// SetNotes replaces the notes of a package
+func (s *Service) SetNotes(id, notes string) (models.FirmwarePackage, error) {
+	return s.update(id, func(pkg *models.FirmwarePackage) {
+		pkg.Notes = notes
+	})
+}
+
+// SetPinned protects a package from garbage collection
+func (s *Service) SetPinned(id string, pinned bool) (models.FirmwarePackage, error) {
+	return s.update(id, func(pkg *models.FirmwarePackage) {
+		pkg.Pinned = pinned
+	})
+}
+
+// SetOverride approves a package without a valid signature for deployment
+func (s *Service) SetOverride(id, reason string) (models.FirmwarePackage, error) {
+	return s.update(id, func(pkg *models.FirmwarePackage) {
+		pkg.Override = &models.PackageOverride{Reason: reason, ApprovedAt: time.Now().Format(timeLayout)}
+	})
+}
+
+// MarkUsed records that a package was used for an update
+func (s *Service) MarkUsed(id string) {
+	if _, err := s.update(id, func(pkg *models.FirmwarePackage) {
+		pkg.LastUsedAt = time.Now().Format(timeLayout)
+	}); err != nil {
+		fmt.Printf("Failed to mark package %s as used: %v\n", id, err)
+	}
+}
+
+// Delete removes a package from the repository
+func (s *Service) Delete(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, err := s.load(id); err != nil {
+		return err
+	}
+	return removePackageDir(s.packageDir(id))
+}
+
+// removePackageDir deletes a package directory, restoring write permission on read-only files first
+func removePackageDir(dir string) error {
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		os.Chmod(filepath.Join(dir, entry.Name()), 0644)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to delete package: %w", err)
+	}
+	return nil
+}
+
+// referencedIDs resolves references to package IDs, by ID or by a file path inside a package directory
+func (s *Service) referencedIDs(inUse []models.PackageReference) map[string]bool {
+	ids := make(map[string]bool, len(inUse))
+	for _, ref := range inUse {
+		if ref.PackageID != "" {
+			ids[ref.PackageID] = true
+		}
+		if ref.FilePath == "" {
+			continue
+		}
+		if rel, err := filepath.Rel(s.dir, filepath.Dir(ref.FilePath)); err == nil && rel != "." && rel != ".." && filepath.Dir(rel) == "." {
+			ids[rel] = true
+		}
+	}
+	return ids
+}
+
+// CollectGarbage removes unpinned packages that have not been used for unusedDays.
+// Packages still needed by the schedules, update jobs or rollouts in inUse are kept.
+func (s *Service) CollectGarbage(unusedDays int, inUse []models.PackageReference) ([]models.FirmwarePackage, error) {
+	if unusedDays <= 0 {
+		unusedDays = DefaultUnusedDays
+	}
+	cutoff := time.Now().AddDate(0, 0, -unusedDays).Format(timeLayout)
+	referenced := s.referencedIDs(inUse)
+
+	removed := []models.FirmwarePackage{}
+	for _, pkg := range s.List() {
+		lastUsed := pkg.LastUsedAt
+		if lastUsed == "" {
+			lastUsed = pkg.UploadedAt
+		}
+		if pkg.Pinned || lastUsed > cutoff || referenced[pkg.ID] {
+			continue
+		}
+
+		if err := s.Delete(pkg.ID); err != nil {
+			return removed, err
+		}
+		removed = append(removed, pkg)
+		fmt.Printf("Removed unused package %s (%s)\n", pkg.FileName, pkg.ID)
+	}
+	return removed, nil
+}
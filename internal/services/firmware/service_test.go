@@ -0,0 +1,144 @@
+package firmware
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"application-updater/internal/models"
+	"application-updater/internal/services/signing"
+	"application-updater/internal/utils"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestImportDeduplicatesByHash(t *testing.T) {
+	s := NewService(t.TempDir())
+
+	pkg, created, err := s.Import(writeFile(t, "app.bin", "binary"), writeFile(t, "app.md5", "md5"), "first", "20240101")
+	if err != nil || !created {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if pkg.Size != 6 || pkg.MD5 != "9d7183f16acce70658f686ae7f1a4d20" {
+		t.Errorf("Unexpected package metadata: %+v", pkg)
+	}
+	filePath, md5FilePath := s.Paths(pkg)
+	if content, _ := os.ReadFile(filePath); string(content) != "binary" {
+		t.Errorf("Expected stored binary, got %q", content)
+	}
+	if _, err := os.Stat(md5FilePath); err != nil {
+		t.Errorf("Expected stored MD5 file: %v", err)
+	}
+
+	again, created, err := s.Import(writeFile(t, "renamed.bin", "binary"), "", "second", "")
+	if err != nil || created || again.ID != pkg.ID || again.Notes != "first" {
+		t.Errorf("Expected identical content to return the existing package, got %+v created=%v err=%v", again, created, err)
+	}
+	if len(s.List()) != 1 {
+		t.Errorf("Expected 1 package, got %d", len(s.List()))
+	}
+}
+
+func TestCollectGarbageKeepsPinnedAndRecent(t *testing.T) {
+	s := NewService(t.TempDir())
+	old, _, _ := s.Import(writeFile(t, "old.bin", "old"), "", "", "")
+	pinned, _, _ := s.Import(writeFile(t, "pinned.bin", "pinned"), "", "", "")
+	recent, _, _ := s.Import(writeFile(t, "recent.bin", "recent"), "", "", "")
+
+	for _, id := range []string{old.ID, pinned.ID} {
+		s.update(id, func(pkg *models.FirmwarePackage) { pkg.UploadedAt = "2000-01-01 00:00:00" })
+	}
+	s.SetPinned(pinned.ID, true)
+
+	removed, err := s.CollectGarbage(30, nil)
+	if err != nil {
+		t.Fatalf("CollectGarbage failed: %v", err)
+	}
+	if len(removed) != 1 || removed[0].ID != old.ID {
+		t.Fatalf("Expected only the old package to be removed, got %+v", removed)
+	}
+	if _, err := s.Get(recent.ID); err != nil {
+		t.Errorf("Expected recent package to be kept: %v", err)
+	}
+}
+
+func TestCollectGarbageKeepsReferencedPackages(t *testing.T) {
+	s := NewService(t.TempDir())
+	byID, _, _ := s.Import(writeFile(t, "scheduled.bin", "scheduled"), "", "", "")
+	byPath, _, _ := s.Import(writeFile(t, "deferred.bin", "deferred"), "", "", "")
+	unused, _, _ := s.Import(writeFile(t, "unused.bin", "unused"), "", "", "")
+	for _, id := range []string{byID.ID, byPath.ID, unused.ID} {
+		s.update(id, func(pkg *models.FirmwarePackage) { pkg.UploadedAt = "2000-01-01 00:00:00" })
+	}
+
+	filePath, _ := s.Paths(byPath)
+	inUse := []models.PackageReference{
+		{PackageID: byID.ID},
+		{FilePath: filePath},
+		{FilePath: writeFile(t, "elsewhere.bin", "elsewhere")},
+	}
+	removed, err := s.CollectGarbage(1, inUse)
+	if err != nil {
+		t.Fatalf("CollectGarbage failed: %v", err)
+	}
+	if len(removed) != 1 || removed[0].ID != unused.ID {
+		t.Fatalf("Expected only the unreferenced package to be removed, got %+v", removed)
+	}
+	for _, pkg := range []models.FirmwarePackage{byID, byPath} {
+		if path, _ := s.Paths(pkg); !utils.FileExists(path) {
+			t.Errorf("Expected referenced package %s to be kept", pkg.FileName)
+		}
+	}
+}
+
+func TestCheckDeployableRequiresTrustedSignature(t *testing.T) {
+	publicKey, privateKey, err := signing.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _ := signing.ParsePublicKey(publicKey)
+	keys := []models.TrustedKey{{ID: signing.KeyID(parsed), PublicKey: publicKey}}
+
+	s := NewService(t.TempDir())
+	bundle := filepath.Join(t.TempDir(), "app"+signing.BundleExtension)
+	if _, err := signing.WriteBundle(bundle, writeFile(t, "app.bin", "binary"), models.PackageManifest{TargetBuild: "20240101"}, privateKey); err != nil {
+		t.Fatal(err)
+	}
+	signed, _, err := s.ImportBundle(bundle)
+	if err != nil {
+		t.Fatalf("ImportBundle failed: %v", err)
+	}
+	if signed.BuildTime != "20240101" || signed.Manifest == nil {
+		t.Errorf("Expected manifest to be attached, got %+v", signed)
+	}
+	if overridden, err := CheckDeployable(signed, keys); err != nil || overridden {
+		t.Errorf("Expected signed package to be deployable, got overridden=%v err=%v", overridden, err)
+	}
+
+	unsigned, _, err := s.Import(writeFile(t, "other.bin", "other"), "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CheckDeployable(unsigned, keys); !errors.Is(err, signing.ErrUnsigned) || err.Error() != "拒绝部署 other.bin：未签名" {
+		t.Errorf("Expected unsigned package to be refused, got %v", err)
+	}
+	if _, err := CheckDeployable(signed, nil); !errors.Is(err, signing.ErrUntrusted) {
+		t.Errorf("Expected package signed by an unknown key to be refused, got %v", err)
+	}
+
+	unsigned, err = s.SetOverride(unsigned.ID, "hotfix from vendor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if overridden, err := CheckDeployable(unsigned, keys); err != nil || !overridden {
+		t.Errorf("Expected override to allow deployment, got overridden=%v err=%v", overridden, err)
+	}
+}
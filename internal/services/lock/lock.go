@@ -0,0 +1,127 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"application-updater/internal/models"
+)
+
+// Operations that take a device lock
+const (
+	OperationUpgrade      = "upgrade"
+	OperationRollback     = "rollback"
+	OperationBackup       = "backup"
+	OperationRestore      = "restore"
+	OperationTimeSync     = "time sync"
+	OperationCameraConfig = "camera config"
+)
+
+// BusyError is returned when a device is locked by another operation
+type BusyError struct {
+	IP        string
+	Operation string
+	Since     time.Time
+}
+
+func (e *BusyError) Error() string {
+	return fmt.Sprintf("device %s is busy: %s in progress since %s", e.IP, e.Operation, e.Since.Format("15:04:05"))
+}
+
+// holder describes the operation currently holding a device lock
+type holder struct {
+	operation string
+	since     time.Time
+	released  chan struct{}
+}
+
+// Manager serializes operations per device so that, for example, a backup
+// cannot stop application-web while an upgrade is uploading to the same box
+type Manager struct {
+	mutex   sync.Mutex
+	holders map[string]*holder
+}
+
+// NewManager creates an empty lock manager
+func NewManager() *Manager {
+	return &Manager{holders: make(map[string]*holder)}
+}
+
+// defaultManager is shared by all services since devices are a process-wide resource
+var defaultManager = NewManager()
+
+// Default returns the lock manager shared by all services
+func Default() *Manager {
+	return defaultManager
+}
+
+// TryAcquire locks a device for an operation, failing fast with a BusyError if it is already locked
+func (m *Manager) TryAcquire(ip, operation string) (func(), error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if h, ok := m.holders[ip]; ok {
+		return nil, &BusyError{IP: ip, Operation: h.operation, Since: h.since}
+	}
+	return m.lockLocked(ip, operation), nil
+}
+
+// Acquire locks a device for an operation, queueing behind the current holder until ctx is done
+func (m *Manager) Acquire(ctx context.Context, ip, operation string) (func(), error) {
+	for {
+		m.mutex.Lock()
+		h, ok := m.holders[ip]
+		if !ok {
+			release := m.lockLocked(ip, operation)
+			m.mutex.Unlock()
+			return release, nil
+		}
+		m.mutex.Unlock()
+
+		select {
+		case <-h.released:
+		case <-ctx.Done():
+			return nil, &BusyError{IP: ip, Operation: h.operation, Since: h.since}
+		}
+	}
+}
+
+// lockLocked records a new holder, the caller must hold m.mutex
+func (m *Manager) lockLocked(ip, operation string) func() {
+	h := &holder{operation: operation, since: time.Now(), released: make(chan struct{})}
+	m.holders[ip] = h
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.mutex.Lock()
+			if m.holders[ip] == h {
+				delete(m.holders, ip)
+			}
+			m.mutex.Unlock()
+			close(h.released)
+		})
+	}
+}
+
+// List returns the devices currently locked, sorted by IP
+func (m *Manager) List() []models.DeviceLock {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	locks := make([]models.DeviceLock, 0, len(m.holders))
+	for ip, h := range m.holders {
+		locks = append(locks, models.DeviceLock{
+			IP:        ip,
+			Operation: h.operation,
+			Since:     h.since.Format("2006-01-02 15:04:05"),
+		})
+	}
+	sort.Slice(locks, func(i, j int) bool {
+		return locks[i].IP < locks[j].IP
+	})
+	return locks
+}
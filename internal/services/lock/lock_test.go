@@ -0,0 +1,61 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTryAcquireFailsFast(t *testing.T) {
+	m := NewManager()
+
+	release, err := m.TryAcquire("10.0.0.1", OperationUpgrade)
+	if err != nil {
+		t.Fatalf("TryAcquire failed: %v", err)
+	}
+
+	_, err = m.TryAcquire("10.0.0.1", OperationBackup)
+	var busy *BusyError
+	if !errors.As(err, &busy) || busy.Operation != OperationUpgrade {
+		t.Fatalf("Expected BusyError naming the upgrade, got %v", err)
+	}
+
+	if _, err := m.TryAcquire("10.0.0.2", OperationBackup); err != nil {
+		t.Errorf("Expected other devices to stay available, got %v", err)
+	}
+
+	release()
+	release() // releasing twice is harmless
+	if _, err := m.TryAcquire("10.0.0.1", OperationBackup); err != nil {
+		t.Errorf("Expected device to be available after release, got %v", err)
+	}
+}
+
+func TestAcquireQueues(t *testing.T) {
+	m := NewManager()
+	release, _ := m.TryAcquire("10.0.0.1", OperationBackup)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		release()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	next, err := m.Acquire(ctx, "10.0.0.1", OperationUpgrade)
+	if err != nil {
+		t.Fatalf("Expected queued Acquire to succeed after release, got %v", err)
+	}
+	if locks := m.List(); len(locks) != 1 || locks[0].Operation != OperationUpgrade {
+		t.Errorf("Expected the upgrade to hold the lock, got %v", locks)
+	}
+	next()
+
+	m.TryAcquire("10.0.0.1", OperationBackup)
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := m.Acquire(ctx, "10.0.0.1", OperationUpgrade); err == nil {
+		t.Errorf("Expected Acquire to give up when the context expires")
+	}
+}
@@ -0,0 +1,130 @@
+package rollout
+
+import (
+	"fmt"
+	"sort"
+
+	"application-updater/internal/models"
+)
+
+// buildWaves splits the plan's devices into a canary wave followed by percentage or region waves
+func buildWaves(plan models.RolloutPlan, lookup func(id string) (models.Device, error)) ([]models.RolloutWave, error) {
+	if len(plan.DeviceIDs) == 0 {
+		return nil, fmt.Errorf("rollout has no devices")
+	}
+
+	devices := make([]models.Device, 0, len(plan.DeviceIDs))
+	seen := make(map[string]bool)
+	for _, id := range plan.DeviceIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		device, err := lookup(id)
+		if err != nil {
+			return nil, fmt.Errorf("device %s: %w", id, err)
+		}
+		devices = append(devices, device)
+	}
+
+	// Canary devices come first, either chosen explicitly or the first N of the plan
+	canary := make(map[string]bool)
+	for _, id := range plan.CanaryIDs {
+		if !seen[id] {
+			return nil, fmt.Errorf("canary device %s is not part of the rollout", id)
+		}
+		canary[id] = true
+	}
+	if len(canary) == 0 {
+		for i := 0; i < plan.CanaryCount && i < len(devices); i++ {
+			canary[devices[i].ID] = true
+		}
+	}
+
+	waves := []models.RolloutWave{}
+	rest := []models.Device{}
+	canaryIDs := []string{}
+	for _, device := range devices {
+		if canary[device.ID] {
+			canaryIDs = append(canaryIDs, device.ID)
+		} else {
+			rest = append(rest, device)
+		}
+	}
+	if len(canaryIDs) > 0 {
+		waves = append(waves, newWave("金丝雀", canaryIDs))
+	}
+
+	switch plan.Strategy {
+	case models.RolloutByRegion:
+		waves = append(waves, regionWaves(rest)...)
+	case models.RolloutByPercent, "":
+		waves = append(waves, percentWaves(rest, plan.WavePercents)...)
+	default:
+		return nil, fmt.Errorf("unknown rollout strategy: %s", plan.Strategy)
+	}
+
+	return waves, nil
+}
+
+// percentWaves splits devices by the given percentages, putting any remainder in a final wave
+func percentWaves(devices []models.Device, percents []int) []models.RolloutWave {
+	waves := []models.RolloutWave{}
+	start := 0
+	for i, percent := range percents {
+		if start >= len(devices) {
+			break
+		}
+		count := (len(devices)*percent + 99) / 100
+		if count < 1 {
+			count = 1
+		}
+		end := start + count
+		if end > len(devices) {
+			end = len(devices)
+		}
+		waves = append(waves, newWave(fmt.Sprintf("第%d批 (%d%%)", i+1, percent), deviceIDs(devices[start:end])))
+		start = end
+	}
+
+	if start < len(devices) {
+		waves = append(waves, newWave(fmt.Sprintf("第%d批 (剩余)", len(waves)+1), deviceIDs(devices[start:])))
+	}
+	return waves
+}
+
+// regionWaves creates one wave per region, sorted by region name
+func regionWaves(devices []models.Device) []models.RolloutWave {
+	byRegion := make(map[string][]models.Device)
+	regions := []string{}
+	for _, device := range devices {
+		if _, ok := byRegion[device.Region]; !ok {
+			regions = append(regions, device.Region)
+		}
+		byRegion[device.Region] = append(byRegion[device.Region], device)
+	}
+	sort.Strings(regions)
+
+	waves := make([]models.RolloutWave, 0, len(regions))
+	for _, region := range regions {
+		name := region
+		if name == "" {
+			name = "未分配区域"
+		}
+		waves = append(waves, newWave(name, deviceIDs(byRegion[region])))
+	}
+	return waves
+}
+
+func newWave(name string, ids []string) models.RolloutWave {
+	return models.RolloutWave{Name: name, DeviceIDs: ids, Status: models.WavePending}
+}
+
+func deviceIDs(devices []models.Device) []string {
+	ids := make([]string, len(devices))
+	for i, device := range devices {
+		ids[i] = device.ID
+	}
+	return ids
+}
@@ -0,0 +1,242 @@
+package rollout
+
+import (
+	"fmt"
+	"time"
+
+	"application-updater/internal/models"
+)
+
+// run drives a rollout wave by wave until it is paused, aborted, completed or the service closes
+func (s *Service) run(id string, wake chan struct{}) {
+	for {
+		s.mutex.Lock()
+		rollout := s.rollouts[id]
+		if s.closing || rollout.Status != models.RolloutRunning {
+			delete(s.runners, id)
+			s.mutex.Unlock()
+			return
+		}
+
+		wave := currentWave(rollout)
+		if wave == nil {
+			rollout.Status = models.RolloutCompleted
+			rollout.Message = ""
+			s.saveLocked(rollout)
+			delete(s.runners, id)
+			s.mutex.Unlock()
+			fmt.Printf("Rollout %s completed\n", rollout.Name)
+			return
+		}
+		status := wave.Status
+		s.mutex.Unlock()
+
+		switch status {
+		case models.WavePending, models.WaveUploading:
+			s.uploadWave(id)
+		case models.WaveSoaking:
+			s.soakWave(id, wake)
+		default:
+			// A completed or failed wave should already have been advanced past
+			s.mutex.Lock()
+			rollout.CurrentWave++
+			s.saveLocked(rollout)
+			s.mutex.Unlock()
+		}
+	}
+}
+
+// uploadWave pushes the package to every device of the current wave that has not been updated yet
+func (s *Service) uploadWave(id string) {
+	s.mutex.Lock()
+	rollout := s.rollouts[id]
+	waveIndex := rollout.CurrentWave
+	wave := &rollout.Waves[waveIndex]
+
+	succeeded := make(map[string]bool)
+	for _, result := range wave.Results {
+		if result.Success {
+			succeeded[result.IP] = true
+		}
+	}
+
+	ids := []string{}
+	ips := make(map[string]string)
+	for _, deviceID := range wave.DeviceIDs {
+		device, err := s.upgrader.GetDevice(deviceID)
+		if err != nil {
+			wave.Results = mergeResults(wave.Results, models.UpdateResult{IP: deviceID, Skipped: true, Message: "设备已不存在，已跳过"})
+			continue
+		}
+		ips[deviceID] = device.IP
+		if !succeeded[device.IP] {
+			ids = append(ids, deviceID)
+		}
+	}
+
+	filePath, md5FilePath, err := s.packagePaths(rollout)
+	if err != nil {
+		// Without the package no device can be updated, let the operator decide
+		rollout.Status = models.RolloutPaused
+		rollout.Message = fmt.Sprintf("%s 无法上传，更新包 %s 已不在本地包仓库中", wave.Name, rollout.PackageID)
+		s.saveLocked(rollout)
+		s.mutex.Unlock()
+		fmt.Printf("Rollout %s paused, package %s not found: %v\n", rollout.Name, rollout.PackageID, err)
+		return
+	}
+
+	if wave.Status == models.WavePending {
+		wave.StartedAt = time.Now().Format(timeLayout)
+	}
+	wave.Status = models.WaveUploading
+	s.saveLocked(rollout)
+
+	fileName, md5FileName, username, password := rollout.FileName, rollout.MD5FileName, rollout.Username, rollout.Password
+	verify := rollout.Plan.Verify
+	s.mutex.Unlock()
+
+	fmt.Printf("Rollout %s: uploading wave %s to %d devices\n", rollout.Name, wave.Name, len(ids))
+	var results []models.UpdateResult
+	var uploadErr error
+	if len(ids) > 0 {
+		results, uploadErr = s.upgrader.UpdateDevicesFromFile(ids, fileName, filePath, md5FileName, md5FilePath, username, password, verify)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// Devices that were not attempted (for example offline) are reported as skipped
+	attempted := make(map[string]bool)
+	for _, result := range results {
+		attempted[result.IP] = true
+		wave.Results = mergeResults(wave.Results, result)
+	}
+	for _, deviceID := range ids {
+		if ip := ips[deviceID]; !attempted[ip] {
+			message := "设备离线，已跳过"
+			if uploadErr != nil {
+				message = uploadErr.Error()
+			}
+			wave.Results = mergeResults(wave.Results, models.UpdateResult{IP: ip, Skipped: true, Message: message})
+		}
+	}
+
+	if s.exceedsThreshold(rollout, wave.Results) {
+		s.failWaveLocked(rollout, wave, "上传")
+	} else {
+		wave.Status = models.WaveSoaking
+		wave.SoakUntil = time.Now().Add(time.Duration(rollout.Plan.SoakMinutes) * s.soakUnit).Format(timeLayout)
+	}
+	s.saveLocked(rollout)
+}
+
+// soakWave waits out the soak period and then checks that the updated devices came back
+func (s *Service) soakWave(id string, wake chan struct{}) {
+	s.mutex.Lock()
+	rollout := s.rollouts[id]
+	wave := &rollout.Waves[rollout.CurrentWave]
+	soakUntil, err := time.ParseInLocation(timeLayout, wave.SoakUntil, time.Local)
+	if err != nil {
+		soakUntil = time.Now()
+	}
+	s.mutex.Unlock()
+
+	if wait := time.Until(soakUntil); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-wake:
+			// Paused, aborted or closing; run re-checks the status
+			timer.Stop()
+			return
+		}
+	}
+
+	// Only the devices that took the update are expected to be healthy
+	s.mutex.Lock()
+	targets := []string{}
+	for _, result := range wave.Results {
+		if result.Success {
+			targets = append(targets, result.IP)
+		}
+	}
+	s.mutex.Unlock()
+
+	health := make([]models.UpdateResult, 0, len(targets))
+	for _, ip := range targets {
+		device, err := s.upgrader.TestDevice(ip)
+		if err != nil {
+			health = append(health, models.UpdateResult{IP: ip, Success: false, Message: fmt.Sprintf("健康检查失败: %v", err)})
+			continue
+		}
+		health = append(health, models.UpdateResult{IP: ip, Success: true, Message: "在线，buildTime: " + device.BuildTime})
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	wave.Health = health
+	if s.exceedsThreshold(rollout, health) {
+		s.failWaveLocked(rollout, wave, "健康检查")
+	} else {
+		wave.Status = models.WaveCompleted
+		wave.FinishedAt = time.Now().Format(timeLayout)
+		rollout.CurrentWave++
+	}
+	s.saveLocked(rollout)
+}
+
+// exceedsThreshold reports whether the failure rate of the attempted devices is above the plan's threshold
+func (s *Service) exceedsThreshold(rollout *models.Rollout, results []models.UpdateResult) bool {
+	attempted, failed := 0, 0
+	for _, result := range results {
+		if result.Skipped {
+			continue
+		}
+		attempted++
+		if !result.Success {
+			failed++
+		}
+	}
+	if attempted == 0 {
+		return false
+	}
+	return failed*100 > rollout.Plan.FailureThresholdPercent*attempted
+}
+
+// failWaveLocked marks the wave failed and pauses the rollout unless it was aborted meanwhile
+func (s *Service) failWaveLocked(rollout *models.Rollout, wave *models.RolloutWave, phase string) {
+	wave.Status = models.WaveFailed
+	wave.FinishedAt = time.Now().Format(timeLayout)
+	if rollout.Status == models.RolloutAborted {
+		return
+	}
+	rollout.Status = models.RolloutPaused
+	rollout.Message = fmt.Sprintf("%s %s失败率超过 %d%%，已自动暂停", wave.Name, phase, rollout.Plan.FailureThresholdPercent)
+	if wrong := countOutcome(wave.Results, models.OutcomeUnexpectedBuild); wrong > 0 {
+		rollout.Message += fmt.Sprintf("，其中 %d 台设备运行的不是预期版本", wrong)
+	}
+	fmt.Printf("Rollout %s paused: %s\n", rollout.Name, rollout.Message)
+}
+
+// countOutcome counts the results with the given verification outcome
+func countOutcome(results []models.UpdateResult, outcome string) int {
+	count := 0
+	for _, result := range results {
+		if result.Outcome == outcome {
+			count++
+		}
+	}
+	return count
+}
+
+// mergeResults replaces the result for the same IP or appends a new one
+func mergeResults(results []models.UpdateResult, result models.UpdateResult) []models.UpdateResult {
+	for i := range results {
+		if results[i].IP == result.IP {
+			results[i] = result
+			return results
+		}
+	}
+	return append(results, result)
+}
@@ -0,0 +1,400 @@
+package rollout
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"application-updater/internal/models"
+	"application-updater/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+const timeLayout = "2006-01-02 15:04:05"
+
+// Upgrader uploads update files to devices and checks that they are reachable afterwards
+type Upgrader interface {
+	GetDevice(id string) (models.Device, error)
+	UpdateDevicesFromFile(deviceIds []string, fileName, filePath, md5FileName, md5FilePath, username, password string, options models.UpgradeOptions) ([]models.UpdateResult, error)
+	TestDevice(ip string) (*models.Device, error)
+	RollbackDevices(deviceIds []string, sshUsername, sshPassword string) ([]models.UpdateResult, error)
+}
+
+// Packages resolves the repository packages that rollouts upload
+type Packages interface {
+	Get(id string) (models.FirmwarePackage, error)
+	Paths(pkg models.FirmwarePackage) (filePath, md5FilePath string)
+}
+
+// Service runs staged rollouts and persists their progress so they survive an app restart
+type Service struct {
+	mutex    sync.Mutex
+	dir      string
+	upgrader Upgrader
+	packages Packages
+	rollouts map[string]*models.Rollout
+	runners  map[string]chan struct{} // 每个执行中的发布对应一个唤醒通道
+	closing  bool
+
+	// soakUnit is the length of one soak minute, shortened in tests
+	soakUnit time.Duration
+}
+
+// NewService loads the rollouts stored in dir and resumes the ones that were running
+func NewService(dir string, upgrader Upgrader, packages Packages) *Service {
+	s := &Service{
+		dir:      dir,
+		upgrader: upgrader,
+		packages: packages,
+		rollouts: make(map[string]*models.Rollout),
+		runners:  make(map[string]chan struct{}),
+		soakUnit: time.Minute,
+	}
+
+	if err := utils.EnsureDirExists(dir); err != nil {
+		fmt.Printf("Failed to create rollouts directory: %v\n", err)
+	}
+	s.load()
+	return s
+}
+
+// load reads all persisted rollouts and resumes running ones
+func (s *Service) load() {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		fmt.Printf("Failed to read rollouts directory: %v\n", err)
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		var rollout models.Rollout
+		if err := utils.LoadConfig(filepath.Join(s.dir, entry.Name(), "rollout.json"), &rollout); err != nil {
+			fmt.Printf("Failed to load rollout %s: %v\n", entry.Name(), err)
+			continue
+		}
+		s.rollouts[rollout.ID] = &rollout
+
+		// Rollouts finished by an older version still hold the device passwords
+		if finished(&rollout) && hasCredentials(&rollout) {
+			s.saveLocked(&rollout)
+		}
+		if rollout.Status != models.RolloutRunning {
+			continue
+		}
+
+		// An upload cut off by a restart may have left devices half-updated, let the operator decide
+		if wave := currentWave(&rollout); wave != nil && wave.Status == models.WaveUploading {
+			rollout.Status = models.RolloutPaused
+			rollout.Message = "上传过程中应用已退出，请检查设备状态后继续"
+			s.saveLocked(&rollout)
+			continue
+		}
+
+		fmt.Printf("Resuming rollout %s (%s)\n", rollout.Name, rollout.ID)
+		s.startLocked(rollout.ID)
+	}
+}
+
+// rolloutDir returns the directory holding a rollout's state
+func (s *Service) rolloutDir(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+// packagePaths returns the files a rollout uploads. Rollouts created by older versions
+// without a repository package keep their own copy in the rollout directory.
+func (s *Service) packagePaths(rollout *models.Rollout) (filePath, md5FilePath string, err error) {
+	if rollout.PackageID == "" {
+		dir := s.rolloutDir(rollout.ID)
+		filePath = filepath.Join(dir, rollout.FileName)
+		if rollout.MD5FileName != "" {
+			md5FilePath = filepath.Join(dir, rollout.MD5FileName)
+		}
+		return filePath, md5FilePath, nil
+	}
+
+	pkg, err := s.packages.Get(rollout.PackageID)
+	if err != nil {
+		return "", "", err
+	}
+	filePath, md5FilePath = s.packages.Paths(pkg)
+	return filePath, md5FilePath, nil
+}
+
+// saveLocked persists a rollout, the caller must hold s.mutex.
+// A completed or aborted rollout no longer keeps the device passwords.
+func (s *Service) saveLocked(rollout *models.Rollout) {
+	if finished(rollout) {
+		clearCredentials(rollout)
+	}
+	rollout.UpdatedAt = time.Now().Format(timeLayout)
+	if err := utils.SaveConfig(filepath.Join(s.rolloutDir(rollout.ID), "rollout.json"), rollout); err != nil {
+		fmt.Printf("Failed to save rollout %s: %v\n", rollout.ID, err)
+	}
+}
+
+// finished reports whether a rollout will not upload again
+func finished(rollout *models.Rollout) bool {
+	return rollout.Status == models.RolloutCompleted || rollout.Status == models.RolloutAborted
+}
+
+func hasCredentials(rollout *models.Rollout) bool {
+	return rollout.Password != "" || rollout.Plan.Verify.SSHPassword != ""
+}
+
+// clearCredentials removes the passwords a rollout uploads and verifies with
+func clearCredentials(rollout *models.Rollout) {
+	rollout.Password = ""
+	rollout.Plan.Verify.SSHPassword = ""
+}
+
+// currentWave returns the wave being processed, or nil when all waves are done
+func currentWave(rollout *models.Rollout) *models.RolloutWave {
+	if rollout.CurrentWave < 0 || rollout.CurrentWave >= len(rollout.Waves) {
+		return nil
+	}
+	return &rollout.Waves[rollout.CurrentWave]
+}
+
+// Create stores the plan as a new pending rollout of a repository package.
+// The package files are looked up again for every wave, so the package must stay in the repository.
+func (s *Service) Create(name string, plan models.RolloutPlan, packageID, username, password string) (models.Rollout, error) {
+	pkg, err := s.packages.Get(packageID)
+	if err != nil {
+		return models.Rollout{}, err
+	}
+	if filePath, _ := s.packages.Paths(pkg); !utils.FileExists(filePath) {
+		return models.Rollout{}, fmt.Errorf("update file not found: %s", filePath)
+	}
+
+	waves, err := buildWaves(plan, s.upgrader.GetDevice)
+	if err != nil {
+		return models.Rollout{}, err
+	}
+
+	rollout := &models.Rollout{
+		ID:          uuid.New().String(),
+		Name:        name,
+		CreatedAt:   time.Now().Format(timeLayout),
+		PackageID:   pkg.ID,
+		FileName:    pkg.FileName,
+		MD5FileName: pkg.MD5FileName,
+		Username:    username,
+		Password:    password,
+		Plan:        plan,
+		Waves:       waves,
+		Status:      models.RolloutPending,
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.rollouts[rollout.ID] = rollout
+	s.saveLocked(rollout)
+
+	created := *rollout
+	clearCredentials(&created)
+	return created, nil
+}
+
+// List returns all rollouts without their passwords, newest first
+func (s *Service) List() []models.Rollout {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	rollouts := make([]models.Rollout, 0, len(s.rollouts))
+	for _, rollout := range s.rollouts {
+		listed := *rollout
+		clearCredentials(&listed)
+		rollouts = append(rollouts, listed)
+	}
+	sort.Slice(rollouts, func(i, j int) bool {
+		return rollouts[i].CreatedAt > rollouts[j].CreatedAt
+	})
+	return rollouts
+}
+
+// Get returns a rollout by ID without its passwords
+func (s *Service) Get(id string) (models.Rollout, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	rollout, ok := s.rollouts[id]
+	if !ok {
+		return models.Rollout{}, fmt.Errorf("rollout %s not found", id)
+	}
+	found := *rollout
+	clearCredentials(&found)
+	return found, nil
+}
+
+// Start begins a pending rollout
+func (s *Service) Start(id string) error {
+	return s.transition(id, []string{models.RolloutPending}, func(rollout *models.Rollout) {
+		rollout.Status = models.RolloutRunning
+		rollout.Message = ""
+		s.startLocked(id)
+	})
+}
+
+// Pause stops a running rollout after the current upload; a soak period is interrupted immediately
+func (s *Service) Pause(id string) error {
+	return s.transition(id, []string{models.RolloutRunning}, func(rollout *models.Rollout) {
+		rollout.Status = models.RolloutPaused
+		rollout.Message = "已手动暂停"
+		s.stopLocked(id)
+	})
+}
+
+// Resume continues a paused rollout. A wave that failed its threshold is accepted and the next wave starts.
+func (s *Service) Resume(id string) error {
+	return s.transition(id, []string{models.RolloutPaused}, func(rollout *models.Rollout) {
+		if wave := currentWave(rollout); wave != nil && wave.Status == models.WaveFailed {
+			wave.Status = models.WaveCompleted
+			rollout.CurrentWave++
+		}
+		rollout.Status = models.RolloutRunning
+		rollout.Message = ""
+		s.startLocked(id)
+	})
+}
+
+// Abort stops a rollout permanently, devices already updated are left as they are
+func (s *Service) Abort(id string) error {
+	return s.transition(id, []string{models.RolloutPending, models.RolloutRunning, models.RolloutPaused}, func(rollout *models.Rollout) {
+		rollout.Status = models.RolloutAborted
+		rollout.Message = "已中止"
+		s.stopLocked(id)
+	})
+}
+
+// RollbackWave restores the pre-upgrade snapshot on every device of a wave.
+// The rollout must not be running; it is left paused or aborted so the operator decides how to continue.
+func (s *Service) RollbackWave(id string, waveIndex int, sshUsername, sshPassword string) ([]models.UpdateResult, error) {
+	s.mutex.Lock()
+	rollout, ok := s.rollouts[id]
+	if !ok {
+		s.mutex.Unlock()
+		return nil, fmt.Errorf("rollout %s not found", id)
+	}
+	if rollout.Status == models.RolloutRunning {
+		s.mutex.Unlock()
+		return nil, fmt.Errorf("pause rollout %s before rolling back", rollout.Name)
+	}
+	if waveIndex < 0 || waveIndex >= len(rollout.Waves) || rollout.Waves[waveIndex].Status == models.WavePending {
+		s.mutex.Unlock()
+		return nil, fmt.Errorf("wave %d of rollout %s has not been uploaded", waveIndex, rollout.Name)
+	}
+	deviceIDs := append([]string{}, rollout.Waves[waveIndex].DeviceIDs...)
+	s.mutex.Unlock()
+
+	results, err := s.upgrader.RollbackDevices(deviceIDs, sshUsername, sshPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	wave := &rollout.Waves[waveIndex]
+	wave.RolledBackAt = time.Now().Format(timeLayout)
+	wave.Rollback = results
+	rollout.Message = fmt.Sprintf("第%d批已回滚", waveIndex+1)
+	s.saveLocked(rollout)
+	return results, nil
+}
+
+// transition applies a status change if the rollout is in one of the allowed states
+func (s *Service) transition(id string, from []string, apply func(rollout *models.Rollout)) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	rollout, ok := s.rollouts[id]
+	if !ok {
+		return fmt.Errorf("rollout %s not found", id)
+	}
+
+	for _, status := range from {
+		if rollout.Status == status {
+			apply(rollout)
+			s.saveLocked(rollout)
+			return nil
+		}
+	}
+	return fmt.Errorf("rollout %s is %s", rollout.Name, rollout.Status)
+}
+
+// startLocked launches the runner goroutine unless one is still active, the caller must hold s.mutex
+func (s *Service) startLocked(id string) {
+	if _, running := s.runners[id]; running {
+		return
+	}
+
+	wake := make(chan struct{}, 1)
+	s.runners[id] = wake
+	go s.run(id, wake)
+}
+
+// stopLocked wakes the runner so it notices the status change, the caller must hold s.mutex.
+// An upload in progress is not interrupted, the runner stops once it has finished.
+func (s *Service) stopLocked(id string) {
+	if wake, ok := s.runners[id]; ok {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// HasRunning reports whether any rollout is still being driven, including waves that are soaking
+func (s *Service) HasRunning() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.runners) > 0
+}
+
+// Close stops all runners without changing the persisted state, running rollouts resume on the next start
+func (s *Service) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.closing = true
+	for id := range s.runners {
+		s.stopLocked(id)
+	}
+}
+
+// PackageReferences lists the repository packages of the unfinished rollouts stored in dir
+func PackageReferences(dir string) ([]models.PackageReference, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rollouts directory: %w", err)
+	}
+
+	var refs []models.PackageReference
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		var rollout models.Rollout
+		if err := utils.LoadConfig(filepath.Join(dir, entry.Name(), "rollout.json"), &rollout); err != nil {
+			return nil, fmt.Errorf("failed to load rollout %s: %w", entry.Name(), err)
+		}
+		if finished(&rollout) || rollout.PackageID == "" {
+			continue
+		}
+		refs = append(refs, models.PackageReference{PackageID: rollout.PackageID})
+	}
+	return refs, nil
+}
@@ -0,0 +1,264 @@
+package rollout
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"application-updater/internal/models"
+	"application-updater/internal/utils"
+)
+
+// fakeUpgrader records uploads and fails the devices listed in broken, or in wrongBuild with an unexpected build
+type fakeUpgrader struct {
+	mutex      sync.Mutex
+	devices    map[string]models.Device
+	broken     map[string]bool
+	wrongBuild map[string]bool
+	uploaded   []string
+	files      []string // update file of each upload
+}
+
+func newFakeUpgrader(n int) *fakeUpgrader {
+	f := &fakeUpgrader{devices: make(map[string]models.Device), broken: make(map[string]bool), wrongBuild: make(map[string]bool)}
+	for i := 1; i <= n; i++ {
+		id := fmt.Sprintf("d%d", i)
+		region := "north"
+		if i%2 == 0 {
+			region = "south"
+		}
+		f.devices[id] = models.Device{ID: id, IP: fmt.Sprintf("10.0.0.%d", i), Region: region, Status: "online"}
+	}
+	return f
+}
+
+func (f *fakeUpgrader) GetDevice(id string) (models.Device, error) {
+	device, ok := f.devices[id]
+	if !ok {
+		return models.Device{}, fmt.Errorf("not found")
+	}
+	return device, nil
+}
+
+func (f *fakeUpgrader) UpdateDevicesFromFile(ids []string, fileName, filePath, md5FileName, md5FilePath, username, password string, options models.UpgradeOptions) ([]models.UpdateResult, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.files = append(f.files, filePath)
+	results := []models.UpdateResult{}
+	for _, id := range ids {
+		device := f.devices[id]
+		f.uploaded = append(f.uploaded, id)
+		result := models.UpdateResult{IP: device.IP, Success: !f.broken[id] && !f.wrongBuild[id]}
+		if f.wrongBuild[id] {
+			result.Outcome = models.OutcomeUnexpectedBuild
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (f *fakeUpgrader) TestDevice(ip string) (*models.Device, error) {
+	return &models.Device{IP: ip, BuildTime: "2025-01-01 00:00:00"}, nil
+}
+
+func (f *fakeUpgrader) RollbackDevices(ids []string, sshUsername, sshPassword string) ([]models.UpdateResult, error) {
+	return nil, nil
+}
+
+func (f *fakeUpgrader) uploadCount() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return len(f.uploaded)
+}
+
+// fakePackages is a repository holding the packages of the test
+type fakePackages struct {
+	dir      string
+	packages map[string]models.FirmwarePackage
+}
+
+// newFakePackages creates a repository with a single dummy package "pkg"
+func newFakePackages(t *testing.T) *fakePackages {
+	t.Helper()
+	p := &fakePackages{dir: t.TempDir(), packages: make(map[string]models.FirmwarePackage)}
+	p.packages["pkg"] = models.FirmwarePackage{ID: "pkg", FileName: "app.bin"}
+	if err := os.WriteFile(filepath.Join(p.dir, "app.bin"), []byte("binary"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func (p *fakePackages) Get(id string) (models.FirmwarePackage, error) {
+	pkg, ok := p.packages[id]
+	if !ok {
+		return models.FirmwarePackage{}, fmt.Errorf("not found")
+	}
+	return pkg, nil
+}
+
+func (p *fakePackages) Paths(pkg models.FirmwarePackage) (string, string) {
+	return filepath.Join(p.dir, pkg.FileName), ""
+}
+
+// waitForStatus polls until the rollout reaches the given status
+func waitForStatus(t *testing.T, s *Service, id, status string) models.Rollout {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if rollout, _ := s.Get(id); rollout.Status == status {
+			return rollout
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	rollout, _ := s.Get(id)
+	t.Fatalf("Rollout did not reach %s, status %s: %s", status, rollout.Status, rollout.Message)
+	return rollout
+}
+
+func TestBuildWaves(t *testing.T) {
+	upgrader := newFakeUpgrader(10)
+	ids := []string{"d1", "d2", "d3", "d4", "d5", "d6", "d7", "d8", "d9", "d10"}
+
+	waves, err := buildWaves(models.RolloutPlan{DeviceIDs: ids, CanaryCount: 2, WavePercents: []int{25}}, upgrader.GetDevice)
+	if err != nil {
+		t.Fatalf("buildWaves failed: %v", err)
+	}
+	sizes := []int{}
+	for _, wave := range waves {
+		sizes = append(sizes, len(wave.DeviceIDs))
+	}
+	if fmt.Sprint(sizes) != "[2 2 6]" {
+		t.Errorf("Expected canary of 2, a 25%% wave and the rest, got %v", sizes)
+	}
+
+	waves, _ = buildWaves(models.RolloutPlan{DeviceIDs: ids, CanaryIDs: []string{"d1"}, Strategy: models.RolloutByRegion}, upgrader.GetDevice)
+	if len(waves) != 3 || waves[1].Name != "north" || len(waves[1].DeviceIDs) != 4 {
+		t.Errorf("Expected canary then one wave per region, got %+v", waves)
+	}
+}
+
+func TestRolloutPausesOnFailuresAndResumes(t *testing.T) {
+	dir := t.TempDir()
+	upgrader := newFakeUpgrader(4)
+	upgrader.broken["d1"] = true
+
+	packages := newFakePackages(t)
+	s := NewService(dir, upgrader, packages)
+	s.soakUnit = time.Millisecond
+	rollout, err := s.Create("test", models.RolloutPlan{
+		DeviceIDs:   []string{"d1", "d2", "d3", "d4"},
+		CanaryCount: 1,
+		SoakMinutes: 1,
+	}, "pkg", "admin", "admin")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if err := s.Start(rollout.ID); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	paused := waitForStatus(t, s, rollout.ID, models.RolloutPaused)
+	if paused.Waves[0].Status != models.WaveFailed || upgrader.uploadCount() != 1 {
+		t.Fatalf("Expected the failed canary to stop the rollout before other waves, got %+v", paused)
+	}
+
+	// State survives a restart, and resuming accepts the failed canary
+	s.Close()
+	s = NewService(dir, upgrader, packages)
+	s.soakUnit = time.Millisecond
+	if err := s.Resume(rollout.ID); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	completed := waitForStatus(t, s, rollout.ID, models.RolloutCompleted)
+	if upgrader.uploadCount() != 4 || completed.Waves[1].Status != models.WaveCompleted {
+		t.Errorf("Expected remaining devices to be updated, got %d uploads, %+v", upgrader.uploadCount(), completed.Waves)
+	}
+
+	// Every wave uploads the repository package, the rollout keeps no copy of its own
+	for _, file := range upgrader.files {
+		if file != filepath.Join(packages.dir, "app.bin") {
+			t.Errorf("Expected waves to upload the repository package, got %s", file)
+		}
+	}
+	if utils.FileExists(filepath.Join(dir, rollout.ID, "app.bin")) {
+		t.Errorf("Expected the rollout not to copy the package")
+	}
+}
+
+func TestRolloutPausesWhenPackageIsGone(t *testing.T) {
+	upgrader := newFakeUpgrader(2)
+	packages := newFakePackages(t)
+	s := NewService(t.TempDir(), upgrader, packages)
+	rollout, err := s.Create("test", models.RolloutPlan{DeviceIDs: []string{"d1", "d2"}}, "pkg", "admin", "admin")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	delete(packages.packages, "pkg")
+	if err := s.Start(rollout.ID); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	paused := waitForStatus(t, s, rollout.ID, models.RolloutPaused)
+	if upgrader.uploadCount() != 0 || paused.Waves[0].Status != models.WavePending {
+		t.Errorf("Expected no upload without the package, got %+v", paused)
+	}
+}
+
+func TestRolloutDropsPasswordsWhenFinished(t *testing.T) {
+	dir := t.TempDir()
+	s := NewService(dir, newFakeUpgrader(2), newFakePackages(t))
+	rollout, err := s.Create("test", models.RolloutPlan{
+		DeviceIDs: []string{"d1", "d2"},
+		Verify:    models.UpgradeOptions{SSHUsername: "root", SSHPassword: "ssh-secret"},
+	}, "pkg", "admin", "secret")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if listed := s.List()[0]; listed.Password != "" || listed.Plan.Verify.SSHPassword != "" {
+		t.Errorf("Expected List to leave out passwords, got %+v", listed)
+	}
+
+	stored := func() models.Rollout {
+		var stored models.Rollout
+		if err := utils.LoadConfig(filepath.Join(dir, rollout.ID, "rollout.json"), &stored); err != nil {
+			t.Fatal(err)
+		}
+		return stored
+	}
+	// A pending rollout still needs the passwords to upload
+	if pending := stored(); pending.Password != "secret" || pending.Plan.Verify.SSHPassword != "ssh-secret" {
+		t.Fatalf("Expected the pending rollout to keep its passwords, got %+v", pending)
+	}
+
+	if err := s.Abort(rollout.ID); err != nil {
+		t.Fatalf("Abort failed: %v", err)
+	}
+	aborted := stored()
+	if aborted.Password != "" || aborted.Plan.Verify.SSHPassword != "" || aborted.Username != "admin" {
+		t.Errorf("Expected the aborted rollout to drop only its passwords, got %+v", aborted)
+	}
+}
+
+func TestRolloutCountsUnexpectedBuilds(t *testing.T) {
+	upgrader := newFakeUpgrader(3)
+	upgrader.broken["d1"] = true
+	upgrader.wrongBuild["d2"] = true
+
+	s := NewService(t.TempDir(), upgrader, newFakePackages(t))
+	rollout, err := s.Create("test", models.RolloutPlan{DeviceIDs: []string{"d1", "d2", "d3"}, CanaryCount: 3}, "pkg", "admin", "admin")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := s.Start(rollout.ID); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	paused := waitForStatus(t, s, rollout.ID, models.RolloutPaused)
+	if !strings.Contains(paused.Message, "其中 1 台设备运行的不是预期版本") {
+		t.Errorf("Expected the wrong build to be counted apart from the failed upload, got %q", paused.Message)
+	}
+}
@@ -0,0 +1,226 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"application-updater/internal/models"
+)
+
+// tick starts the batches that are due and pauses schedules that missed their time or window
+func (s *Service) tick() {
+	now := s.now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	changed := false
+	for _, schedule := range s.data.Schedules {
+		if schedule.Status != models.ScheduleWaiting || s.running[schedule.ID] {
+			continue
+		}
+		changed = true
+		s.checkLocked(schedule, now)
+		schedule.CheckedAt = now.Format(timeLayout)
+	}
+	if changed {
+		s.saveLocked()
+	}
+}
+
+// checkLocked decides what to do with a waiting schedule, the caller must hold s.mutex
+func (s *Service) checkLocked(schedule *models.ScheduledUpdate, now time.Time) {
+	if len(schedule.Pending) == 0 {
+		schedule.Status = models.ScheduleCompleted
+		return
+	}
+
+	runAt := parseTime(runAtLayout, schedule.RunAt)
+	if now.Before(runAt) {
+		return
+	}
+
+	if !schedule.UseWindows {
+		grace := time.Duration(schedule.GraceMinutes) * time.Minute
+		if grace <= 0 {
+			grace = defaultGraceMinutes * time.Minute
+		}
+		if !runAt.IsZero() && now.After(runAt.Add(grace)) {
+			s.pauseLocked(schedule, fmt.Sprintf("错过计划执行时间 %s", schedule.RunAt))
+			return
+		}
+		s.startLocked(schedule, schedule.Pending)
+		return
+	}
+
+	regions := s.pendingByRegion(schedule)
+	windows := s.windowsByRegion()
+
+	// 应用未运行(或电脑休眠)期间某个区域的维护窗口已经结束，交给操作员决定
+	checkedAt := parseTime(timeLayout, schedule.CheckedAt)
+	if checkedAt.Before(runAt) {
+		checkedAt = runAt
+	}
+	if now.Sub(checkedAt) > 2*tickInterval {
+		for region := range regions {
+			if windowOccurred(windows[region], checkedAt, now) && !windowOccurred(windows[region], now, now.Add(time.Minute)) {
+				s.pauseLocked(schedule, fmt.Sprintf("应用未运行期间错过了区域 %s 的维护窗口", displayRegion(region)))
+				return
+			}
+		}
+	}
+
+	due := []string{}
+	for region, ids := range regions {
+		if len(windows[region]) == 0 {
+			s.pauseLocked(schedule, fmt.Sprintf("区域 %s 没有配置维护窗口", displayRegion(region)))
+			return
+		}
+		for _, window := range windows[region] {
+			if windowOpen(window, now) {
+				due = append(due, ids...)
+				break
+			}
+		}
+	}
+	if len(due) > 0 {
+		sort.Strings(due)
+		s.startLocked(schedule, due)
+	}
+}
+
+// pendingByRegion groups the pending devices of a schedule by region
+func (s *Service) pendingByRegion(schedule *models.ScheduledUpdate) map[string][]string {
+	regions := make(map[string][]string)
+	for _, id := range schedule.Pending {
+		region := ""
+		if device, err := s.updater.GetDevice(id); err == nil {
+			region = device.Region
+		}
+		regions[region] = append(regions[region], id)
+	}
+	return regions
+}
+
+// windowsByRegion groups the maintenance windows by region, the caller must hold s.mutex
+func (s *Service) windowsByRegion() map[string][]models.MaintenanceWindow {
+	windows := make(map[string][]models.MaintenanceWindow)
+	for _, window := range s.data.Windows {
+		windows[window.Region] = append(windows[window.Region], window)
+	}
+	return windows
+}
+
+// windowOccurred reports whether one of the windows was open at some minute between from and to
+func windowOccurred(windows []models.MaintenanceWindow, from, to time.Time) bool {
+	if earliest := to.Add(-missedWindowLookback); from.Before(earliest) {
+		from = earliest
+	}
+	for t := from.Truncate(time.Minute); t.Before(to); t = t.Add(time.Minute) {
+		for _, window := range windows {
+			if windowOpen(window, t) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pauseLocked pauses a schedule with a reason, the caller must hold s.mutex
+func (s *Service) pauseLocked(schedule *models.ScheduledUpdate, reason string) {
+	fmt.Printf("Pausing scheduled update %s: %s\n", schedule.Name, reason)
+	schedule.Status = models.SchedulePaused
+	schedule.Message = reason
+	schedule.UpdatedAt = s.now().Format(timeLayout)
+}
+
+// startLocked updates a batch of devices in the background, the caller must hold s.mutex
+func (s *Service) startLocked(schedule *models.ScheduledUpdate, deviceIDs []string) {
+	if schedule.StartedAt == "" {
+		schedule.StartedAt = s.now().Format(timeLayout)
+	}
+	schedule.Status = models.ScheduleRunning
+	schedule.Message = ""
+	s.running[schedule.ID] = true
+
+	fmt.Printf("Starting scheduled update %s on %d devices\n", schedule.Name, len(deviceIDs))
+	go s.execute(schedule.ID, *schedule, deviceIDs)
+}
+
+// execute runs one batch and records the results
+func (s *Service) execute(id string, schedule models.ScheduledUpdate, deviceIDs []string) {
+	job, err := s.updater.StartUpdateJob(deviceIDs, schedule.PackageID, schedule.FileName, schedule.FilePath, schedule.MD5FileName, schedule.MD5FilePath, schedule.Username, schedule.Password, schedule.Options)
+
+	// 离线设备由更新任务记为跳过，任务未能开始时所有设备都记为跳过
+	results := job.Results
+	missing := job.Pending
+	if err != nil {
+		missing = deviceIDs
+	}
+	for _, deviceID := range missing {
+		result := models.UpdateResult{IP: deviceID, Skipped: true, Message: "设备离线，未更新"}
+		if err != nil {
+			result.Message = "未更新: " + err.Error()
+		}
+		if device, getErr := s.updater.GetDevice(deviceID); getErr == nil {
+			result.IP = device.IP
+		}
+		results = append(results, result)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	current, findErr := s.findLocked(id)
+	delete(s.running, id)
+	if findErr != nil {
+		return
+	}
+
+	if job.ID != "" {
+		current.JobIDs = append(current.JobIDs, job.ID)
+	}
+	current.Results = append(current.Results, results...)
+	current.Pending = without(current.Pending, deviceIDs)
+	if current.Status == models.ScheduleRunning {
+		current.Status = models.ScheduleWaiting
+		if len(current.Pending) == 0 {
+			current.Status = models.ScheduleCompleted
+		}
+	}
+	current.UpdatedAt = s.now().Format(timeLayout)
+	s.saveLocked()
+}
+
+// parseTime parses a local time, an empty or invalid value returns the zero time
+func parseTime(layout, value string) time.Time {
+	t, err := time.ParseInLocation(layout, value, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func displayRegion(region string) string {
+	if strings.TrimSpace(region) == "" {
+		return "(未分配)"
+	}
+	return region
+}
+
+// without returns ids minus the removed ones
+func without(ids, removed []string) []string {
+	drop := make(map[string]bool, len(removed))
+	for _, id := range removed {
+		drop[id] = true
+	}
+	result := []string{}
+	for _, id := range ids {
+		if !drop[id] {
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -0,0 +1,318 @@
+package schedule
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"application-updater/internal/models"
+	"application-updater/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+const (
+	timeLayout  = "2006-01-02 15:04:05"
+	runAtLayout = "2006-01-02 15:04"
+
+	defaultGraceMinutes = 30
+	// missedWindowLookback limits how far back missed windows are searched after the app was not running
+	missedWindowLookback = 8 * 24 * time.Hour
+)
+
+// tickInterval is how often due schedules are checked
+var tickInterval = 30 * time.Second
+
+// Updater runs update jobs on devices
+type Updater interface {
+	GetDevice(id string) (models.Device, error)
+	StartUpdateJob(deviceIds []string, packageID, fileName, filePath, md5FileName, md5FilePath, username, password string, options models.UpgradeOptions) (models.UpdateJob, error)
+}
+
+// scheduleFile is the persisted state of a workspace's schedules
+type scheduleFile struct {
+	Windows   []models.MaintenanceWindow `json:"windows"`
+	Schedules []*models.ScheduledUpdate  `json:"schedules"`
+}
+
+// Service runs scheduled updates in the background, at a given time or inside per-region maintenance windows
+type Service struct {
+	mutex   sync.Mutex
+	path    string
+	updater Updater
+	data    scheduleFile
+	running map[string]bool // 正在执行的计划
+	stop    chan struct{}
+	done    chan struct{}
+
+	// now returns the current time, replaced in tests
+	now func() time.Time
+}
+
+// NewService loads the schedules stored in dir and starts checking them in the background
+func NewService(dir string, updater Updater) *Service {
+	s := newService(dir, updater)
+	go s.loop()
+	return s
+}
+
+// newService loads the persisted schedules without starting the background loop
+func newService(dir string, updater Updater) *Service {
+	s := &Service{
+		path:    filepath.Join(dir, "schedules.json"),
+		updater: updater,
+		running: make(map[string]bool),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		now:     time.Now,
+	}
+
+	if utils.FileExists(s.path) {
+		if err := utils.LoadConfig(s.path, &s.data); err != nil {
+			fmt.Printf("Failed to load schedules: %v\n", err)
+		}
+	}
+
+	// A batch cut off by a restart keeps its devices pending and runs again
+	for _, schedule := range s.data.Schedules {
+		if schedule.Status == models.ScheduleRunning {
+			schedule.Status = models.ScheduleWaiting
+		}
+	}
+	// Schedules finished by an older version may still hold passwords
+	for _, schedule := range s.data.Schedules {
+		if finished(schedule) && hasCredentials(schedule) {
+			s.saveLocked()
+			break
+		}
+	}
+	return s
+}
+
+// loop checks the schedules until Close is called
+func (s *Service) loop() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	s.tick()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.tick()
+		}
+	}
+}
+
+// Close stops the background loop; a batch already uploading finishes on its own
+func (s *Service) Close() {
+	close(s.stop)
+	<-s.done
+}
+
+// saveLocked persists all schedules, the caller must hold s.mutex.
+// Passwords are only kept while a schedule can still run.
+func (s *Service) saveLocked() {
+	for _, schedule := range s.data.Schedules {
+		if finished(schedule) {
+			clearCredentials(schedule)
+		}
+	}
+	if err := utils.SaveConfig(s.path, s.data); err != nil {
+		fmt.Printf("Failed to save schedules: %v\n", err)
+	}
+}
+
+// finished reports whether a schedule will not run again
+func finished(schedule *models.ScheduledUpdate) bool {
+	return schedule.Status == models.ScheduleCompleted || schedule.Status == models.ScheduleCancelled
+}
+
+func hasCredentials(schedule *models.ScheduledUpdate) bool {
+	return schedule.Password != "" || schedule.Options.SSHPassword != ""
+}
+
+// clearCredentials removes the passwords a schedule runs with
+func clearCredentials(schedule *models.ScheduledUpdate) {
+	schedule.Password = ""
+	schedule.Options.SSHPassword = ""
+}
+
+// findLocked returns a schedule by ID, the caller must hold s.mutex
+func (s *Service) findLocked(id string) (*models.ScheduledUpdate, error) {
+	for _, schedule := range s.data.Schedules {
+		if schedule.ID == id {
+			return schedule, nil
+		}
+	}
+	return nil, fmt.Errorf("scheduled update %s not found", id)
+}
+
+// GetWindows returns the maintenance windows of all regions
+func (s *Service) GetWindows() []models.MaintenanceWindow {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]models.MaintenanceWindow{}, s.data.Windows...)
+}
+
+// SaveWindows replaces the maintenance windows, a region may have several
+func (s *Service) SaveWindows(windows []models.MaintenanceWindow) error {
+	for _, window := range windows {
+		if err := validateWindow(window); err != nil {
+			return err
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.data.Windows = windows
+	s.saveLocked()
+	return nil
+}
+
+// List returns all schedules, newest first, without their passwords
+func (s *Service) List() []models.ScheduledUpdate {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	schedules := make([]models.ScheduledUpdate, 0, len(s.data.Schedules))
+	for _, schedule := range s.data.Schedules {
+		listed := *schedule
+		clearCredentials(&listed)
+		schedules = append(schedules, listed)
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].CreatedAt > schedules[j].CreatedAt
+	})
+	return schedules
+}
+
+// HasActive reports whether any schedule is still waiting to run
+func (s *Service) HasActive() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, schedule := range s.data.Schedules {
+		if schedule.Status == models.ScheduleWaiting || schedule.Status == models.ScheduleRunning {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRunning reports whether a batch of a schedule is updating devices right now
+func (s *Service) HasRunning() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.running) > 0
+}
+
+// Create stores a new scheduled update
+func (s *Service) Create(schedule models.ScheduledUpdate) (models.ScheduledUpdate, error) {
+	if len(schedule.DeviceIDs) == 0 {
+		return models.ScheduledUpdate{}, fmt.Errorf("no devices selected")
+	}
+	if schedule.FilePath == "" || !utils.FileExists(schedule.FilePath) {
+		return models.ScheduledUpdate{}, fmt.Errorf("update file not found: %s", schedule.FilePath)
+	}
+	if schedule.RunAt != "" {
+		if _, err := time.ParseInLocation(runAtLayout, schedule.RunAt, time.Local); err != nil {
+			return models.ScheduledUpdate{}, fmt.Errorf("invalid run time %q, expected %s", schedule.RunAt, runAtLayout)
+		}
+	}
+
+	now := s.now().Format(timeLayout)
+	schedule.ID = uuid.New().String()
+	schedule.CreatedAt = now
+	schedule.UpdatedAt = now
+	schedule.CheckedAt = now
+	schedule.Status = models.ScheduleWaiting
+	schedule.Pending = append([]string{}, schedule.DeviceIDs...)
+	schedule.StartedAt, schedule.Message, schedule.JobIDs, schedule.Results = "", "", nil, nil
+	// Devices updated by an earlier batch or by hand are not uploaded again
+	if schedule.Options.ExpectedBuild != "" {
+		schedule.Options.SkipCurrent = true
+	}
+	// Updates must stay inside their windows, so offline devices are reported as skipped instead of waiting
+	schedule.Options.DeferOffline = false
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.data.Schedules = append(s.data.Schedules, &schedule)
+	s.saveLocked()
+	return schedule, nil
+}
+
+// Pause stops a schedule from starting further batches
+func (s *Service) Pause(id string) error {
+	return s.transition(id, []string{models.ScheduleWaiting, models.ScheduleRunning}, func(schedule *models.ScheduledUpdate) {
+		schedule.Status = models.SchedulePaused
+		schedule.Message = "已手动暂停"
+	})
+}
+
+// Resume lets a paused schedule run again. Without maintenance windows the remaining devices are updated right away.
+func (s *Service) Resume(id string) error {
+	return s.transition(id, []string{models.SchedulePaused}, func(schedule *models.ScheduledUpdate) {
+		schedule.Status = models.ScheduleWaiting
+		schedule.Message = ""
+		schedule.CheckedAt = s.now().Format(timeLayout)
+		if !schedule.UseWindows {
+			schedule.RunAt = ""
+		}
+	})
+}
+
+// Cancel stops a schedule permanently, devices already updated are left as they are
+func (s *Service) Cancel(id string) error {
+	return s.transition(id, []string{models.ScheduleWaiting, models.ScheduleRunning, models.SchedulePaused}, func(schedule *models.ScheduledUpdate) {
+		schedule.Status = models.ScheduleCancelled
+		schedule.Message = "已取消"
+	})
+}
+
+// transition applies a status change if the schedule is in one of the allowed states
+func (s *Service) transition(id string, from []string, apply func(schedule *models.ScheduledUpdate)) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	schedule, err := s.findLocked(id)
+	if err != nil {
+		return err
+	}
+	for _, status := range from {
+		if schedule.Status == status {
+			apply(schedule)
+			schedule.UpdatedAt = s.now().Format(timeLayout)
+			s.saveLocked()
+			return nil
+		}
+	}
+	return fmt.Errorf("scheduled update %s is %s", schedule.Name, schedule.Status)
+}
+
+// PackageReferences lists the update files still needed by the unfinished schedules stored in dir
+func PackageReferences(dir string) ([]models.PackageReference, error) {
+	path := filepath.Join(dir, "schedules.json")
+	if !utils.FileExists(path) {
+		return nil, nil
+	}
+	var data scheduleFile
+	if err := utils.LoadConfig(path, &data); err != nil {
+		return nil, fmt.Errorf("failed to read schedules: %w", err)
+	}
+
+	var refs []models.PackageReference
+	for _, schedule := range data.Schedules {
+		if schedule.Status == models.ScheduleCompleted || schedule.Status == models.ScheduleCancelled {
+			continue
+		}
+		refs = append(refs, models.PackageReference{PackageID: schedule.PackageID, FilePath: schedule.FilePath})
+	}
+	return refs, nil
+}
@@ -0,0 +1,164 @@
+package schedule
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"application-updater/internal/models"
+)
+
+// fakeUpdater updates every device immediately, devices listed in offline get no result
+type fakeUpdater struct {
+	mutex   sync.Mutex
+	regions map[string]string
+	offline map[string]bool
+	batches [][]string
+}
+
+func (f *fakeUpdater) GetDevice(id string) (models.Device, error) {
+	return models.Device{ID: id, IP: "ip-" + id, Region: f.regions[id]}, nil
+}
+
+func (f *fakeUpdater) StartUpdateJob(ids []string, packageID, fileName, filePath, md5FileName, md5FilePath, username, password string, options models.UpgradeOptions) (models.UpdateJob, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.batches = append(f.batches, ids)
+
+	job := models.UpdateJob{ID: "job", Pending: []string{}}
+	for _, id := range ids {
+		if f.offline[id] {
+			job.Pending = append(job.Pending, id)
+			continue
+		}
+		job.Results = append(job.Results, models.UpdateResult{IP: "ip-" + id, Success: true})
+	}
+	return job, nil
+}
+
+func newTestService(t *testing.T, updater Updater, now time.Time) (*Service, string) {
+	t.Helper()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.bin")
+	os.WriteFile(file, []byte("binary"), 0644)
+
+	s := newService(dir, updater)
+	s.now = func() time.Time { return now }
+	return s, file
+}
+
+// waitForStatus polls until the schedule reaches the given status
+func waitForStatus(t *testing.T, s *Service, status string) models.ScheduledUpdate {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if schedules := s.List(); len(schedules) == 1 && schedules[0].Status == status {
+			return schedules[0]
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Schedule did not reach %s: %+v", status, s.List())
+	return models.ScheduledUpdate{}
+}
+
+func TestWindowOpenAcrossMidnight(t *testing.T) {
+	window := models.MaintenanceWindow{Start: "22:00", End: "04:00", Days: []int{int(time.Friday)}}
+	friday := time.Date(2024, 3, 1, 23, 0, 0, 0, time.Local)
+
+	if !windowOpen(window, friday) || !windowOpen(window, friday.Add(4*time.Hour)) {
+		t.Errorf("Expected the Friday window to be open until Saturday 04:00")
+	}
+	if windowOpen(window, friday.Add(6*time.Hour)) || windowOpen(window, friday.AddDate(0, 0, 1)) {
+		t.Errorf("Expected the window to be closed outside Friday night")
+	}
+}
+
+func TestScheduleRunsInsideRegionWindows(t *testing.T) {
+	updater := &fakeUpdater{regions: map[string]string{"a1": "A", "b1": "B"}, offline: map[string]bool{"b1": true}}
+	now := time.Date(2024, 3, 1, 23, 0, 0, 0, time.Local)
+	s, file := newTestService(t, updater, now)
+
+	s.SaveWindows([]models.MaintenanceWindow{
+		{Region: "A", Start: "22:00", End: "04:00"},
+		{Region: "B", Start: "01:00", End: "03:00"},
+	})
+	if _, err := s.Create(models.ScheduledUpdate{Name: "night", DeviceIDs: []string{"a1", "b1"}, FilePath: file, UseWindows: true}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	s.tick()
+	schedule := waitForStatus(t, s, models.ScheduleWaiting)
+	if len(updater.batches) != 1 || len(updater.batches[0]) != 1 || updater.batches[0][0] != "a1" {
+		t.Fatalf("Expected only region A to run at 23:00, got %v", updater.batches)
+	}
+	if len(schedule.Pending) != 1 || schedule.Pending[0] != "b1" {
+		t.Errorf("Expected b1 to stay pending, got %v", schedule.Pending)
+	}
+
+	// Region B's window opens, the offline device is recorded as skipped
+	s.now = func() time.Time { return now.Add(2*time.Hour + 30*time.Second) }
+	s.tick()
+	schedule = waitForStatus(t, s, models.ScheduleCompleted)
+	if len(schedule.Results) != 2 || !schedule.Results[1].Skipped {
+		t.Errorf("Expected the offline device to be recorded as skipped, got %+v", schedule.Results)
+	}
+}
+
+func TestSchedulePausesWhenRunTimeWasMissed(t *testing.T) {
+	updater := &fakeUpdater{}
+	now := time.Date(2024, 3, 1, 23, 0, 0, 0, time.Local)
+	s, file := newTestService(t, updater, now)
+
+	if _, err := s.Create(models.ScheduledUpdate{Name: "late", DeviceIDs: []string{"d1"}, FilePath: file, RunAt: "2024-03-01 20:00"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	s.tick()
+
+	schedule := s.List()[0]
+	if schedule.Status != models.SchedulePaused || len(updater.batches) != 0 {
+		t.Fatalf("Expected the missed schedule to pause without updating, got %+v", schedule)
+	}
+
+	if err := s.Resume(schedule.ID); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	s.tick()
+	waitForStatus(t, s, models.ScheduleCompleted)
+}
+
+func TestScheduleDropsPasswordsWhenFinished(t *testing.T) {
+	now := time.Date(2024, 3, 1, 23, 0, 0, 0, time.Local)
+	s, file := newTestService(t, &fakeUpdater{}, now)
+	dir := filepath.Dir(s.path)
+
+	created, err := s.Create(models.ScheduledUpdate{
+		Name:      "night",
+		DeviceIDs: []string{"d1"},
+		FilePath:  file,
+		RunAt:     "2024-03-02 02:00",
+		Username:  "admin",
+		Password:  "secret",
+		Options:   models.UpgradeOptions{SSHPassword: "ssh-secret"},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if listed := s.List()[0]; listed.Password != "" || listed.Options.SSHPassword != "" {
+		t.Errorf("Expected List to leave out the passwords, got %+v", listed)
+	}
+
+	// The waiting schedule still needs its password after a restart
+	if stored := newService(dir, &fakeUpdater{}).data.Schedules[0]; stored.Password != "secret" {
+		t.Fatalf("Expected the waiting schedule to keep its password, got %q", stored.Password)
+	}
+
+	if err := s.Cancel(created.ID); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	stored := newService(dir, &fakeUpdater{}).data.Schedules[0]
+	if stored.Password != "" || stored.Options.SSHPassword != "" || stored.Username != "admin" {
+		t.Errorf("Expected the cancelled schedule to drop its passwords, got %+v", stored)
+	}
+}
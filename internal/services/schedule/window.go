@@ -0,0 +1,73 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+
+	"application-updater/internal/models"
+)
+
+// parseClock parses an HH:MM time of day into minutes after midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// validateWindow checks the times and days of a maintenance window
+func validateWindow(window models.MaintenanceWindow) error {
+	start, err := parseClock(window.Start)
+	if err != nil {
+		return err
+	}
+	end, err := parseClock(window.End)
+	if err != nil {
+		return err
+	}
+	if start == end {
+		return fmt.Errorf("maintenance window for region %q is empty", window.Region)
+	}
+	for _, day := range window.Days {
+		if day < 0 || day > 6 {
+			return fmt.Errorf("invalid weekday %d, expected 0 (Sunday) to 6", day)
+		}
+	}
+	return nil
+}
+
+// dayAllowed reports whether a window may start on the given weekday
+func dayAllowed(days []int, weekday time.Weekday) bool {
+	if len(days) == 0 {
+		return true
+	}
+	for _, day := range days {
+		if time.Weekday(day) == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// windowOpen reports whether now falls inside the window.
+// A window crossing midnight belongs to the day it starts on.
+func windowOpen(window models.MaintenanceWindow, now time.Time) bool {
+	start, err1 := parseClock(window.Start)
+	end, err2 := parseClock(window.End)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	if start < end {
+		return minute >= start && minute < end && dayAllowed(window.Days, now.Weekday())
+	}
+	if minute >= start {
+		return dayAllowed(window.Days, now.Weekday())
+	}
+	if minute < end {
+		return dayAllowed(window.Days, now.AddDate(0, 0, -1).Weekday())
+	}
+	return false
+}
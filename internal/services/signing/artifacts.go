@@ -0,0 +1,145 @@
+package signing
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"application-updater/internal/models"
+	"application-updater/internal/utils"
+)
+
+const (
+	bundleManifestFile  = "bundle.json"
+	bundleSignatureFile = "bundle.sig"
+
+	// ArtifactBundleExtension is the file extension of multi-artifact bundles
+	ArtifactBundleExtension = ".aubundle"
+)
+
+// ArtifactBundle is a multi-artifact bundle whose files are in Dir
+type ArtifactBundle struct {
+	Manifest  models.BundleManifest
+	Signature string // 为空表示未签名
+	Dir       string
+}
+
+// IsArtifactBundle reports whether path is a multi-artifact bundle archive
+func IsArtifactBundle(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ArtifactBundleExtension)
+}
+
+// ArtifactPath resolves an artifact file inside the bundle directory, rejecting paths that leave it
+func ArtifactPath(dir, file string) (string, error) {
+	if file == "" || filepath.IsAbs(file) {
+		return "", fmt.Errorf("invalid artifact file: %q", file)
+	}
+	clean := filepath.Clean(filepath.FromSlash(file))
+	if clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid artifact file: %q", file)
+	}
+	return filepath.Join(dir, clean), nil
+}
+
+// LoadArtifactBundle reads an unpacked bundle from its manifest file. The signature is optional,
+// the SHA-256 of every file that lists one is checked.
+func LoadArtifactBundle(manifestPath string) (ArtifactBundle, error) {
+	bundle := ArtifactBundle{Dir: filepath.Dir(manifestPath)}
+	if err := utils.LoadConfig(manifestPath, &bundle.Manifest); err != nil {
+		return ArtifactBundle{}, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+	if signature, err := os.ReadFile(filepath.Join(bundle.Dir, bundleSignatureFile)); err == nil {
+		bundle.Signature = strings.TrimSpace(string(signature))
+	}
+
+	for _, artifact := range bundle.Manifest.Artifacts {
+		if artifact.File == "" {
+			continue
+		}
+		path, err := ArtifactPath(bundle.Dir, artifact.File)
+		if err != nil {
+			return ArtifactBundle{}, err
+		}
+		_, sha, err := utils.FileChecksums(path)
+		if err != nil {
+			return ArtifactBundle{}, fmt.Errorf("artifact %s: %w", artifact.Name, err)
+		}
+		if artifact.SHA256 != "" && sha != artifact.SHA256 {
+			return ArtifactBundle{}, fmt.Errorf("artifact %s: %w", artifact.Name, ErrTampered)
+		}
+	}
+	return bundle, nil
+}
+
+// OpenArtifactBundle extracts a bundle archive into dir and loads it
+func OpenArtifactBundle(path, dir string) (ArtifactBundle, error) {
+	if err := utils.UnzipFile(path, dir); err != nil {
+		return ArtifactBundle{}, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "*", bundleManifestFile))
+	if len(matches) != 1 {
+		return ArtifactBundle{}, fmt.Errorf("invalid bundle: expected exactly one %s", bundleManifestFile)
+	}
+	return LoadArtifactBundle(matches[0])
+}
+
+// WriteArtifactBundle fills in the checksums of the files listed in a manifest, signs it and
+// writes the manifest, the signature and the files to a zip archive.
+func WriteArtifactBundle(destination, manifestPath, privateKey string) (models.BundleManifest, error) {
+	key, err := ParsePrivateKey(privateKey)
+	if err != nil {
+		return models.BundleManifest{}, err
+	}
+	var manifest models.BundleManifest
+	if err := utils.LoadConfig(manifestPath, &manifest); err != nil {
+		return manifest, fmt.Errorf("invalid bundle manifest: %w", err)
+	}
+	source := filepath.Dir(manifestPath)
+
+	stagingRoot, err := os.MkdirTemp("", "bundle-sign-*")
+	if err != nil {
+		return manifest, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingRoot)
+	staging := filepath.Join(stagingRoot, "bundle")
+
+	for i := range manifest.Artifacts {
+		artifact := &manifest.Artifacts[i]
+		if artifact.File == "" {
+			continue
+		}
+		path, err := ArtifactPath(source, artifact.File)
+		if err != nil {
+			return manifest, err
+		}
+		if _, artifact.SHA256, err = utils.FileChecksums(path); err != nil {
+			return manifest, fmt.Errorf("artifact %s: %w", artifact.Name, err)
+		}
+		target, _ := ArtifactPath(staging, artifact.File)
+		if err := utils.EnsureDirExists(filepath.Dir(target)); err != nil {
+			return manifest, err
+		}
+		if err := utils.CopyFile(path, target); err != nil {
+			return manifest, fmt.Errorf("failed to copy artifact %s: %w", artifact.Name, err)
+		}
+	}
+
+	manifest.SignedAt = time.Now().Format(timeLayout)
+	manifest, signature, err := SignBundleManifest(manifest, key)
+	if err != nil {
+		return manifest, err
+	}
+	if err := utils.SaveConfig(filepath.Join(staging, bundleManifestFile), manifest); err != nil {
+		return manifest, err
+	}
+	if err := os.WriteFile(filepath.Join(staging, bundleSignatureFile), []byte(signature), 0644); err != nil {
+		return manifest, fmt.Errorf("failed to write signature: %w", err)
+	}
+
+	if err := utils.ZipDirectory(staging, destination); err != nil {
+		return manifest, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return manifest, nil
+}
@@ -0,0 +1,138 @@
+package signing
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"application-updater/internal/models"
+	"application-updater/internal/utils"
+)
+
+const (
+	manifestFile  = "manifest.json"
+	signatureFile = "manifest.sig"
+
+	// BundleExtension is the file extension of signed packages
+	BundleExtension = ".aupkg"
+)
+
+const timeLayout = "2006-01-02 15:04:05"
+
+// Bundle is a signed package extracted to a local directory
+type Bundle struct {
+	Manifest    models.PackageManifest
+	Signature   string
+	FilePath    string
+	MD5FilePath string
+}
+
+// WriteBundle signs a binary and writes it, its MD5 file, the manifest and the signature to a zip archive.
+// The checksums, size and signing time of the manifest are filled in here.
+func WriteBundle(destination, binaryPath string, manifest models.PackageManifest, privateKey string) (models.PackageManifest, error) {
+	key, err := ParsePrivateKey(privateKey)
+	if err != nil {
+		return manifest, err
+	}
+
+	info, err := os.Stat(binaryPath)
+	if err != nil {
+		return manifest, fmt.Errorf("failed to read binary: %w", err)
+	}
+	if info.Size() == 0 {
+		return manifest, fmt.Errorf("binary %s is empty", binaryPath)
+	}
+	md5sum, sha, err := utils.FileChecksums(binaryPath)
+	if err != nil {
+		return manifest, fmt.Errorf("failed to read binary: %w", err)
+	}
+	manifest.FileName = filepath.Base(binaryPath)
+	manifest.Size = info.Size()
+	manifest.SHA256 = sha
+	manifest.MD5 = md5sum
+	manifest.SignedAt = time.Now().Format(timeLayout)
+
+	manifest, signature, err := SignManifest(manifest, key)
+	if err != nil {
+		return manifest, err
+	}
+
+	stagingRoot, err := os.MkdirTemp("", "package-sign-*")
+	if err != nil {
+		return manifest, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingRoot)
+
+	// The archive stores the package under a single top-level directory named after the binary
+	staging := filepath.Join(stagingRoot, strings.TrimSuffix(manifest.FileName, filepath.Ext(manifest.FileName)))
+	if err := utils.EnsureDirExists(staging); err != nil {
+		return manifest, err
+	}
+	if err := utils.CopyFile(binaryPath, filepath.Join(staging, manifest.FileName)); err != nil {
+		return manifest, fmt.Errorf("failed to copy binary: %w", err)
+	}
+	md5Content := fmt.Sprintf("%s  %s\n", manifest.MD5, manifest.FileName)
+	if err := os.WriteFile(filepath.Join(staging, manifest.FileName+".md5"), []byte(md5Content), 0644); err != nil {
+		return manifest, fmt.Errorf("failed to write MD5 file: %w", err)
+	}
+	if err := utils.SaveConfig(filepath.Join(staging, manifestFile), manifest); err != nil {
+		return manifest, err
+	}
+	if err := os.WriteFile(filepath.Join(staging, signatureFile), []byte(signature), 0644); err != nil {
+		return manifest, fmt.Errorf("failed to write signature: %w", err)
+	}
+
+	if err := utils.ZipDirectory(staging, destination); err != nil {
+		return manifest, fmt.Errorf("failed to write package: %w", err)
+	}
+	return manifest, nil
+}
+
+// IsBundle reports whether path is a signed package rather than a bare binary
+func IsBundle(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), BundleExtension)
+}
+
+// OpenBundle extracts a package into dir and checks that the binary matches the manifest.
+// The signature itself is checked against the trusted keys by VerifyManifest.
+func OpenBundle(path, dir string) (Bundle, error) {
+	if err := utils.UnzipFile(path, dir); err != nil {
+		return Bundle{}, fmt.Errorf("failed to read package: %w", err)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(dir, "*", manifestFile))
+	if len(matches) != 1 {
+		return Bundle{}, fmt.Errorf("invalid package: expected exactly one %s", manifestFile)
+	}
+	root := filepath.Dir(matches[0])
+
+	var bundle Bundle
+	if err := utils.LoadConfig(matches[0], &bundle.Manifest); err != nil {
+		return Bundle{}, fmt.Errorf("invalid package manifest: %w", err)
+	}
+	signature, err := os.ReadFile(filepath.Join(root, signatureFile))
+	if err != nil {
+		return Bundle{}, ErrUnsigned
+	}
+	bundle.Signature = strings.TrimSpace(string(signature))
+
+	name := filepath.Base(bundle.Manifest.FileName)
+	if name != bundle.Manifest.FileName || name == manifestFile || name == signatureFile {
+		return Bundle{}, fmt.Errorf("invalid file name in manifest: %s", bundle.Manifest.FileName)
+	}
+	bundle.FilePath = filepath.Join(root, name)
+	if md5Path := bundle.FilePath + ".md5"; utils.FileExists(md5Path) {
+		bundle.MD5FilePath = md5Path
+	}
+
+	md5sum, sha, err := utils.FileChecksums(bundle.FilePath)
+	if err != nil {
+		return Bundle{}, fmt.Errorf("invalid package: %w", err)
+	}
+	if sha != bundle.Manifest.SHA256 || md5sum != bundle.Manifest.MD5 {
+		return Bundle{}, ErrTampered
+	}
+	return bundle, nil
+}
@@ -0,0 +1,87 @@
+package signing
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"application-updater/internal/models"
+	"application-updater/internal/utils"
+)
+
+const keysFile = "trusted_keys.json"
+
+// KeyStore keeps the list of public keys whose signatures are trusted
+type KeyStore struct {
+	mutex sync.Mutex
+	path  string
+}
+
+// NewKeyStore opens the trusted key list stored in dir
+func NewKeyStore(dir string) *KeyStore {
+	return &KeyStore{path: filepath.Join(dir, keysFile)}
+}
+
+// load reads the key list, the caller must hold k.mutex
+func (k *KeyStore) load() []models.TrustedKey {
+	keys := []models.TrustedKey{}
+	if utils.FileExists(k.path) {
+		if err := utils.LoadConfig(k.path, &keys); err != nil {
+			fmt.Printf("Failed to load trusted keys: %v\n", err)
+		}
+	}
+	return keys
+}
+
+// List returns all trusted keys
+func (k *KeyStore) List() []models.TrustedKey {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	return k.load()
+}
+
+// Add trusts a base64 ed25519 public key
+func (k *KeyStore) Add(name, publicKey string) (models.TrustedKey, error) {
+	name, publicKey = strings.TrimSpace(name), strings.TrimSpace(publicKey)
+	if name == "" {
+		return models.TrustedKey{}, fmt.Errorf("key name is required")
+	}
+	parsed, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return models.TrustedKey{}, err
+	}
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	key := models.TrustedKey{ID: KeyID(parsed), Name: name, PublicKey: publicKey, AddedAt: time.Now().Format(timeLayout)}
+	keys := k.load()
+	for _, existing := range keys {
+		if existing.ID == key.ID {
+			return models.TrustedKey{}, fmt.Errorf("key is already trusted as %s", existing.Name)
+		}
+	}
+	if err := utils.SaveConfig(k.path, append(keys, key)); err != nil {
+		return models.TrustedKey{}, err
+	}
+	return key, nil
+}
+
+// Remove stops trusting a key, packages it signed can no longer be deployed without an override
+func (k *KeyStore) Remove(id string) (models.TrustedKey, error) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	keys := k.load()
+	for i, key := range keys {
+		if key.ID == id {
+			if err := utils.SaveConfig(k.path, append(keys[:i], keys[i+1:]...)); err != nil {
+				return models.TrustedKey{}, err
+			}
+			return key, nil
+		}
+	}
+	return models.TrustedKey{}, fmt.Errorf("trusted key %s not found", id)
+}
@@ -0,0 +1,124 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"application-updater/internal/models"
+)
+
+// Reasons a package may not be deployed without an override
+var (
+	ErrUnsigned  = errors.New("package is not signed")
+	ErrUntrusted = errors.New("package is signed by a key that is not trusted")
+	ErrTampered  = errors.New("package signature does not match, it may have been tampered with")
+)
+
+// KeyID derives the short ID of a public key used to look it up in the trusted list
+func KeyID(publicKey ed25519.PublicKey) string {
+	sum := sha256.Sum256(publicKey)
+	return hex.EncodeToString(sum[:8])
+}
+
+// GenerateKey creates a new key pair, both keys base64 encoded
+func GenerateKey() (publicKey, privateKey string, err error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(public), base64.StdEncoding.EncodeToString(private), nil
+}
+
+// ParsePublicKey decodes a base64 ed25519 public key
+func ParsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid ed25519 public key")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// ParsePrivateKey decodes a base64 ed25519 private key
+func ParsePrivateKey(encoded string) (ed25519.PrivateKey, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid ed25519 private key")
+	}
+	return ed25519.PrivateKey(key), nil
+}
+
+// SignManifest stamps the manifest with the key ID and returns it with its base64 signature
+func SignManifest(manifest models.PackageManifest, privateKey ed25519.PrivateKey) (models.PackageManifest, string, error) {
+	manifest.KeyID = KeyID(privateKey.Public().(ed25519.PublicKey))
+	signature, err := sign(manifest, privateKey)
+	return manifest, signature, err
+}
+
+// VerifyManifest checks the signature against the trusted keys and returns the key that signed it
+func VerifyManifest(manifest *models.PackageManifest, signature string, keys []models.TrustedKey) (models.TrustedKey, error) {
+	if manifest == nil || signature == "" {
+		return models.TrustedKey{}, ErrUnsigned
+	}
+	return verify(manifest, manifest.KeyID, signature, keys)
+}
+
+// SignBundleManifest stamps a bundle manifest with the key ID and returns it with its base64 signature
+func SignBundleManifest(manifest models.BundleManifest, privateKey ed25519.PrivateKey) (models.BundleManifest, string, error) {
+	manifest.KeyID = KeyID(privateKey.Public().(ed25519.PublicKey))
+	signature, err := sign(manifest, privateKey)
+	return manifest, signature, err
+}
+
+// VerifyBundleManifest checks a bundle signature against the trusted keys and returns the key that signed it
+func VerifyBundleManifest(manifest *models.BundleManifest, signature string, keys []models.TrustedKey) (models.TrustedKey, error) {
+	if manifest == nil || signature == "" {
+		return models.TrustedKey{}, ErrUnsigned
+	}
+	return verify(manifest, manifest.KeyID, signature, keys)
+}
+
+// sign returns the base64 signature of the JSON encoding of v
+func sign(v interface{}, privateKey ed25519.PrivateKey) (string, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, payload)), nil
+}
+
+// verify checks the signature of the JSON encoding of v with the trusted key keyID
+func verify(v interface{}, keyID, signature string, keys []models.TrustedKey) (models.TrustedKey, error) {
+	var signer *models.TrustedKey
+	for i := range keys {
+		if keys[i].ID == keyID {
+			signer = &keys[i]
+			break
+		}
+	}
+	if signer == nil {
+		return models.TrustedKey{}, fmt.Errorf("%w (%s)", ErrUntrusted, keyID)
+	}
+
+	publicKey, err := ParsePublicKey(signer.PublicKey)
+	if err != nil {
+		return models.TrustedKey{}, err
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return models.TrustedKey{}, ErrTampered
+	}
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return models.TrustedKey{}, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if !ed25519.Verify(publicKey, payload, sig) {
+		return models.TrustedKey{}, ErrTampered
+	}
+	return *signer, nil
+}
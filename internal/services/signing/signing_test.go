@@ -0,0 +1,141 @@
+package signing
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"application-updater/internal/models"
+	"application-updater/internal/utils"
+)
+
+func newKey(t *testing.T) (models.TrustedKey, string) {
+	t.Helper()
+	publicKey, privateKey, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _ := ParsePublicKey(publicKey)
+	return models.TrustedKey{ID: KeyID(parsed), Name: "release", PublicKey: publicKey}, privateKey
+}
+
+func writeBundle(t *testing.T, privateKey string) string {
+	t.Helper()
+	binary := filepath.Join(t.TempDir(), "application-web")
+	if err := os.WriteFile(binary, []byte("binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "application-web"+BundleExtension)
+	if _, err := WriteBundle(path, binary, models.PackageManifest{TargetBuild: "2024-05-01 10:00:00"}, privateKey); err != nil {
+		t.Fatalf("WriteBundle failed: %v", err)
+	}
+	return path
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	key, privateKey := newKey(t)
+	path := writeBundle(t, privateKey)
+
+	bundle, err := OpenBundle(path, t.TempDir())
+	if err != nil {
+		t.Fatalf("OpenBundle failed: %v", err)
+	}
+	if bundle.Manifest.FileName != "application-web" || bundle.Manifest.TargetBuild != "2024-05-01 10:00:00" || bundle.MD5FilePath == "" {
+		t.Errorf("Unexpected bundle: %+v", bundle)
+	}
+	if content, _ := os.ReadFile(bundle.FilePath); string(content) != "binary" {
+		t.Errorf("Expected extracted binary, got %q", content)
+	}
+	signer, err := VerifyManifest(&bundle.Manifest, bundle.Signature, []models.TrustedKey{key})
+	if err != nil || signer.ID != key.ID {
+		t.Errorf("Expected signature by %s to verify, got %+v err=%v", key.ID, signer, err)
+	}
+}
+
+func TestVerifyManifestRejects(t *testing.T) {
+	key, privateKey := newKey(t)
+	other, _ := newKey(t)
+	parsed, _ := ParsePrivateKey(privateKey)
+	manifest, signature, err := SignManifest(models.PackageManifest{FileName: "application-web", SHA256: "abc"}, parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := VerifyManifest(&manifest, "", []models.TrustedKey{key}); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("Expected ErrUnsigned, got %v", err)
+	}
+	if _, err := VerifyManifest(&manifest, signature, []models.TrustedKey{other}); !errors.Is(err, ErrUntrusted) {
+		t.Errorf("Expected ErrUntrusted, got %v", err)
+	}
+
+	tampered := manifest
+	tampered.MinBuild = "2020-01-01 00:00:00"
+	if _, err := VerifyManifest(&tampered, signature, []models.TrustedKey{key}); !errors.Is(err, ErrTampered) {
+		t.Errorf("Expected changed manifest to be rejected, got %v", err)
+	}
+}
+
+func TestOpenBundleDetectsReplacedBinary(t *testing.T) {
+	_, privateKey := newKey(t)
+	path := writeBundle(t, privateKey)
+
+	dir := t.TempDir()
+	if _, err := OpenBundle(path, dir); err != nil {
+		t.Fatal(err)
+	}
+	// Swap the binary and repack it with the original manifest and signature
+	os.WriteFile(filepath.Join(dir, "application-web", "application-web"), []byte("evil"), 0755)
+	repacked := filepath.Join(t.TempDir(), "repacked"+BundleExtension)
+	if err := utils.ZipDirectory(filepath.Join(dir, "application-web"), repacked); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenBundle(repacked, t.TempDir()); !errors.Is(err, ErrTampered) {
+		t.Errorf("Expected ErrTampered for a replaced binary, got %v", err)
+	}
+}
+
+func TestArtifactBundleRoundTrip(t *testing.T) {
+	key, privateKey := newKey(t)
+	source := t.TempDir()
+	os.MkdirAll(filepath.Join(source, "config"), 0755)
+	os.WriteFile(filepath.Join(source, "config", "app.yaml"), []byte("config"), 0644)
+	manifest := models.BundleManifest{
+		Name: "release",
+		Artifacts: []models.BundleArtifact{
+			{Name: "config", Delivery: models.DeliverySSH, File: "config/app.yaml", Path: "/etc/app.yaml"},
+			{Name: "restart", Delivery: models.DeliveryCommand, Command: "systemctl restart application-web"},
+		},
+	}
+	manifestPath := filepath.Join(source, "bundle.json")
+	if err := utils.SaveConfig(manifestPath, manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "release"+ArtifactBundleExtension)
+	if _, err := WriteArtifactBundle(path, manifestPath, privateKey); err != nil {
+		t.Fatalf("WriteArtifactBundle failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	bundle, err := OpenArtifactBundle(path, dir)
+	if err != nil {
+		t.Fatalf("OpenArtifactBundle failed: %v", err)
+	}
+	if bundle.Manifest.Artifacts[0].SHA256 == "" {
+		t.Errorf("Expected artifact checksums to be filled in, got %+v", bundle.Manifest)
+	}
+	if _, err := VerifyBundleManifest(&bundle.Manifest, bundle.Signature, []models.TrustedKey{key}); err != nil {
+		t.Errorf("Expected the bundle signature to verify: %v", err)
+	}
+
+	// A changed file is detected through the signed checksum
+	os.WriteFile(filepath.Join(bundle.Dir, "config", "app.yaml"), []byte("evil"), 0644)
+	if _, err := LoadArtifactBundle(filepath.Join(bundle.Dir, bundleManifestFile)); !errors.Is(err, ErrTampered) {
+		t.Errorf("Expected ErrTampered for a changed artifact, got %v", err)
+	}
+	if _, err := ArtifactPath(dir, "../outside"); err == nil {
+		t.Errorf("Expected a path leaving the bundle to be rejected")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"application-updater/internal/models"
+	"application-updater/internal/services/lock"
 
 	"golang.org/x/crypto/ssh"
 )
@@ -91,6 +92,14 @@ func (s *Service) syncSingleDeviceTime(deviceIP, username, password, dateTimeStr
 		Timestamp: currentTime.Format("2006-01-02 15:04:05"),
 	}
 
+	// 设备上有其他操作(如升级、备份)进行中时跳过
+	release, err := lock.Default().TryAcquire(deviceIP, lock.OperationTimeSync)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	defer release()
+
 	// 创建SSH客户端配置
 	config := &ssh.ClientConfig{
 		User: username,
@@ -0,0 +1,115 @@
+package transfer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// minLatencySignal is the round trip below which latency is never treated as congestion,
+// so that jitter on a fast local link does not lower the limit
+const minLatencySignal = 200 * time.Millisecond
+
+// Concurrency bounds how many transfers run at once. In adaptive mode the limit is
+// halved when a transfer fails, its throughput drops below half of the recent average
+// or the latency to the device rises above three times the recent average, and raised
+// by one after a full round of healthy transfers.
+type Concurrency struct {
+	mutex    sync.Mutex
+	cond     *sync.Cond
+	active   int
+	limit    int
+	min      int
+	max      int
+	adaptive bool
+
+	healthy    int           // healthy transfers since the last change
+	throughput float64       // moving average in bytes per second
+	latency    time.Duration // moving average of the round trip to a device while transfers run
+}
+
+func newConcurrency(max, min int, adaptive bool) *Concurrency {
+	c := &Concurrency{limit: max, min: min, max: max, adaptive: adaptive}
+	c.cond = sync.NewCond(&c.mutex)
+	return c
+}
+
+// Acquire blocks until a transfer slot is free
+func (c *Concurrency) Acquire() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for c.active >= c.limit {
+		c.cond.Wait()
+	}
+	c.active++
+}
+
+// Release frees a slot and, in adaptive mode, feeds the outcome of the transfer into the limit.
+// latency is the round trip of a small request to the device made while the slot was held, 0 if there was none.
+func (c *Concurrency) Release(err error, bytes int64, elapsed, latency time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.active--
+	if c.adaptive {
+		c.adapt(err, bytes, elapsed, latency)
+	}
+	c.cond.Broadcast()
+}
+
+// Limit returns the current number of parallel transfers
+func (c *Concurrency) Limit() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.limit
+}
+
+// adapt adjusts the limit, the caller must hold c.mutex. Throughput is scaled by the
+// number of transfers that were running so that sharing a bandwidth cap between more
+// uploads does not look like a degrading link. Latency rises when the uploads saturate
+// the link, even before throughput or errors show it.
+func (c *Concurrency) adapt(err error, bytes int64, elapsed, latency time.Duration) {
+	// Devices that never started uploading say nothing about the link
+	if err == nil && elapsed == 0 {
+		return
+	}
+
+	degraded := err != nil
+	if !degraded && latency > 0 {
+		if c.latency > 0 && latency > 3*c.latency && latency > minLatencySignal {
+			degraded = true
+		}
+		if c.latency == 0 {
+			c.latency = latency
+		} else {
+			c.latency = (7*c.latency + 3*latency) / 10
+		}
+	}
+	if !degraded && bytes > 0 && elapsed > 0 {
+		current := float64(bytes) / elapsed.Seconds() * float64(c.active+1)
+		if c.throughput > 0 && current < c.throughput/2 {
+			degraded = true
+		}
+		if c.throughput == 0 {
+			c.throughput = current
+		} else {
+			c.throughput = 0.7*c.throughput + 0.3*current
+		}
+	}
+
+	if degraded {
+		c.healthy = 0
+		if next := c.limit / 2; next >= c.min && next < c.limit {
+			fmt.Printf("Transfers degrading, lowering concurrency from %d to %d\n", c.limit, next)
+			c.limit = next
+		} else if c.limit > c.min {
+			c.limit = c.min
+		}
+		return
+	}
+
+	c.healthy++
+	if c.healthy >= c.limit && c.limit < c.max {
+		c.healthy = 0
+		c.limit++
+	}
+}
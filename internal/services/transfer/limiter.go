@@ -0,0 +1,73 @@
+package transfer
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// chunkSize caps a single read so that limited transfers stay smooth
+const chunkSize = 32 * 1024
+
+// bucket is a token bucket limiting a byte rate, a rate of 0 means unlimited
+type bucket struct {
+	mutex  sync.Mutex
+	rate   float64 // bytes per second
+	tokens float64
+	last   time.Time
+}
+
+// setRate changes the rate in bytes per second
+func (b *bucket) setRate(bytesPerSecond int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.rate = float64(bytesPerSecond)
+	b.tokens = 0
+	b.last = time.Now()
+}
+
+// wait blocks until n bytes may be transferred
+func (b *bucket) wait(n int) {
+	b.mutex.Lock()
+	if b.rate <= 0 {
+		b.mutex.Unlock()
+		return
+	}
+
+	now := time.Now()
+	// Allow a burst of a quarter second so short pauses are not lost entirely
+	burst := b.rate / 4
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+	b.tokens -= float64(n)
+
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	b.mutex.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// limitedReader waits on every bucket before handing data on
+type limitedReader struct {
+	r       io.Reader
+	buckets []*bucket
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if len(p) > chunkSize {
+		p = p[:chunkSize]
+	}
+	n, err := l.r.Read(p)
+	for _, b := range l.buckets {
+		b.wait(n)
+	}
+	return n, err
+}
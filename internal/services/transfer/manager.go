@@ -0,0 +1,112 @@
+package transfer
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+
+	"application-updater/internal/models"
+	"application-updater/internal/utils"
+)
+
+const (
+	settingsFile = "transfer_settings.json"
+
+	// DefaultMaxConcurrent is the number of parallel uploads when none is configured
+	DefaultMaxConcurrent = 8
+)
+
+// Manager applies the bandwidth caps and concurrency settings of a workspace
+type Manager struct {
+	mutex    sync.Mutex
+	dir      string
+	settings models.TransferSettings
+	global   *bucket
+	regions  map[string]*bucket
+}
+
+// NewManager loads the transfer settings stored in dir
+func NewManager(dir string) *Manager {
+	m := &Manager{dir: dir, global: &bucket{}, regions: make(map[string]*bucket)}
+
+	var settings models.TransferSettings
+	if path := filepath.Join(dir, settingsFile); utils.FileExists(path) {
+		if err := utils.LoadConfig(path, &settings); err != nil {
+			fmt.Printf("Failed to load transfer settings: %v\n", err)
+		}
+	}
+	m.apply(settings)
+	return m
+}
+
+// withDefaults fills in unset concurrency settings
+func withDefaults(settings models.TransferSettings) models.TransferSettings {
+	if settings.MaxConcurrent <= 0 {
+		settings.MaxConcurrent = DefaultMaxConcurrent
+	}
+	if settings.MinConcurrent <= 0 {
+		settings.MinConcurrent = 1
+	}
+	if settings.MinConcurrent > settings.MaxConcurrent {
+		settings.MinConcurrent = settings.MaxConcurrent
+	}
+	return settings
+}
+
+// apply switches the buckets to new settings, transfers in progress pick up the new rates
+func (m *Manager) apply(settings models.TransferSettings) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.settings = withDefaults(settings)
+	m.global.setRate(settings.GlobalLimitKBps * 1024)
+	for region, b := range m.regions {
+		b.setRate(settings.RegionLimitsKBps[region] * 1024)
+	}
+}
+
+// Settings returns the current settings with defaults filled in
+func (m *Manager) Settings() models.TransferSettings {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.settings
+}
+
+// SaveSettings validates, stores and applies new settings
+func (m *Manager) SaveSettings(settings models.TransferSettings) error {
+	if settings.GlobalLimitKBps < 0 || settings.MaxConcurrent < 0 || settings.MinConcurrent < 0 {
+		return fmt.Errorf("transfer limits must not be negative")
+	}
+	for region, limit := range settings.RegionLimitsKBps {
+		if limit < 0 {
+			return fmt.Errorf("bandwidth limit of region %s must not be negative", region)
+		}
+	}
+
+	if err := utils.SaveConfig(filepath.Join(m.dir, settingsFile), settings); err != nil {
+		return err
+	}
+	m.apply(settings)
+	return nil
+}
+
+// LimitReader wraps r so that reading from it respects the global and the region's bandwidth cap
+func (m *Manager) LimitReader(region string, r io.Reader) io.Reader {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	b, ok := m.regions[region]
+	if !ok {
+		b = &bucket{}
+		b.setRate(m.settings.RegionLimitsKBps[region] * 1024)
+		m.regions[region] = b
+	}
+	return &limitedReader{r: r, buckets: []*bucket{m.global, b}}
+}
+
+// NewConcurrency returns a limiter for one batch of transfers
+func (m *Manager) NewConcurrency() *Concurrency {
+	settings := m.Settings()
+	return newConcurrency(settings.MaxConcurrent, settings.MinConcurrent, settings.Adaptive)
+}
@@ -0,0 +1,97 @@
+package transfer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"application-updater/internal/models"
+)
+
+func TestLimitReaderCapsRate(t *testing.T) {
+	m := NewManager(t.TempDir())
+	if err := m.SaveSettings(models.TransferSettings{RegionLimitsKBps: map[string]int{"north": 64}}); err != nil {
+		t.Fatalf("SaveSettings failed: %v", err)
+	}
+
+	// 48KB at 64KB/s with a 16KB burst must take noticeably longer than the quarter-second burst allows for free
+	start := time.Now()
+	n, err := io.Copy(io.Discard, m.LimitReader("north", bytes.NewReader(make([]byte, 48*1024))))
+	if err != nil || n != 48*1024 {
+		t.Fatalf("Expected all bytes to be copied, got %d, %v", n, err)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("Expected the region cap to slow the copy down, took %v", elapsed)
+	}
+
+	// Other regions are not affected by the cap
+	start = time.Now()
+	io.Copy(io.Discard, m.LimitReader("south", bytes.NewReader(make([]byte, 48*1024))))
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Expected an uncapped region to copy at full speed, took %v", elapsed)
+	}
+}
+
+func TestAdaptiveConcurrency(t *testing.T) {
+	c := newConcurrency(8, 2, true)
+
+	c.Acquire()
+	c.Release(errors.New("connection reset"), 0, time.Second, 0)
+	if c.Limit() != 4 {
+		t.Fatalf("Expected a failed transfer to halve the limit to 4, got %d", c.Limit())
+	}
+	c.Acquire()
+	c.Release(errors.New("connection reset"), 0, time.Second, 0)
+	c.Acquire()
+	c.Release(errors.New("connection reset"), 0, time.Second, 0)
+	if c.Limit() != 2 {
+		t.Fatalf("Expected the limit to stop at the minimum of 2, got %d", c.Limit())
+	}
+
+	// A round of healthy transfers raises the limit again
+	for i := 0; i < 2; i++ {
+		c.Acquire()
+		c.Release(nil, 1<<20, time.Second, 0)
+	}
+	if c.Limit() != 3 {
+		t.Errorf("Expected healthy transfers to raise the limit to 3, got %d", c.Limit())
+	}
+
+	// Devices that never reached the upload do not count
+	c.Acquire()
+	c.Release(nil, 1<<20, 0, 0)
+	if c.Limit() != 3 {
+		t.Errorf("Expected a skipped device to leave the limit at 3, got %d", c.Limit())
+	}
+}
+
+func TestFixedConcurrency(t *testing.T) {
+	c := newConcurrency(3, 1, false)
+	c.Acquire()
+	c.Release(errors.New("timeout"), 0, time.Second, 0)
+	if c.Limit() != 3 {
+		t.Errorf("Expected a fixed limit to ignore failures, got %d", c.Limit())
+	}
+}
+
+func TestAdaptiveConcurrencyLatency(t *testing.T) {
+	c := newConcurrency(8, 1, true)
+
+	// Jitter on a fast link stays below the latency signal
+	for _, latency := range []time.Duration{20 * time.Millisecond, 80 * time.Millisecond} {
+		c.Acquire()
+		c.Release(nil, 1<<20, time.Second, latency)
+	}
+	if c.Limit() != 8 {
+		t.Fatalf("Expected small latency changes to keep the limit at 8, got %d", c.Limit())
+	}
+
+	// The round trip climbs while throughput holds, the link is saturated
+	c.Acquire()
+	c.Release(nil, 1<<20, time.Second, time.Second)
+	if c.Limit() != 4 {
+		t.Errorf("Expected rising latency to halve the limit to 4, got %d", c.Limit())
+	}
+}
@@ -0,0 +1,44 @@
+package workspace
+
+import (
+	"application-updater/internal/models"
+)
+
+// Manager defines the interface for workspace operations
+type Manager interface {
+	// List returns all workspaces, including archived ones
+	List() []models.Workspace
+
+	// Current returns the active workspace
+	Current() models.Workspace
+
+	// Dir returns the directory holding the data of a workspace
+	Dir(id string) string
+
+	// Create creates an empty workspace
+	Create(name, description string) (models.Workspace, error)
+
+	// Clone creates a new workspace from a copy of an existing one
+	Clone(sourceID, name string) (models.Workspace, error)
+
+	// Switch makes a workspace the active one
+	Switch(id string) (models.Workspace, error)
+
+	// Archive hides a workspace from switching without deleting its data
+	Archive(id string) error
+
+	// Unarchive makes an archived workspace available again
+	Unarchive(id string) error
+
+	// SaveCredentials stores the default device credentials of a workspace
+	SaveCredentials(id string, credentials models.WorkspaceCredentials) error
+
+	// Export writes a workspace bundle to a zip file
+	Export(id, destination string) error
+
+	// Import creates a new workspace from a bundle written by Export
+	Import(source string) (models.Workspace, error)
+}
+
+// Ensure Service implements Manager
+var _ Manager = (*Service)(nil)
@@ -0,0 +1,482 @@
+package workspace
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"application-updater/internal/models"
+	"application-updater/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultID is the workspace created on first start, holding any legacy data
+	DefaultID = "default"
+
+	// DatabaseFile is the device database inside a workspace directory
+	DatabaseFile = "devices.db"
+
+	workspaceFile = "workspace.json"
+	stateFile     = "workspaces.json"
+	timeLayout    = "2006-01-02 15:04:05"
+)
+
+// legacyFiles are the per-inventory files that used to live directly in the config directory
+var legacyFiles = []string{DatabaseFile, "devices.json", "device_settings.json", "backup_settings.json", "snapshots"}
+
+// state is persisted in workspaces.json and records the active workspace
+type state struct {
+	Current string `json:"current"`
+}
+
+// Service manages named workspaces, each stored in its own directory under configs/workspaces
+type Service struct {
+	mutex     sync.Mutex
+	configDir string
+	current   string
+
+	// snapshotDB copies the open database of the active workspace to the given path.
+	// Copying the file directly could capture a half-written transaction.
+	snapshotDB func(destination string) error
+}
+
+// NewService creates the workspace service, migrating legacy single-inventory data into the default workspace
+func NewService(configDir string) *Service {
+	s := &Service{configDir: configDir}
+
+	if err := utils.EnsureDirExists(s.rootDir()); err != nil {
+		fmt.Printf("Failed to create workspaces directory: %v\n", err)
+	}
+
+	if !utils.FileExists(filepath.Join(s.Dir(DefaultID), workspaceFile)) {
+		if err := s.migrateLegacyData(); err != nil {
+			fmt.Printf("Warning: Failed to migrate legacy data into default workspace: %v\n", err)
+		}
+	}
+
+	var st state
+	if err := utils.LoadConfig(filepath.Join(configDir, stateFile), &st); err == nil {
+		s.current = st.Current
+	}
+	if _, err := s.load(s.current); err != nil {
+		s.current = DefaultID
+	}
+
+	fmt.Printf("Active workspace: %s\n", s.current)
+	return s
+}
+
+// SetDatabaseSnapshotter sets the function used to copy the database of the active workspace
+func (s *Service) SetDatabaseSnapshotter(snapshotDB func(destination string) error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.snapshotDB = snapshotDB
+}
+
+// rootDir returns the directory containing all workspaces
+func (s *Service) rootDir() string {
+	return filepath.Join(s.configDir, "workspaces")
+}
+
+// Dir returns the directory holding the data of a workspace
+func (s *Service) Dir(id string) string {
+	return filepath.Join(s.rootDir(), id)
+}
+
+// migrateLegacyData creates the default workspace and moves the old top-level files into it
+func (s *Service) migrateLegacyData() error {
+	dir := s.Dir(DefaultID)
+	if err := utils.EnsureDirExists(dir); err != nil {
+		return fmt.Errorf("failed to create default workspace: %w", err)
+	}
+
+	for _, name := range legacyFiles {
+		legacyPath := filepath.Join(s.configDir, name)
+		if _, err := os.Stat(legacyPath); err != nil {
+			continue
+		}
+		if err := os.Rename(legacyPath, filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("failed to move %s: %w", name, err)
+		}
+		fmt.Printf("Moved legacy %s into default workspace\n", name)
+	}
+
+	// Backup settings used to be stored relative to the working directory
+	cwdSettings := filepath.Join("configs", "backup_settings.json")
+	target := filepath.Join(dir, "backup_settings.json")
+	if utils.FileExists(cwdSettings) && !utils.FileExists(target) {
+		if err := utils.CopyFile(cwdSettings, target); err != nil {
+			return fmt.Errorf("failed to copy backup settings: %w", err)
+		}
+	}
+
+	return s.save(models.Workspace{
+		ID:        DefaultID,
+		Name:      "默认",
+		CreatedAt: time.Now().Format(timeLayout),
+	})
+}
+
+// load reads the metadata of a workspace
+func (s *Service) load(id string) (models.Workspace, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return models.Workspace{}, fmt.Errorf("invalid workspace id: %q", id)
+	}
+
+	var workspace models.Workspace
+	if err := utils.LoadConfig(filepath.Join(s.Dir(id), workspaceFile), &workspace); err != nil {
+		return models.Workspace{}, fmt.Errorf("workspace %s not found: %w", id, err)
+	}
+	workspace.ID = id
+	return workspace, nil
+}
+
+// save writes the metadata of a workspace
+func (s *Service) save(workspace models.Workspace) error {
+	return utils.SaveConfig(filepath.Join(s.Dir(workspace.ID), workspaceFile), workspace)
+}
+
+// List returns all workspaces sorted by name, including archived ones
+func (s *Service) List() []models.Workspace {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entries, err := os.ReadDir(s.rootDir())
+	if err != nil {
+		fmt.Printf("Failed to read workspaces directory: %v\n", err)
+		return []models.Workspace{}
+	}
+
+	workspaces := []models.Workspace{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		workspace, err := s.load(entry.Name())
+		if err != nil {
+			continue
+		}
+		workspaces = append(workspaces, workspace)
+	}
+
+	sort.Slice(workspaces, func(i, j int) bool {
+		return workspaces[i].Name < workspaces[j].Name
+	})
+	return workspaces
+}
+
+// Current returns the active workspace
+func (s *Service) Current() models.Workspace {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	workspace, err := s.load(s.current)
+	if err != nil {
+		return models.Workspace{ID: s.current, Name: s.current}
+	}
+	return workspace
+}
+
+// checkName rejects empty names and names already used by another workspace
+func (s *Service) checkName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("workspace name is empty")
+	}
+
+	entries, err := os.ReadDir(s.rootDir())
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		if workspace, err := s.load(entry.Name()); err == nil && workspace.Name == name {
+			return fmt.Errorf("workspace %q already exists", name)
+		}
+	}
+	return nil
+}
+
+// Create creates an empty workspace
+func (s *Service) Create(name, description string) (models.Workspace, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.checkName(name); err != nil {
+		return models.Workspace{}, err
+	}
+
+	workspace := models.Workspace{
+		ID:          uuid.New().String(),
+		Name:        strings.TrimSpace(name),
+		Description: description,
+		CreatedAt:   time.Now().Format(timeLayout),
+	}
+	if err := utils.EnsureDirExists(s.Dir(workspace.ID)); err != nil {
+		return models.Workspace{}, fmt.Errorf("failed to create workspace directory: %w", err)
+	}
+	if err := s.save(workspace); err != nil {
+		return models.Workspace{}, err
+	}
+
+	return workspace, nil
+}
+
+// copyWorkspace copies the data of a workspace into dst, taking a consistent copy of the
+// database if the workspace is currently open
+func (s *Service) copyWorkspace(id, dst string) error {
+	src := s.Dir(id)
+	live := id == s.current && s.snapshotDB != nil
+
+	err := utils.CopyDirectory(src, dst, func(relPath string) bool {
+		return live && (relPath == DatabaseFile || strings.HasPrefix(relPath, DatabaseFile+"-"))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy workspace: %w", err)
+	}
+
+	if live && utils.FileExists(filepath.Join(src, DatabaseFile)) {
+		if err := s.snapshotDB(filepath.Join(dst, DatabaseFile)); err != nil {
+			return fmt.Errorf("failed to copy device database: %w", err)
+		}
+	}
+	return nil
+}
+
+// Clone creates a new workspace from a copy of an existing one
+func (s *Service) Clone(sourceID, name string) (models.Workspace, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	source, err := s.load(sourceID)
+	if err != nil {
+		return models.Workspace{}, err
+	}
+	if err := s.checkName(name); err != nil {
+		return models.Workspace{}, err
+	}
+
+	clone := source
+	clone.ID = uuid.New().String()
+	clone.Name = strings.TrimSpace(name)
+	clone.CreatedAt = time.Now().Format(timeLayout)
+	clone.Archived = false
+	clone.ArchivedAt = ""
+
+	if err := s.copyWorkspace(sourceID, s.Dir(clone.ID)); err != nil {
+		os.RemoveAll(s.Dir(clone.ID))
+		return models.Workspace{}, err
+	}
+	if err := s.save(clone); err != nil {
+		return models.Workspace{}, err
+	}
+
+	return clone, nil
+}
+
+// Switch makes a workspace the active one
+func (s *Service) Switch(id string) (models.Workspace, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	workspace, err := s.load(id)
+	if err != nil {
+		return models.Workspace{}, err
+	}
+	if workspace.Archived {
+		return models.Workspace{}, fmt.Errorf("workspace %q is archived", workspace.Name)
+	}
+
+	if err := utils.SaveConfig(filepath.Join(s.configDir, stateFile), state{Current: id}); err != nil {
+		return models.Workspace{}, fmt.Errorf("failed to save active workspace: %w", err)
+	}
+	s.current = id
+	s.snapshotDB = nil
+
+	return workspace, nil
+}
+
+// Archive hides a workspace from switching without deleting its data
+func (s *Service) Archive(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if id == s.current {
+		return fmt.Errorf("cannot archive the active workspace")
+	}
+
+	workspace, err := s.load(id)
+	if err != nil {
+		return err
+	}
+	workspace.Archived = true
+	workspace.ArchivedAt = time.Now().Format(timeLayout)
+	return s.save(workspace)
+}
+
+// Unarchive makes an archived workspace available again
+func (s *Service) Unarchive(id string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	workspace, err := s.load(id)
+	if err != nil {
+		return err
+	}
+	workspace.Archived = false
+	workspace.ArchivedAt = ""
+	return s.save(workspace)
+}
+
+// SaveCredentials stores the default device credentials of a workspace
+func (s *Service) SaveCredentials(id string, credentials models.WorkspaceCredentials) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	workspace, err := s.load(id)
+	if err != nil {
+		return err
+	}
+	workspace.Credentials = credentials
+	return s.save(workspace)
+}
+
+// Export writes a workspace bundle to a zip file. The bundle leaves out the default credentials
+// and every stored password, so it can be handed to someone else.
+func (s *Service) Export(id, destination string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, err := s.load(id); err != nil {
+		return err
+	}
+
+	stagingRoot, err := os.MkdirTemp("", "workspace-export-*")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingRoot)
+
+	// The archive stores the workspace under a single top-level directory named after its ID
+	staging := filepath.Join(stagingRoot, id)
+	if err := s.copyWorkspace(id, staging); err != nil {
+		return err
+	}
+	if err := stripSecrets(staging); err != nil {
+		return err
+	}
+
+	if err := utils.ZipDirectory(staging, destination); err != nil {
+		return fmt.Errorf("failed to write workspace bundle: %w", err)
+	}
+
+	fmt.Printf("Exported workspace %s to %s\n", id, destination)
+	return nil
+}
+
+// stripSecrets removes the default credentials from a staged workspace and blanks every password
+// field in its JSON files, such as those of update jobs, rollouts, schedules and backup settings
+func stripSecrets(dir string) error {
+	var workspace models.Workspace
+	path := filepath.Join(dir, workspaceFile)
+	if err := utils.LoadConfig(path, &workspace); err != nil {
+		return fmt.Errorf("failed to read workspace: %w", err)
+	}
+	workspace.Credentials = models.WorkspaceCredentials{}
+	if err := utils.SaveConfig(path, workspace); err != nil {
+		return fmt.Errorf("failed to write workspace: %w", err)
+	}
+
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") || entry.Name() == workspaceFile {
+			return nil
+		}
+
+		var data interface{}
+		if err := utils.LoadConfig(path, &data); err != nil {
+			return fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+		if !blankPasswords(data) {
+			return nil
+		}
+		if err := utils.SaveConfig(path, data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", entry.Name(), err)
+		}
+		return nil
+	})
+}
+
+// blankPasswords clears every non-empty string field whose name ends in "password" and reports whether any changed
+func blankPasswords(value interface{}) bool {
+	changed := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if secret, ok := field.(string); ok && secret != "" && strings.HasSuffix(strings.ToLower(key), "password") {
+				v[key] = ""
+				changed = true
+				continue
+			}
+			changed = blankPasswords(field) || changed
+		}
+	case []interface{}:
+		for _, item := range v {
+			changed = blankPasswords(item) || changed
+		}
+	}
+	return changed
+}
+
+// Import creates a new workspace from a bundle written by Export
+func (s *Service) Import(source string) (models.Workspace, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stagingRoot, err := os.MkdirTemp("", "workspace-import-*")
+	if err != nil {
+		return models.Workspace{}, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingRoot)
+
+	if err := utils.UnzipFile(source, stagingRoot); err != nil {
+		return models.Workspace{}, fmt.Errorf("failed to read workspace bundle: %w", err)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(stagingRoot, "*", workspaceFile))
+	if len(matches) != 1 {
+		return models.Workspace{}, fmt.Errorf("invalid workspace bundle: expected exactly one %s", workspaceFile)
+	}
+
+	var workspace models.Workspace
+	if err := utils.LoadConfig(matches[0], &workspace); err != nil {
+		return models.Workspace{}, fmt.Errorf("invalid workspace bundle: %w", err)
+	}
+
+	// Always import as a new workspace so an existing one is never overwritten
+	workspace.ID = uuid.New().String()
+	workspace.Archived = false
+	workspace.ArchivedAt = ""
+	baseName := workspace.Name
+	for i := 2; s.checkName(workspace.Name) != nil; i++ {
+		workspace.Name = fmt.Sprintf("%s (%d)", baseName, i)
+	}
+
+	if err := utils.CopyDirectory(filepath.Dir(matches[0]), s.Dir(workspace.ID), nil); err != nil {
+		os.RemoveAll(s.Dir(workspace.ID))
+		return models.Workspace{}, fmt.Errorf("failed to import workspace: %w", err)
+	}
+	if err := s.save(workspace); err != nil {
+		return models.Workspace{}, err
+	}
+
+	fmt.Printf("Imported workspace %s as %s\n", workspace.Name, workspace.ID)
+	return workspace, nil
+}
@@ -0,0 +1,109 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"application-updater/internal/models"
+	"application-updater/internal/utils"
+)
+
+func TestServiceMigratesLegacyData(t *testing.T) {
+	configDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(configDir, "devices.json"), []byte("[]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewService(configDir)
+	if current := s.Current(); current.ID != DefaultID {
+		t.Fatalf("Expected default workspace to be active, got %q", current.ID)
+	}
+	if !utils.FileExists(filepath.Join(s.Dir(DefaultID), "devices.json")) {
+		t.Errorf("Expected legacy devices.json to be moved into the default workspace")
+	}
+	if utils.FileExists(filepath.Join(configDir, "devices.json")) {
+		t.Errorf("Expected legacy devices.json to be removed from the config directory")
+	}
+}
+
+func TestServiceCloneSwitchArchive(t *testing.T) {
+	configDir := t.TempDir()
+	s := NewService(configDir)
+	os.WriteFile(filepath.Join(s.Dir(DefaultID), "device_settings.json"), []byte("{}"), 0644)
+
+	clone, err := s.Clone(DefaultID, "Customer A")
+	if err != nil {
+		t.Fatalf("Clone failed: %v", err)
+	}
+	if !utils.FileExists(filepath.Join(s.Dir(clone.ID), "device_settings.json")) {
+		t.Errorf("Expected clone to copy workspace files")
+	}
+	if _, err := s.Create("Customer A", ""); err == nil {
+		t.Errorf("Expected duplicate workspace name to be rejected")
+	}
+
+	if _, err := s.Switch(clone.ID); err != nil {
+		t.Fatalf("Switch failed: %v", err)
+	}
+	if err := s.Archive(clone.ID); err == nil {
+		t.Errorf("Expected archiving the active workspace to fail")
+	}
+	if err := s.Archive(DefaultID); err != nil {
+		t.Fatalf("Archive failed: %v", err)
+	}
+	if _, err := s.Switch(DefaultID); err == nil {
+		t.Errorf("Expected switching to an archived workspace to fail")
+	}
+
+	// The active workspace is remembered across restarts
+	if current := NewService(configDir).Current(); current.ID != clone.ID {
+		t.Errorf("Expected %s to stay active after restart, got %s", clone.ID, current.ID)
+	}
+}
+
+func TestServiceExportImport(t *testing.T) {
+	s := NewService(t.TempDir())
+	os.WriteFile(filepath.Join(s.Dir(DefaultID), "backup_settings.json"), []byte("{}"), 0644)
+	os.MkdirAll(filepath.Join(s.Dir(DefaultID), "update_jobs"), 0755)
+	os.WriteFile(filepath.Join(s.Dir(DefaultID), "update_jobs", "job.json"),
+		[]byte(`{"id":"job","username":"admin","password":"secret","options":{"sshPassword":"ssh-secret"}}`), 0644)
+	if err := s.SaveCredentials(DefaultID, models.WorkspaceCredentials{Username: "admin", Password: "secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	bundle := filepath.Join(t.TempDir(), "workspace.zip")
+	if err := s.Export(DefaultID, bundle); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	imported, err := s.Import(bundle)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if imported.ID == DefaultID || imported.Name == s.Current().Name {
+		t.Errorf("Expected import to create a new workspace with a unique name, got %+v", imported)
+	}
+	if !utils.FileExists(filepath.Join(s.Dir(imported.ID), "backup_settings.json")) {
+		t.Errorf("Expected imported workspace to contain bundled files")
+	}
+
+	// The bundle carries no passwords
+	if imported.Credentials != (models.WorkspaceCredentials{}) {
+		t.Errorf("Expected the bundle to leave out the default credentials, got %+v", imported.Credentials)
+	}
+	var job map[string]interface{}
+	if err := utils.LoadConfig(filepath.Join(s.Dir(imported.ID), "update_jobs", "job.json"), &job); err != nil {
+		t.Fatal(err)
+	}
+	options, _ := job["options"].(map[string]interface{})
+	if job["password"] != "" || options["sshPassword"] != "" || job["username"] != "admin" {
+		t.Errorf("Expected the bundled job to lose only its passwords, got %v", job)
+	}
+	if s.Current().Credentials.Password != "secret" {
+		t.Errorf("Expected the exported workspace to keep its credentials")
+	}
+	if len(s.List()) != 2 {
+		t.Errorf("Expected 2 workspaces after import, got %d", len(s.List()))
+	}
+}
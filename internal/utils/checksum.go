@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// FileChecksums returns the hex encoded MD5 and SHA-256 of a file
+func FileChecksums(path string) (md5sum, sha256sum string, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", "", err
+	}
+	defer file.Close()
+
+	md5Hash, shaHash := md5.New(), sha256.New()
+	if _, err := io.Copy(io.MultiWriter(md5Hash, shaHash), file); err != nil {
+		return "", "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(md5Hash.Sum(nil)), hex.EncodeToString(shaHash.Sum(nil)), nil
+}
@@ -188,3 +188,32 @@ func ListFilesInDirectory(dir string, pattern string) ([]string, error) {
 	}
 	return matches, nil
 }
+
+// CopyDirectory recursively copies a directory, skipping entries for which skip returns true
+func CopyDirectory(src, dst string, skip func(relPath string) bool) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return fmt.Errorf("failed to calculate relative path: %w", err)
+		}
+		if relPath != "." && skip != nil && skip(relPath) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		target := filepath.Join(dst, relPath)
+		if info.IsDir() {
+			return EnsureDirExists(target)
+		}
+		if err := CopyFile(path, target); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", relPath, err)
+		}
+		return nil
+	})
+}
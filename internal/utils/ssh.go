@@ -6,6 +6,8 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/ssh"
@@ -39,38 +41,90 @@ func ExecuteSSHCommand(client *ssh.Client, command string) (string, error) {
 	return stdout.String(), nil
 }
 
-// SCPFileToRemote copies a local file to a remote server via SCP
-func SCPFileToRemote(client *ssh.Client, localFilePath, remoteFilePath string) error {
+// SCPFileToRemote copies a local file to a remote host by streaming it into dd, then
+// applies the local permissions and checks the remote size. limit wraps the file reader
+// to cap the transfer rate and may be nil.
+func SCPFileToRemote(client *ssh.Client, localFilePath, remoteFilePath string, limit func(io.Reader) io.Reader) error {
+	// Open and stat the local file
+	localFile, err := os.Open(localFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to open local file: %w", err)
+	}
+	defer localFile.Close()
+
+	// Get file info for permissions and size
+	fileInfo, err := localFile.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat local file: %w", err)
+	}
+	fileSize := fileInfo.Size()
+
+	escapedPath := EscapeShellArg(remoteFilePath)
+
+	// Make sure remote directory exists
+	if output, err := ExecuteSSHCommand(client, "mkdir -p "+EscapeShellArg(filepath.Dir(remoteFilePath))); err != nil {
+		return fmt.Errorf("failed to create remote directory: %w, output: %s", err, output)
+	}
+
+	// Create a new SSH session for the transfer
 	session, err := client.NewSession()
 	if err != nil {
-		return fmt.Errorf("failed to create session: %v", err)
+		return fmt.Errorf("failed to create SSH session: %w", err)
 	}
 	defer session.Close()
 
-	file, err := os.Open(localFilePath)
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+
+	// Get stdin pipe to write file data
+	stdin, err := session.StdinPipe()
 	if err != nil {
-		return fmt.Errorf("failed to open local file: %v", err)
+		return fmt.Errorf("failed to get stdin pipe: %w", err)
+	}
+
+	// Start file transfer on the remote side with dd to handle binary data better
+	if err := session.Start(fmt.Sprintf("dd of=%s bs=32k", escapedPath)); err != nil {
+		return fmt.Errorf("failed to start remote command: %w", err)
 	}
-	defer file.Close()
 
-	stat, err := file.Stat()
+	var source io.Reader = localFile
+	if limit != nil {
+		source = limit(localFile)
+	}
+	written, err := io.Copy(stdin, source)
+	if err == nil && written != fileSize {
+		err = fmt.Errorf("incomplete file transfer: wrote %d bytes out of %d", written, fileSize)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get file stats: %v", err)
+		// Try to remove incomplete file
+		ExecuteSSHCommand(client, "rm -f "+escapedPath)
+		return fmt.Errorf("failed to copy file data: %w", err)
 	}
 
-	go func() {
-		w, _ := session.StdinPipe()
-		defer w.Close()
+	// Close stdin to signal end of file transfer
+	if err := stdin.Close(); err != nil {
+		return fmt.Errorf("failed to close stdin pipe: %w", err)
+	}
+	if err := session.Wait(); err != nil {
+		return fmt.Errorf("command failed: %w\nStderr: %s", err, stderr.String())
+	}
 
-		fmt.Fprintf(w, "C%#o %d %s\n", stat.Mode().Perm(), stat.Size(), filepath.Base(remoteFilePath))
-		io.Copy(w, file)
-		fmt.Fprint(w, "\x00")
-	}()
+	// Set file permissions
+	if output, err := ExecuteSSHCommand(client, fmt.Sprintf("chmod %o %s", fileInfo.Mode().Perm(), escapedPath)); err != nil {
+		return fmt.Errorf("failed to set file permissions: %w, output: %s", err, output)
+	}
 
-	cmd := fmt.Sprintf("scp -t %s", filepath.Dir(remoteFilePath))
-	err = session.Run(cmd)
+	// Verify file size
+	output, err := ExecuteSSHCommand(client, "stat -c %s "+escapedPath)
 	if err != nil {
-		return fmt.Errorf("failed to run scp command: %v", err)
+		return fmt.Errorf("failed to verify file size: %w", err)
+	}
+	remoteSize, err := strconv.ParseInt(strings.TrimSpace(output), 10, 64)
+	if err != nil {
+		return fmt.Errorf("failed to parse remote file size: %w", err)
+	}
+	if remoteSize != fileSize {
+		return fmt.Errorf("file size mismatch: expected %d bytes, got %d bytes", fileSize, remoteSize)
 	}
 
 	return nil
@@ -95,3 +149,8 @@ func CreateSSHClient(host, user, password string, port int) (*ssh.Client, error)
 
 	return client, nil
 }
+
+// EscapeShellArg quotes an argument for use in a remote shell command
+func EscapeShellArg(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", "'\\''") + "'"
+}